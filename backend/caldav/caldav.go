@@ -0,0 +1,256 @@
+// Package caldav reads and writes the XML bodies of the WebDAV (RFC 4918) and
+// CalDAV (RFC 4791) requests a task application makes to find a calendar
+// collection and fetch its objects: PROPFIND, and the calendar-query and
+// calendar-multiget reports.
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// XML namespaces
+const (
+	NamespaceDAV    = "DAV:"
+	NamespaceCalDAV = "urn:ietf:params:xml:ns:caldav"
+	// NamespaceServer holds the extensions of Apple's calendar server, such as getctag
+	NamespaceServer = "http://calendarserver.org/ns/"
+)
+
+// Properties served by this package's users
+var (
+	ResourceType                  = xml.Name{Space: NamespaceDAV, Local: "resourcetype"}
+	DisplayName                   = xml.Name{Space: NamespaceDAV, Local: "displayname"}
+	GetETag                       = xml.Name{Space: NamespaceDAV, Local: "getetag"}
+	GetContentType                = xml.Name{Space: NamespaceDAV, Local: "getcontenttype"}
+	GetLastModified               = xml.Name{Space: NamespaceDAV, Local: "getlastmodified"}
+	CurrentUserPrincipal          = xml.Name{Space: NamespaceDAV, Local: "current-user-principal"}
+	CalendarHomeSet               = xml.Name{Space: NamespaceCalDAV, Local: "calendar-home-set"}
+	SupportedCalendarComponentSet = xml.Name{Space: NamespaceCalDAV, Local: "supported-calendar-component-set"}
+	CalendarData                  = xml.Name{Space: NamespaceCalDAV, Local: "calendar-data"}
+	GetCTag                       = xml.Name{Space: NamespaceServer, Local: "getctag"}
+)
+
+// Reports
+const (
+	CalendarQuery    = "calendar-query"
+	CalendarMultiget = "calendar-multiget"
+)
+
+// prefixes are the prefixes of the namespaces declared by a multistatus response
+var prefixes = map[string]string{NamespaceDAV: "d", NamespaceCalDAV: "c", NamespaceServer: "cs"}
+
+// Request is the body of a PROPFIND or REPORT request
+type Request struct {
+	// Report is CalendarQuery or CalendarMultiget for a REPORT, and empty for
+	// a PROPFIND
+	Report string
+	// AllProps is set when every property is asked for
+	AllProps bool
+	Props    []xml.Name
+	// Hrefs are the resources a calendar-multiget asks for
+	Hrefs []string
+	// Components are the components a calendar-query asks for, outermost
+	// first, e.g. VCALENDAR and VTODO
+	Components []string
+}
+
+type requestBody struct {
+	XMLName  xml.Name
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     *struct {
+		Props []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"DAV: prop"`
+	Hrefs  []string `xml:"DAV: href"`
+	Filter *struct {
+		CompFilter *compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type compFilter struct {
+	Name       string      `xml:"name,attr"`
+	CompFilter *compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// ParseRequest parses the body of a PROPFIND or REPORT request. An empty body
+// asks for every property.
+func ParseRequest(r io.Reader) (Request, error) {
+	var body requestBody
+	if err := xml.NewDecoder(r).Decode(&body); errors.Is(err, io.EOF) {
+		return Request{AllProps: true}, nil
+	} else if err != nil {
+		return Request{}, err
+	}
+
+	var req Request
+	switch body.XMLName {
+	case xml.Name{Space: NamespaceDAV, Local: "propfind"}:
+	case xml.Name{Space: NamespaceCalDAV, Local: CalendarQuery}, xml.Name{Space: NamespaceCalDAV, Local: CalendarMultiget}:
+		req.Report = body.XMLName.Local
+	default:
+		return Request{}, fmt.Errorf("unsupported request %s %s", body.XMLName.Space, body.XMLName.Local)
+	}
+
+	// propname は値を省いた allprop として扱う
+	req.AllProps = body.AllProp != nil || body.PropName != nil || body.Prop == nil
+	if body.Prop != nil {
+		for _, prop := range body.Prop.Props {
+			req.Props = append(req.Props, prop.XMLName)
+		}
+	}
+	req.Hrefs = body.Hrefs
+	if body.Filter != nil {
+		for filter := body.Filter.CompFilter; filter != nil; filter = filter.CompFilter {
+			req.Components = append(req.Components, strings.ToUpper(filter.Name))
+		}
+	}
+	return req, nil
+}
+
+// Property is a property of a resource
+type Property struct {
+	Name xml.Name
+	// Text is the value of a text property, escaped when it is written
+	Text string
+	// XML is the inner XML of a property whose value is made of elements
+	XML string
+}
+
+// Text returns a text property
+func Text(name xml.Name, text string) Property {
+	return Property{Name: name, Text: text}
+}
+
+// Href returns a property whose value is the URL path href
+func Href(name xml.Name, href string) Property {
+	return Property{Name: name, XML: "<d:href>" + escape(href) + "</d:href>"}
+}
+
+// Collection returns the resourcetype of a collection, which with calendar set
+// is a calendar collection
+func Collection(calendar bool) Property {
+	value := "<d:collection/>"
+	if calendar {
+		value += "<c:calendar/>"
+	}
+	return Property{Name: ResourceType, XML: value}
+}
+
+// Components returns the supported-calendar-component-set of the components
+// a calendar collection holds
+func Components(components ...string) Property {
+	var b strings.Builder
+	for _, component := range components {
+		b.WriteString(`<c:comp name="` + escape(component) + `"/>`)
+	}
+	return Property{Name: SupportedCalendarComponentSet, XML: b.String()}
+}
+
+// Response is a resource of a multistatus response
+type Response struct {
+	Href string
+	// Status is the status of a resource that cannot be returned, such as 404
+	// for an href of calendar-multiget that does not exist, or 0
+	Status int
+	Props  []Property
+	// Missing are the properties asked for that the resource does not have
+	Missing []xml.Name
+}
+
+// Response returns the response of the resource at href with the properties
+// of props the request asks for. Calendar data is only returned when asked
+// for by name.
+func (r Request) Response(href string, props []Property) Response {
+	response := Response{Href: href}
+	if r.AllProps {
+		for _, prop := range props {
+			if prop.Name != CalendarData {
+				response.Props = append(response.Props, prop)
+			}
+		}
+		return response
+	}
+
+	for _, name := range r.Props {
+		found := false
+		for _, prop := range props {
+			if prop.Name == name {
+				response.Props = append(response.Props, prop)
+				found = true
+				break
+			}
+		}
+		if !found {
+			response.Missing = append(response.Missing, name)
+		}
+	}
+	return response
+}
+
+// WriteMultistatus writes a multistatus response body
+func WriteMultistatus(w io.Writer, responses []Response) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + NamespaceCalDAV + `" xmlns:cs="` + NamespaceServer + `">`)
+	for _, response := range responses {
+		b.WriteString("<d:response><d:href>" + escape(response.Href) + "</d:href>")
+		if response.Status != 0 {
+			b.WriteString("<d:status>" + status(response.Status) + "</d:status>")
+		} else {
+			writePropstat(&b, response.Props, nil, http.StatusOK)
+			writePropstat(&b, nil, response.Missing, http.StatusNotFound)
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writePropstat writes the propstat of props and of the names of properties
+// without a value, when there are any
+func writePropstat(b *strings.Builder, props []Property, names []xml.Name, code int) {
+	if len(props) == 0 && len(names) == 0 {
+		return
+	}
+	b.WriteString("<d:propstat><d:prop>")
+	for _, prop := range props {
+		start, end := tags(prop.Name)
+		b.WriteString(start + escape(prop.Text) + prop.XML + end)
+	}
+	for _, name := range names {
+		start, end := tags(name)
+		b.WriteString(start + end)
+	}
+	b.WriteString("</d:prop><d:status>" + status(code) + "</d:status></d:propstat>")
+}
+
+// tags returns the start and end tags of an element, declaring its namespace
+// when it has no prefix
+func tags(name xml.Name) (string, string) {
+	if prefix, ok := prefixes[name.Space]; ok {
+		return "<" + prefix + ":" + name.Local + ">", "</" + prefix + ":" + name.Local + ">"
+	}
+	if name.Space == "" {
+		return "<" + name.Local + ">", "</" + name.Local + ">"
+	}
+	return `<x:` + name.Local + ` xmlns:x="` + escape(name.Space) + `">`, "</x:" + name.Local + ">"
+}
+
+func status(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePropfind(t *testing.T) {
+	req, err := ParseRequest(strings.NewReader(`<?xml version="1.0"?>
+<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:X="urn:x">
+  <prop><resourcetype/><C:calendar-home-set/><X:color/></prop>
+</propfind>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "", req.Report)
+	assert.False(t, req.AllProps)
+	assert.Equal(t, []xml.Name{ResourceType, CalendarHomeSet, {Space: "urn:x", Local: "color"}}, req.Props)
+
+	// 本文のない PROPFIND はすべてのプロパティを求める
+	req, err = ParseRequest(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.True(t, req.AllProps)
+
+	req, err = ParseRequest(strings.NewReader(`<propfind xmlns="DAV:"><allprop/></propfind>`))
+	require.NoError(t, err)
+	assert.True(t, req.AllProps)
+}
+
+func TestParseReports(t *testing.T) {
+	req, err := ParseRequest(strings.NewReader(`<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="vtodo"/></C:comp-filter></C:filter>
+</C:calendar-query>`))
+	require.NoError(t, err)
+	assert.Equal(t, CalendarQuery, req.Report)
+	assert.Equal(t, []xml.Name{GetETag, CalendarData}, req.Props)
+	assert.Equal(t, []string{"VCALENDAR", "VTODO"}, req.Components)
+
+	req, err = ParseRequest(strings.NewReader(`<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/></D:prop>
+  <D:href>/a/1.ics</D:href><D:href>/a/2.ics</D:href>
+</C:calendar-multiget>`))
+	require.NoError(t, err)
+	assert.Equal(t, CalendarMultiget, req.Report)
+	assert.Equal(t, []string{"/a/1.ics", "/a/2.ics"}, req.Hrefs)
+
+	_, err = ParseRequest(strings.NewReader(`<D:sync-collection xmlns:D="DAV:"/>`))
+	assert.Error(t, err)
+	_, err = ParseRequest(strings.NewReader(`<propfind`))
+	assert.Error(t, err)
+}
+
+func TestResponse(t *testing.T) {
+	props := []Property{Text(DisplayName, "Todos"), Text(CalendarData, "BEGIN:VCALENDAR")}
+
+	response := Request{AllProps: true}.Response("/a/", props)
+	assert.Equal(t, []Property{Text(DisplayName, "Todos")}, response.Props)
+
+	response = Request{Props: []xml.Name{CalendarData, GetCTag}}.Response("/a/", props)
+	assert.Equal(t, []Property{Text(CalendarData, "BEGIN:VCALENDAR")}, response.Props)
+	assert.Equal(t, []xml.Name{GetCTag}, response.Missing)
+}
+
+func TestWriteMultistatus(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, WriteMultistatus(&b, []Response{
+		{
+			Href:    "/a/",
+			Props:   []Property{Collection(true), Components("VTODO"), Href(CurrentUserPrincipal, "/a/"), Text(DisplayName, "A & B")},
+			Missing: []xml.Name{{Space: "urn:x", Local: "color"}},
+		},
+		{Href: "/a/missing.ics", Status: 404},
+	}))
+	out := b.String()
+
+	assert.Contains(t, out, `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"`)
+	assert.Contains(t, out, `<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>`)
+	assert.Contains(t, out, `<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`)
+	assert.Contains(t, out, `<d:current-user-principal><d:href>/a/</d:href></d:current-user-principal>`)
+	assert.Contains(t, out, `<d:displayname>A &amp; B</d:displayname>`)
+	assert.Contains(t, out, `<d:prop><x:color xmlns:x="urn:x"></x:color></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>`)
+	assert.Contains(t, out, `<d:href>/a/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>`)
+
+	// 出力は XML として読める
+	var ms struct {
+		Responses []struct {
+			Href string `xml:"DAV: href"`
+		} `xml:"DAV: response"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(out), &ms))
+	assert.Len(t, ms.Responses, 2)
+}
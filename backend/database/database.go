@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"shared-todo-backend/models"
+	"shared-todo-backend/search"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
@@ -36,10 +37,29 @@ func InitDatabase() {
 		&models.User{},
 		&models.Todo{},
 		&models.TodoUserStatus{},
+		&models.Notification{},
+		&models.ReminderLog{},
+		&models.ReminderDelivery{},
+		&models.DigestDelivery{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.Activity{},
+		&models.MemoRevision{},
+		&models.MemoOperation{},
+		&models.ClientMutation{},
+		&models.IdempotencyKey{},
+		&models.CalendarFeed{},
+		&models.CalDAVToken{},
+		&models.CalDAVObject{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// 全文検索インデックスを作成
+	if err := search.Setup(DB); err != nil {
+		log.Fatal("Failed to set up search index:", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 }
\ No newline at end of file
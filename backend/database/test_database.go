@@ -2,6 +2,7 @@ package database
 
 import (
 	"shared-todo-backend/models"
+	"shared-todo-backend/search"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
@@ -20,11 +21,29 @@ func SetupTestDatabase() (*gorm.DB, error) {
 		&models.User{},
 		&models.Todo{},
 		&models.TodoUserStatus{},
+		&models.Notification{},
+		&models.ReminderLog{},
+		&models.ReminderDelivery{},
+		&models.DigestDelivery{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.Activity{},
+		&models.MemoRevision{},
+		&models.MemoOperation{},
+		&models.ClientMutation{},
+		&models.IdempotencyKey{},
+		&models.CalendarFeed{},
+		&models.CalDAVToken{},
+		&models.CalDAVObject{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := search.Setup(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
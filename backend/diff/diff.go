@@ -0,0 +1,159 @@
+// Package diff compares texts line by line and formats the result as a
+// unified diff, as used for the memo revision history.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// Op is the kind of an edit
+type Op int
+
+// Edit operations
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Edit is a line of the diff: kept, deleted from a or inserted from b
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// SplitLines splits a text into lines. An empty text has no lines and a final
+// newline does not start another line.
+func SplitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Lines returns the shortest edit script turning a into b, based on their
+// longest common subsequence. Deletions come before insertions in each change.
+func Lines(a, b []string) []Edit {
+	// lcs[i][j] は a[i:] と b[j:] の最長共通部分列の長さ
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, Edit{Delete, a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Insert, b[j]})
+	}
+	return edits
+}
+
+// Unified returns the unified diff of two texts with DefaultContext lines of
+// context, or an empty string when they have the same lines
+func Unified(fromName, toName, a, b string) string {
+	edits := Lines(SplitLines(a), SplitLines(b))
+
+	var changes []int
+	for i, e := range edits {
+		if e.Op != Equal {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// 間の変更されていない行が前後の文脈に収まる変更は同じハンクにまとめる
+	for start := 0; start < len(changes); {
+		end := start
+		for end+1 < len(changes) && changes[end+1]-changes[end] <= 2*DefaultContext+1 {
+			end++
+		}
+		writeHunk(&sb, edits, max(changes[start]-DefaultContext, 0), min(changes[end]+DefaultContext+1, len(edits)))
+		start = end + 1
+	}
+	return sb.String()
+}
+
+// writeHunk writes edits[from:to] with its header
+func writeHunk(sb *strings.Builder, edits []Edit, from, to int) {
+	// ハンクより前の行数から開始行を求める
+	aLine, bLine := 0, 0
+	for _, e := range edits[:from] {
+		if e.Op != Insert {
+			aLine++
+		}
+		if e.Op != Delete {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, e := range edits[from:to] {
+		if e.Op != Insert {
+			aCount++
+		}
+		if e.Op != Delete {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+	for _, e := range edits[from:to] {
+		switch e.Op {
+		case Equal:
+			sb.WriteString(" ")
+		case Delete:
+			sb.WriteString("-")
+		case Insert:
+			sb.WriteString("+")
+		}
+		sb.WriteString(e.Text)
+		sb.WriteString("\n")
+	}
+}
+
+// hunkRange formats the line range of a hunk. An empty range refers to the
+// line before it, as in GNU diff.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
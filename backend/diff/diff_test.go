@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLines(t *testing.T) {
+	assert.Nil(t, SplitLines(""))
+	assert.Equal(t, []string{"a", "b"}, SplitLines("a\nb\n"))
+	assert.Equal(t, []string{"a", "", "b"}, SplitLines("a\r\n\r\nb"))
+}
+
+func TestLines(t *testing.T) {
+	edits := Lines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	assert.Equal(t, []Edit{
+		{Equal, "a"},
+		{Delete, "b"},
+		{Insert, "x"},
+		{Equal, "c"},
+		{Insert, "d"},
+	}, edits)
+}
+
+func TestUnified(t *testing.T) {
+	assert.Equal(t, "", Unified("a", "b", "same\ntext", "same\ntext\n"))
+
+	expected := strings.Join([]string{
+		"--- revision 1",
+		"+++ revision 2",
+		"@@ -1,3 +1,3 @@",
+		" milk",
+		"-eggs",
+		"+bread",
+		" rice",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, Unified("revision 1", "revision 2", "milk\neggs\nrice", "milk\nbread\nrice"))
+
+	// 空のテキストとの比較
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n", Unified("a", "b", "", "new"))
+	assert.Equal(t, "--- a\n+++ b\n@@ -1 +0,0 @@\n-old\n", Unified("a", "b", "old", ""))
+}
+
+func TestUnifiedHunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, string(rune('a'+i-1)))
+	}
+	a := strings.Join(lines, "\n")
+	changed := append([]string{}, lines...)
+	changed[1] = "B"
+	changed[17] = "R"
+	b := strings.Join(changed, "\n")
+
+	// 離れた変更は別のハンクになる
+	diff := Unified("a", "b", a, b)
+	assert.Equal(t, 2, strings.Count(diff, "@@ -"))
+	assert.Contains(t, diff, "@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n")
+	assert.Contains(t, diff, "@@ -15,6 +15,6 @@\n o\n p\n q\n-r\n+R\n s\n t\n")
+
+	// 近い変更は1つのハンクにまとめる
+	changed[17] = "r"
+	changed[8] = "I"
+	diff = Unified("a", "b", a, strings.Join(changed, "\n"))
+	assert.Equal(t, 1, strings.Count(diff, "@@ -"))
+	assert.Contains(t, diff, "@@ -1,12 +1,12 @@\n")
+}
@@ -0,0 +1,210 @@
+// Package digest summarizes a list for one user: the todos waiting on their
+// check, the todos only waiting on others, the overdue todos and what was
+// completed since the previous digest. A digest renders as JSON, plain text or
+// HTML so that any notifier can deliver it.
+package digest
+
+import (
+	"fmt"
+	"shared-todo-backend/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Digest frequencies a user can choose
+const (
+	FrequencyOff    = "off"
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// Hour is the local hour at which scheduled digests are sent. Weekly digests
+// are sent on Mondays.
+const Hour = 8
+
+// ValidFrequency reports whether s is a known digest frequency
+func ValidFrequency(s string) bool {
+	return s == FrequencyOff || s == FrequencyDaily || s == FrequencyWeekly
+}
+
+// Item is a todo in a digest
+type Item struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"dueDate"`
+	HasDueTime  bool       `json:"hasDueTime"`
+	Due         string     `json:"due,omitempty"`
+	WaitingOn   []string   `json:"waitingOn,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Digest is the summary of a list for one user
+type Digest struct {
+	ListID          string    `json:"listId"`
+	UserID          string    `json:"userId"`
+	TimeZone        string    `json:"timeZone"`
+	Since           time.Time `json:"since"`
+	GeneratedAt     time.Time `json:"generatedAt"`
+	WaitingOnYou    []Item    `json:"waitingOnYou"`
+	WaitingOnOthers []Item    `json:"waitingOnOthers"`
+	Overdue         []Item    `json:"overdue"`
+	Completed       []Item    `json:"completed"`
+}
+
+// Build summarizes list for user at now. Completed todos are included when they
+// were completed after since.
+func Build(db *gorm.DB, list models.List, user models.User, since, now time.Time) (*Digest, error) {
+	timeZone := models.EffectiveTimeZone(list, user)
+	loc := models.LoadLocation(timeZone)
+
+	d := &Digest{
+		ListID:          list.ID,
+		UserID:          user.ID,
+		TimeZone:        timeZone,
+		Since:           since.UTC(),
+		GeneratedAt:     now.UTC(),
+		WaitingOnYou:    []Item{},
+		WaitingOnOthers: []Item{},
+		Overdue:         []Item{},
+		Completed:       []Item{},
+	}
+
+	var users []models.User
+	if err := db.Where("list_id = ?", list.ID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, u := range users {
+		names[u.ID] = displayName(u)
+	}
+
+	var todos []models.Todo
+	err := db.Where("list_id = ? AND (is_completed = ? OR completed_at > ?)", list.ID, false, since.UTC()).
+		Preload("UserStatuses").
+		Find(&todos).Error
+	if err != nil {
+		return nil, err
+	}
+	sortTodos(todos)
+
+	for i := range todos {
+		todo := &todos[i]
+		item := newItem(todo, loc)
+
+		if todo.IsCompleted {
+			d.Completed = append(d.Completed, item)
+			continue
+		}
+
+		checked := map[string]bool{}
+		for _, status := range todo.UserStatuses {
+			checked[status.UserID] = status.IsChecked
+		}
+		for _, u := range users {
+			if !checked[u.ID] && u.ID != user.ID {
+				item.WaitingOn = append(item.WaitingOn, names[u.ID])
+			}
+		}
+
+		if checked[user.ID] {
+			d.WaitingOnOthers = append(d.WaitingOnOthers, item)
+		} else {
+			d.WaitingOnYou = append(d.WaitingOnYou, item)
+		}
+
+		todo.UpdateDueState(now, loc)
+		if todo.IsOverdue {
+			d.Overdue = append(d.Overdue, item)
+		}
+	}
+	return d, nil
+}
+
+// Empty reports whether the digest has nothing to report
+func (d *Digest) Empty() bool {
+	return len(d.WaitingOnYou) == 0 && len(d.WaitingOnOthers) == 0 && len(d.Overdue) == 0 && len(d.Completed) == 0
+}
+
+// Subject returns a one-line summary suitable as an email subject
+func (d *Digest) Subject() string {
+	return fmt.Sprintf("Digest: %d waiting on you, %d overdue, %d completed", len(d.WaitingOnYou), len(d.Overdue), len(d.Completed))
+}
+
+// Scheduled returns the time of the latest digest due at or before now for the
+// frequency, in loc. It returns the zero time when digests are off.
+func Scheduled(frequency string, now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	at := time.Date(local.Year(), local.Month(), local.Day(), Hour, 0, 0, 0, loc)
+
+	switch frequency {
+	case FrequencyDaily:
+		if at.After(local) {
+			at = at.AddDate(0, 0, -1)
+		}
+	case FrequencyWeekly:
+		at = at.AddDate(0, 0, -((int(at.Weekday()) + 6) % 7))
+		if at.After(local) {
+			at = at.AddDate(0, 0, -7)
+		}
+	default:
+		return time.Time{}
+	}
+	return at
+}
+
+// Period returns the time covered by a digest of the frequency
+func Period(frequency string) time.Duration {
+	if frequency == FrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func newItem(todo *models.Todo, loc *time.Location) Item {
+	item := Item{
+		ID:          todo.ID,
+		Title:       todo.Title,
+		Priority:    todo.Priority,
+		DueDate:     todo.DueDate,
+		HasDueTime:  todo.HasDueTime,
+		CompletedAt: todo.CompletedAt,
+	}
+	if todo.DueDate != nil {
+		if todo.HasDueTime {
+			item.Due = todo.DueDate.In(loc).Format("2006-01-02 15:04")
+		} else {
+			item.Due = todo.DueDate.UTC().Format("2006-01-02")
+		}
+	}
+	return item
+}
+
+// sortTodos orders todos by due date, todos without one last, then by ID
+func sortTodos(todos []models.Todo) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := todos[i].DueDate, todos[j].DueDate
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case a != nil && b == nil:
+			return true
+		case a == nil && b != nil:
+			return false
+		}
+		return todos[i].ID < todos[j].ID
+	})
+}
+
+// displayName returns the name shown for a user, like the frontend does
+func displayName(user models.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	if len(user.ID) > 8 {
+		return user.ID[:8]
+	}
+	return user.ID
+}
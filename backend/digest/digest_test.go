@@ -0,0 +1,98 @@
+package digest
+
+import (
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func titles(items []Item) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.Title)
+	}
+	return result
+}
+
+func TestBuild(t *testing.T) {
+	db, err := database.SetupTestDatabase()
+	require.NoError(t, err)
+
+	now := time.Date(2025, 6, 10, 8, 0, 0, 0, time.UTC)
+	list := models.List{ID: "list", TimeZone: "Asia/Tokyo"}
+	db.Create(&list)
+	alice := models.User{ID: "alice", ListID: "list", DisplayName: "Alice"}
+	db.Create(&alice)
+	db.Create(&models.User{ID: "bob-0123456789", ListID: "list"})
+
+	create := func(title string, due *time.Time, aliceChecked, bobChecked bool, completedAt *time.Time) {
+		todo := models.Todo{ListID: "list", Title: title, Priority: "medium", DueDate: due, HasDueTime: due != nil}
+		db.Create(&todo)
+		if completedAt != nil {
+			db.Model(&todo).Updates(map[string]interface{}{"is_completed": true, "completed_at": completedAt.UTC()})
+		}
+		db.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "alice", IsChecked: aliceChecked})
+		db.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "bob-0123456789", IsChecked: bobChecked})
+	}
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+	longAgo := now.Add(-72 * time.Hour)
+	recently := now.Add(-2 * time.Hour)
+
+	create("Later", nil, false, false, nil)
+	create("Late", &yesterday, false, true, nil)
+	create("Soon", &tomorrow, true, false, nil)
+	create("Done recently", nil, true, true, &recently)
+	create("Done long ago", nil, true, true, &longAgo)
+
+	d, err := Build(db, list, alice, now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Asia/Tokyo", d.TimeZone)
+	assert.Equal(t, []string{"Late", "Later"}, titles(d.WaitingOnYou))
+	assert.Equal(t, []string{"Soon"}, titles(d.WaitingOnOthers))
+	assert.Equal(t, []string{"bob-0123"}, d.WaitingOnOthers[0].WaitingOn)
+	assert.Equal(t, "2025-06-11 17:00", d.WaitingOnOthers[0].Due)
+	assert.Equal(t, []string{"Late"}, titles(d.Overdue))
+	assert.Equal(t, []string{"Done recently"}, titles(d.Completed))
+	assert.False(t, d.Empty())
+	assert.Equal(t, "Digest: 2 waiting on you, 1 overdue, 1 completed", d.Subject())
+}
+
+func TestRender(t *testing.T) {
+	d := &Digest{
+		WaitingOnYou: []Item{{Title: "<script>x</script>", Due: "2025-06-10", WaitingOn: []string{"Bob", "Carol"}}},
+	}
+
+	text := d.Text()
+	assert.Contains(t, text, "Waiting on you (1)\n- <script>x</script> (due 2025-06-10) - waiting on Bob, Carol\n")
+	assert.Contains(t, text, "Overdue (0)\nNothing is overdue.")
+
+	html := d.HTML()
+	assert.Contains(t, html, "<li>&lt;script&gt;x&lt;/script&gt; <small>(due 2025-06-10)</small>")
+	assert.False(t, strings.Contains(html, "<script>"))
+	assert.Contains(t, html, "<p>Nothing was completed.</p>")
+}
+
+func TestScheduled(t *testing.T) {
+	tokyo := models.LoadLocation("Asia/Tokyo")
+
+	// 2025-06-10（火）17:00 JST
+	now := time.Date(2025, 6, 10, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 6, 10, Hour, 0, 0, 0, tokyo), Scheduled(FrequencyDaily, now, tokyo))
+	assert.Equal(t, time.Date(2025, 6, 9, Hour, 0, 0, 0, tokyo), Scheduled(FrequencyWeekly, now, tokyo))
+	assert.True(t, Scheduled(FrequencyOff, now, tokyo).IsZero())
+
+	// 08:00前は前日の分
+	early := time.Date(2025, 6, 9, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 6, 9, Hour, 0, 0, 0, tokyo), Scheduled(FrequencyDaily, early, tokyo))
+
+	// 月曜の08:00前は先週の月曜の分
+	mondayEarly := time.Date(2025, 6, 8, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 6, 2, Hour, 0, 0, 0, tokyo), Scheduled(FrequencyWeekly, mondayEarly, tokyo))
+}
@@ -0,0 +1,77 @@
+package digest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// section is a titled group of items as rendered
+type section struct {
+	Title string
+	Items []Item
+	Empty string
+}
+
+func (d *Digest) sections() []section {
+	return []section{
+		{"Waiting on you", d.WaitingOnYou, "Nothing is waiting on you."},
+		{"Waiting on others", d.WaitingOnOthers, "Nothing you checked is waiting on others."},
+		{"Overdue", d.Overdue, "Nothing is overdue."},
+		{"Completed", d.Completed, "Nothing was completed."},
+	}
+}
+
+var templateFuncs = map[string]interface{}{
+	"join": strings.Join,
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Funcs(templateFuncs).Parse(`{{.Subject}}
+{{range .Sections}}
+{{.Title}} ({{len .Items}})
+{{- range .Items}}
+- {{.Title}}
+{{- if .Due}} (due {{.Due}}){{end}}
+{{- if .WaitingOn}} - waiting on {{join .WaitingOn ", "}}{{end}}
+{{- else}}
+{{.Empty}}
+{{- end}}
+{{end -}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(`<h2>{{.Subject}}</h2>
+{{- range .Sections}}
+<h3>{{.Title}} ({{len .Items}})</h3>
+{{- if .Items}}
+<ul>
+{{- range .Items}}
+<li>{{.Title}}
+{{- if .Due}} <small>(due {{.Due}})</small>{{end}}
+{{- if .WaitingOn}} <small>waiting on {{join .WaitingOn ", "}}</small>{{end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>{{.Empty}}</p>
+{{- end}}
+{{- end}}
+`))
+
+type templateData struct {
+	Subject  string
+	Sections []section
+}
+
+// Text renders the digest as plain text
+func (d *Digest) Text() string {
+	var buf bytes.Buffer
+	textTemplate.Execute(&buf, templateData{Subject: d.Subject(), Sections: d.sections()})
+	return buf.String()
+}
+
+// HTML renders the digest as an HTML fragment with all values escaped
+func (d *Digest) HTML() string {
+	var buf bytes.Buffer
+	htmlTemplate.Execute(&buf, templateData{Subject: d.Subject(), Sections: d.sections()})
+	return buf.String()
+}
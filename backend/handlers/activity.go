@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ActorHeader identifies the user making a change on endpoints whose path has
+// no user ID, such as the memo or todo creation
+const ActorHeader = "X-User-ID"
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// requestActor returns the user making the request: the user of the path, or
+// the user named by the X-User-ID header when it belongs to the list
+func requestActor(c *gin.Context, listID string) string {
+	if userID := c.Param("userId"); userID != "" {
+		return userID
+	}
+
+	userID := c.GetHeader(ActorHeader)
+	if userID == "" {
+		return ""
+	}
+	var count int64
+	database.DB.Model(&models.User{}).Where("id = ? AND list_id = ?", userID, listID).Count(&count)
+	if count == 0 {
+		return ""
+	}
+	return userID
+}
+
+// recordActivity appends an entry to the activity log of its list. before and
+// after are stored as JSON. Callers make the change and record it in one
+// transaction, so that a change is never made without its entry.
+func recordActivity(db *gorm.DB, activity models.Activity, before, after interface{}) error {
+	return createActivity(db, &activity, before, after)
+}
+
+// createActivity is recordActivity for an entry whose ID the caller needs
+func createActivity(db *gorm.DB, activity *models.Activity, before, after interface{}) error {
+	var err error
+	if activity.Before, err = activityValue(before); err != nil {
+		return err
+	}
+	if activity.After, err = activityValue(after); err != nil {
+		return err
+	}
+	return db.Create(activity).Error
+}
+
+// activityValue encodes a before or after value; nil stays null
+func activityValue(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// todoSnapshot returns the stored fields of a todo as recorded in the activity log
+func todoSnapshot(todo models.Todo) gin.H {
+	return gin.H{
+		"title":       todo.Title,
+		"description": todo.Description,
+		"priority":    todo.Priority,
+		"labels":      todo.Labels,
+		"dueDate":     todo.DueDate,
+		"hasDueTime":  todo.HasDueTime,
+	}
+}
+
+// statusSnapshot returns the check state of a user as recorded in the activity log
+func statusSnapshot(status models.TodoUserStatus) gin.H {
+	return gin.H{
+		"userId":    status.UserID,
+		"isChecked": status.IsChecked,
+		"checkedAt": status.CheckedAt,
+	}
+}
+
+// GetActivity returns the activity log of a list, newest first. Pass the
+// returned nextCursor as ?cursor= to get older entries.
+func GetActivity(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	limit := defaultActivityLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxActivityLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxActivityLimit)})
+			return
+		}
+		limit = n
+	}
+
+	tx := database.DB.Where("list_id = ?", listID)
+	if s := c.Query("cursor"); s != "" {
+		cursor, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		tx = tx.Where("id < ?", cursor)
+	}
+	if actorID := c.Query("actorId"); actorID != "" {
+		tx = tx.Where("actor_id = ?", actorID)
+	}
+	if todoID := c.Query("todoId"); todoID != "" {
+		tx = tx.Where("target_type = ? AND target_id = ?", models.TargetTodo, todoID)
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	activities := []models.Activity{}
+	if err := tx.Order("id DESC").Limit(limit + 1).Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activity"})
+		return
+	}
+
+	var nextCursor *string
+	if len(activities) > limit {
+		activities = activities[:limit]
+		cursor := strconv.FormatUint(uint64(activities[limit-1].ID), 10)
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities": activities,
+		"nextCursor": nextCursor,
+	})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type activityResponse struct {
+	Activities []models.Activity `json:"activities"`
+	NextCursor *string           `json:"nextCursor"`
+}
+
+func (suite *HandlerTestSuite) getActivity(query string) (int, activityResponse) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/activity"+query, nil)
+	suite.router.ServeHTTP(w, req)
+
+	var response activityResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func actions(activities []models.Activity) []string {
+	result := []string{}
+	for _, activity := range activities {
+		result = append(result, activity.Action)
+	}
+	return result
+}
+
+func (suite *HandlerTestSuite) TestActivityLog() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "old"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	// ヘッダーで操作したユーザーを指定する
+	jsonPayload, _ := json.Marshal(map[string]string{"memo": "new"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/lists/test-list-id/memo", bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ActorHeader, "test-user-id")
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	w = suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	statusPath := "/api/todos/" + strconv.Itoa(int(todo.ID)) + "/status/test-user-id"
+	suite.putJSON(statusPath, map[string]bool{"checked": true})
+	suite.putJSON(statusPath, map[string]bool{"checked": false})
+
+	code, response := suite.getActivity("")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{
+		models.ActionTodoReopened,
+		models.ActionTodoUnchecked,
+		models.ActionTodoCompleted,
+		models.ActionTodoChecked,
+		models.ActionTodoCreated,
+		models.ActionMemoUpdated,
+	}, actions(response.Activities))
+	assert.Nil(suite.T(), response.NextCursor)
+
+	memo := response.Activities[5]
+	assert.Equal(suite.T(), "test-user-id", memo.ActorID)
+	assert.JSONEq(suite.T(), `{"memo": "old"}`, string(memo.Before))
+	assert.JSONEq(suite.T(), `{"memo": "new"}`, string(memo.After))
+
+	// チェックした記録は後から外しても残る
+	checked := response.Activities[3]
+	assert.Equal(suite.T(), "test-user-id", checked.ActorID)
+	assert.Equal(suite.T(), strconv.Itoa(int(todo.ID)), checked.TargetID)
+	var after map[string]interface{}
+	json.Unmarshal(checked.After, &after)
+	assert.Equal(suite.T(), true, after["isChecked"])
+	assert.NotNil(suite.T(), after["checkedAt"])
+
+	created := response.Activities[4]
+	assert.Equal(suite.T(), "", created.ActorID)
+	assert.Equal(suite.T(), "null", string(created.Before))
+}
+
+func (suite *HandlerTestSuite) TestActivityFailureRollsBackChange() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "old"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	// 操作履歴を書き込めない場合は変更も行わない
+	suite.Require().NoError(database.DB.Migrator().DropTable(&models.Activity{}))
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	var todos int64
+	database.DB.Model(&models.Todo{}).Count(&todos)
+	assert.Equal(suite.T(), int64(0), todos)
+
+	w = suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "new"})
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "old", list.Memo)
+
+	w = suite.postJSON("/api/lists/test-list-id/users", nil)
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	var users int64
+	database.DB.Model(&models.User{}).Count(&users)
+	assert.Equal(suite.T(), int64(1), users)
+}
+
+func (suite *HandlerTestSuite) TestActivityPagination() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	for i := 0; i < 5; i++ {
+		suite.putJSON("/api/lists/test-list-id/users/test-user-id/name", map[string]string{"name": "Name " + strconv.Itoa(i)})
+	}
+	suite.putJSON("/api/lists/test-list-id/users/other-user-id/name", map[string]string{"name": "Other"})
+
+	_, first := suite.getActivity("?limit=4")
+	assert.Len(suite.T(), first.Activities, 4)
+	suite.Require().NotNil(first.NextCursor)
+
+	_, second := suite.getActivity("?limit=4&cursor=" + *first.NextCursor)
+	assert.Len(suite.T(), second.Activities, 2)
+	assert.Nil(suite.T(), second.NextCursor)
+	assert.JSONEq(suite.T(), `{"displayName": ""}`, string(second.Activities[1].Before))
+
+	_, filtered := suite.getActivity("?actorId=other-user-id")
+	suite.Require().Len(filtered.Activities, 1)
+	assert.JSONEq(suite.T(), `{"displayName": "Other"}`, string(filtered.Activities[0].After))
+
+	code, _ := suite.getActivity("?limit=0")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = suite.getActivity("?cursor=abc")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+}
+
+func (suite *HandlerTestSuite) TestActivityHidesEmail() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"email": "alice@example.com"})
+
+	_, response := suite.getActivity("")
+	suite.Require().Len(response.Activities, 1)
+	assert.Equal(suite.T(), models.ActionPreferencesUpdated, response.Activities[0].Action)
+	assert.NotContains(suite.T(), string(response.Activities[0].After), "alice@example.com")
+	assert.Contains(suite.T(), string(response.Activities[0].After), `"hasEmail":true`)
+}
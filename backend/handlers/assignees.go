@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateTodoAssignees replaces the users assigned to a todo on behalf of a
+// user of its list. Newly assigned users are notified.
+func UpdateTodoAssignees(c *gin.Context) {
+	todo, ok := memberTodo(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserIDs []string `json:"userIds"`
+		Version *int     `json:"version"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	assignees, ok := listUserIDs(database.DB, todo.ListID, req.UserIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee not found in this list"})
+		return
+	}
+
+	version, ok := checkVersion(c, todo.Version, req.Version, todoState(todo.ID))
+	if !ok {
+		return
+	}
+
+	var statuses []models.TodoUserStatus
+	database.DB.Where("todo_id = ?", todo.ID).Order("user_id").Find(&statuses)
+
+	before := []string{}
+	for _, status := range statuses {
+		if status.IsAssigned {
+			before = append(before, status.UserID)
+		}
+	}
+
+	var added []string
+	changed := false
+	actorID := requestActor(c, todo.ListID)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &models.Todo{}, todo.ID, version, nil); err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			assigned := assignees[status.UserID]
+			if assigned == status.IsAssigned {
+				continue
+			}
+			changed = true
+			if assigned {
+				added = append(added, status.UserID)
+			}
+			if err := tx.Model(&status).Update("is_assigned", assigned).Error; err != nil {
+				return err
+			}
+		}
+		if !changed {
+			return nil
+		}
+		return recordActivity(tx, models.Activity{
+			ListID:     todo.ListID,
+			ActorID:    actorID,
+			Action:     models.ActionAssigneesUpdated,
+			TargetType: models.TargetTodo,
+			TargetID:   strconv.FormatUint(uint64(todo.ID), 10),
+		}, gin.H{"assigneeIds": before}, gin.H{"assigneeIds": assigneeIDs(statuses, assignees)})
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, todoState(todo.ID))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update assignees"})
+		return
+	}
+
+	userIDs := assigneeIDs(statuses, assignees)
+
+	var list models.List
+	database.DB.First(&list, "id = ?", todo.ListID)
+	notifyAssigned(database.DB, list, &todo, added)
+
+	version = todoVersion(todo.ID)
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusOK, gin.H{"assigneeIds": userIDs, "version": version})
+}
+
+// assigneeIDs returns the IDs of the users in assignees, in the order of statuses
+func assigneeIDs(statuses []models.TodoUserStatus, assignees map[string]bool) []string {
+	userIDs := []string{}
+	for _, status := range statuses {
+		if assignees[status.UserID] {
+			userIDs = append(userIDs, status.UserID)
+		}
+	}
+	return userIDs
+}
+
+// listUserIDs returns userIDs as a set. It reports false when one of them is
+// not a user of the list.
+func listUserIDs(db *gorm.DB, listID string, userIDs []string) (map[string]bool, bool) {
+	set := map[string]bool{}
+	for _, id := range userIDs {
+		set[id] = true
+	}
+	if len(set) == 0 {
+		return set, true
+	}
+
+	var count int64
+	db.Model(&models.User{}).Where("list_id = ? AND id IN ?", listID, userIDs).Count(&count)
+	return set, int(count) == len(set)
+}
+
+// mentionedUserIDs returns the IDs of the users named by mentions, each a
+// display name, compared case-insensitively, or a user ID
+func mentionedUserIDs(users []models.User, mentions []string) ([]string, error) {
+	var ids []string
+	for _, mention := range mentions {
+		found := false
+		for _, user := range users {
+			if user.ID == mention || (user.DisplayName != "" && strings.EqualFold(user.DisplayName, mention)) {
+				ids = append(ids, user.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("Assignee not found in this list: @" + mention)
+		}
+	}
+	return ids, nil
+}
+
+// mergeIDs appends the IDs of more that are not in ids yet
+func mergeIDs(ids []string, more []string) []string {
+	for _, id := range more {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
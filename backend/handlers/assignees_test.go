@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify"
+	"strconv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureNotifications records the notifications sent by handlers during a test
+func (suite *HandlerTestSuite) captureNotifications() *[]notify.Message {
+	sent := &[]notify.Message{}
+	Notifier = notify.Func(func(ctx context.Context, msg notify.Message) error {
+		*sent = append(*sent, msg)
+		return nil
+	})
+	suite.T().Cleanup(func() { Notifier = nil })
+	return sent
+}
+
+func (suite *HandlerTestSuite) TestAssignTodo() {
+	sent := suite.captureNotifications()
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "alice", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "bob", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "stranger", ListID: "other-list"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]interface{}{"title": "Report", "assigneeIds": []string{"alice"}})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+
+	suite.Require().Len(*sent, 1)
+	assert.Equal(suite.T(), notify.TypeAssigned, (*sent)[0].Type)
+	assert.Equal(suite.T(), "alice", (*sent)[0].User.ID)
+
+	path := "/api/lists/test-list-id/users/alice/todos/" + strconv.Itoa(int(todo.ID)) + "/assignees"
+	w = suite.putJSON(path, map[string][]string{"userIds": {"alice", "bob"}})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"assigneeIds": ["alice", "bob"], "version": 2}`, w.Body.String())
+
+	// 新たに割り当てられたユーザーだけに通知する
+	suite.Require().Len(*sent, 2)
+	assert.Equal(suite.T(), "bob", (*sent)[1].User.ID)
+	assert.Equal(suite.T(), "Assigned: Report", (*sent)[1].Subject)
+
+	w = suite.putJSON(path, map[string][]string{"userIds": {"bob"}})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Len(suite.T(), *sent, 2)
+
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ? AND user_id = ?", todo.ID, "alice").First(&status)
+	assert.False(suite.T(), status.IsAssigned)
+
+	w = suite.putJSON(path, map[string][]string{"userIds": {"stranger"}})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.postJSON("/api/lists/test-list-id/todos", map[string]interface{}{"title": "Other", "assigneeIds": []string{"stranger"}})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestAssignTodoUnauthorized() {
+	sent := suite.captureNotifications()
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "alice", ListID: "test-list-id"})
+	database.DB.Create(&models.List{ID: "other-list"})
+	database.DB.Create(&models.User{ID: "stranger", ListID: "other-list"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	todoID := strconv.Itoa(int(todo.ID))
+
+	// 他のリストのToDoと、リストに属さないユーザーによる変更は拒否する
+	w = suite.putJSON("/api/lists/other-list/users/stranger/todos/"+todoID+"/assignees", map[string][]string{"userIds": {"alice"}})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	w = suite.putJSON("/api/lists/test-list-id/users/stranger/todos/"+todoID+"/assignees", map[string][]string{"userIds": {"alice"}})
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ? AND user_id = ?", todo.ID, "alice").First(&status)
+	assert.False(suite.T(), status.IsAssigned)
+	assert.Empty(suite.T(), *sent)
+}
+
+func (suite *HandlerTestSuite) TestCompletedNotification() {
+	sent := suite.captureNotifications()
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "alice", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "bob", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/alice", map[string]bool{"checked": true})
+	assert.Empty(suite.T(), *sent)
+
+	// 最後にチェックしたユーザー以外に通知する
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/bob", map[string]bool{"checked": true})
+	suite.Require().Len(*sent, 1)
+	assert.Equal(suite.T(), notify.TypeCompleted, (*sent)[0].Type)
+	assert.Equal(suite.T(), "alice", (*sent)[0].User.ID)
+	assert.Equal(suite.T(), "test-list-id", (*sent)[0].List.ID)
+}
+
+func (suite *HandlerTestSuite) TestUpdateUserPreferencesEmail() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]interface{}{
+		"email":         "alice@example.com",
+		"emailOnAssign": false,
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(suite.T(), "alice@example.com", response["email"])
+	assert.Equal(suite.T(), true, response["emailOnComplete"])
+	assert.Equal(suite.T(), false, response["emailOnAssign"])
+
+	// メールアドレスは他のユーザーに公開しない
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/users/test-user-id", nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.NotContains(suite.T(), w.Body.String(), "alice@example.com")
+
+	for _, email := range []string{"not an email", "Alice <alice@example.com>"} {
+		w = suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"email": email})
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, email)
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBatchOperations is the number of operations a batch can carry
+const maxBatchOperations = 200
+
+// Results of an operation of a batch. In an atomic batch that failed, the
+// operations before the failed one are rolled back and the ones after it are
+// skipped.
+const (
+	batchSucceeded  = "succeeded"
+	batchFailed     = "failed"
+	batchRolledBack = "rolledBack"
+	batchSkipped    = "skipped"
+)
+
+// batchOperation is an operation on the todos of a list: "create", "check",
+// "uncheck", "delete" or "priority"
+type batchOperation struct {
+	Op string `json:"op" binding:"required"`
+
+	// create, and priority for the new priority
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Priority    string   `json:"priority"`
+	DueDate     *string  `json:"dueDate"`
+	AssigneeIDs []string `json:"assigneeIds"`
+
+	// check, uncheck, delete and priority. Version is optional and makes the
+	// operation fail when the todo was changed since.
+	TodoID  uint   `json:"todoId"`
+	UserID  string `json:"userId"`
+	Version int    `json:"version"`
+}
+
+// batchResult is the result of an operation of a batch
+type batchResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	TodoID uint         `json:"todoId,omitempty"`
+	Todo   *models.Todo `json:"todo,omitempty"`
+}
+
+// BatchTodos applies many operations on the todos of a list in one request and
+// reports the result of each. An atomic batch applies all operations or none;
+// otherwise each operation succeeds or fails on its own.
+func BatchTodos(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	var req struct {
+		Atomic     bool             `json:"atomic"`
+		Operations []batchOperation `json:"operations" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Operations must contain between 1 and " + strconv.Itoa(maxBatchOperations) + " operations"})
+		return
+	}
+
+	actorID := requestActor(c, listID)
+
+	// ユーザーは一度だけ読み込み、すべての作成で使う
+	var users []models.User
+	database.DB.Where("list_id = ?", listID).Find(&users)
+
+	results := make([]batchResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = batchResult{Index: i, Op: op.Op}
+	}
+
+	if !req.Atomic {
+		for i, op := range req.Operations {
+			// 取り消される可能性があるため通知はコミット後に送る
+			db, sendNotifications := deferNotifications(database.DB)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return applyBatchOperation(tx, list, users, actorID, op, &results[i])
+			})
+			if err != nil {
+				results[i] = batchResult{Index: i, Op: op.Op, Status: batchFailed, Error: batchError(err)}
+				continue
+			}
+			sendNotifications()
+			results[i].Status = batchSucceeded
+		}
+		c.JSON(http.StatusOK, gin.H{"results": results})
+		return
+	}
+
+	// 取り消される可能性があるため通知はコミット後に送る
+	db, sendNotifications := deferNotifications(database.DB)
+	failed := -1
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, op := range req.Operations {
+			if err := applyBatchOperation(tx, list, users, actorID, op, &results[i]); err != nil {
+				failed = i
+				return err
+			}
+			results[i].Status = batchSucceeded
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range results {
+			switch {
+			case failed < 0 || i < failed:
+				results[i] = batchResult{Index: i, Op: results[i].Op, Status: batchRolledBack}
+			case i == failed:
+				results[i] = batchResult{Index: i, Op: results[i].Op, Status: batchFailed, Error: batchError(err)}
+			default:
+				results[i] = batchResult{Index: i, Op: results[i].Op, Status: batchSkipped}
+			}
+		}
+
+		var opErr *operationError
+		if failed < 0 || !errors.As(err, &opErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply operations", "results": results})
+			return
+		}
+		c.JSON(opErr.status, gin.H{"error": fmt.Sprintf("Operation %d failed: %s", failed, opErr.message), "results": results})
+		return
+	}
+	sendNotifications()
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// batchError returns the message reported for a failed operation
+func batchError(err error) string {
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		return opErr.message
+	}
+	return "Failed to apply operation"
+}
+
+// applyBatchOperation applies an operation of a batch in the transaction tx
+// and fills in its result. A client error is returned as an operationError.
+func applyBatchOperation(tx *gorm.DB, list models.List, users []models.User, actorID string, op batchOperation, result *batchResult) error {
+	if op.Op == "create" {
+		if op.Title == "" {
+			return &operationError{http.StatusBadRequest, "Title is required"}
+		}
+		todo, assignees, err := newTodo(tx, list, op.Title, op.Description, op.Priority, op.DueDate, op.AssigneeIDs)
+		if err != nil {
+			return &operationError{http.StatusBadRequest, err.Error()}
+		}
+		if err := createTodo(tx, list, users, &todo, assignees, op.AssigneeIDs, actorID); err != nil {
+			return err
+		}
+		result.TodoID = todo.ID
+		result.Todo = &todo
+		return nil
+	}
+
+	var todo models.Todo
+	if err := tx.Where("id = ? AND list_id = ?", op.TodoID, list.ID).First(&todo).Error; err != nil {
+		return &operationError{http.StatusNotFound, "Todo not found"}
+	}
+	result.TodoID = todo.ID
+
+	switch op.Op {
+	case "check", "uncheck":
+		userID := op.UserID
+		if userID == "" {
+			userID = actorID
+		}
+		if !hasUser(users, userID) {
+			return &operationError{http.StatusBadRequest, "User not found in this list"}
+		}
+		if err := updateVersioned(tx, &models.Todo{}, todo.ID, op.Version, nil); err != nil {
+			return batchConflict(err)
+		}
+		_, err := setChecked(tx, todo, userID, op.Op == "check", time.Now())
+		return err
+
+	case "delete":
+		return batchConflict(deleteTodo(tx, todo, op.Version, actorID))
+
+	case "priority":
+		if op.Priority != "high" && op.Priority != "medium" && op.Priority != "low" {
+			return &operationError{http.StatusBadRequest, "Priority must be 'high', 'medium', or 'low'"}
+		}
+		if err := setPriority(tx, &todo, op.Priority, op.Version, actorID); err != nil {
+			return batchConflict(err)
+		}
+		renderTodo(&todo, time.Now(), models.LoadLocation(list.TimeZone))
+		result.Todo = &todo
+		return nil
+	}
+	return &operationError{http.StatusBadRequest, "Op must be 'create', 'check', 'uncheck', 'delete' or 'priority'"}
+}
+
+// batchConflict reports a version conflict as a client error
+func batchConflict(err error) error {
+	if errors.Is(err, errVersionConflict) {
+		return &operationError{http.StatusConflict, "Version conflict: the todo was changed"}
+	}
+	return err
+}
+
+func hasUser(users []models.User, userID string) bool {
+	for _, user := range users {
+		if user.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// setPriority changes the priority of a todo, unless its version is no longer
+// version when that is not 0, and records it as activity of actorID
+func setPriority(db *gorm.DB, todo *models.Todo, priority string, version int, actorID string) error {
+	before := todo.Priority
+	if err := updateVersioned(db, &models.Todo{}, todo.ID, version, map[string]interface{}{"priority": priority}); err != nil {
+		return err
+	}
+	if err := db.First(todo, todo.ID).Error; err != nil {
+		return err
+	}
+
+	return recordActivity(db, models.Activity{
+		ListID:     todo.ListID,
+		ActorID:    actorID,
+		Action:     models.ActionPriorityUpdated,
+		TargetType: models.TargetTodo,
+		TargetID:   strconv.FormatUint(uint64(todo.ID), 10),
+	}, gin.H{"priority": before}, gin.H{"priority": priority})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) batch(payload interface{}) (int, []batchResult) {
+	w := suite.postJSON("/api/lists/test-list-id/todos/batch", payload)
+
+	var response struct {
+		Results []batchResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Results
+}
+
+func statuses(results []batchResult) []string {
+	result := make([]string, len(results))
+	for i, r := range results {
+		result[i] = r.Status
+	}
+	return result
+}
+
+func (suite *HandlerTestSuite) TestBatchCreate() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	code, results := suite.batch(map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "create", "title": "Milk", "priority": "high"},
+			{"op": "create", "title": "Eggs", "priority": "urgent"},
+			{"op": "create", "title": "Bread", "assigneeIds": []string{"other-user-id"}},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{batchSucceeded, batchFailed, batchSucceeded}, statuses(results))
+	assert.NotEmpty(suite.T(), results[1].Error)
+	suite.Require().NotNil(results[0].Todo)
+	assert.Equal(suite.T(), "high", results[0].Todo.Priority)
+	assert.Len(suite.T(), results[0].Todo.UserStatuses, 2)
+
+	var count int64
+	database.DB.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND is_assigned = ?", results[2].TodoID, true).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+	database.DB.Model(&models.TodoUserStatus{}).Count(&count)
+	assert.Equal(suite.T(), int64(4), count)
+}
+
+func (suite *HandlerTestSuite) TestBatchAtomic() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+	todo := models.Todo{ListID: "test-list-id", Title: "Milk"}
+	database.DB.Create(&todo)
+	database.DB.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "test-user-id"})
+	sent := suite.captureNotifications()
+
+	operations := []map[string]interface{}{
+		{"op": "create", "title": "Bread", "assigneeIds": []string{"other-user-id"}},
+		{"op": "check", "todoId": todo.ID, "userId": "test-user-id"},
+		{"op": "delete", "todoId": 999},
+		{"op": "priority", "todoId": todo.ID, "priority": "low"},
+	}
+	code, results := suite.batch(map[string]interface{}{"atomic": true, "operations": operations})
+	assert.Equal(suite.T(), http.StatusNotFound, code)
+	assert.Equal(suite.T(), []string{batchRolledBack, batchRolledBack, batchFailed, batchSkipped}, statuses(results))
+	assert.Equal(suite.T(), "Todo not found", results[2].Error)
+
+	// 失敗した場合は何も変更されず、通知も送られない
+	var count int64
+	database.DB.Model(&models.Todo{}).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ? AND user_id = ?", todo.ID, "test-user-id").First(&status)
+	assert.False(suite.T(), status.IsChecked)
+	assert.Empty(suite.T(), *sent)
+
+	operations[2] = map[string]interface{}{"op": "uncheck", "todoId": todo.ID, "userId": "other-user-id"}
+	code, results = suite.batch(map[string]interface{}{"atomic": true, "operations": operations})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{batchSucceeded, batchSucceeded, batchSucceeded, batchSucceeded}, statuses(results))
+	assert.Len(suite.T(), *sent, 1)
+
+	var updated models.Todo
+	database.DB.First(&updated, todo.ID)
+	assert.Equal(suite.T(), "low", updated.Priority)
+}
+
+func (suite *HandlerTestSuite) TestBatchUpdates() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	milk := models.Todo{ListID: "test-list-id", Title: "Milk"}
+	eggs := models.Todo{ListID: "test-list-id", Title: "Eggs"}
+	database.DB.Create(&milk)
+	database.DB.Create(&eggs)
+
+	code, results := suite.batch(map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "check", "todoId": milk.ID, "userId": "test-user-id"},
+			{"op": "check", "todoId": eggs.ID, "userId": "unknown-user-id"},
+			{"op": "priority", "todoId": eggs.ID, "priority": "high", "version": 1},
+			{"op": "delete", "todoId": eggs.ID, "version": 1},
+			{"op": "rename", "todoId": eggs.ID},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{batchSucceeded, batchFailed, batchSucceeded, batchFailed, batchFailed}, statuses(results))
+	assert.Contains(suite.T(), results[3].Error, "Version conflict")
+	suite.Require().NotNil(results[2].Todo)
+	assert.Equal(suite.T(), 2, results[2].Todo.Version)
+
+	_, response := suite.getListData("")
+	suite.Require().Len(response.Todos, 2)
+	assert.True(suite.T(), response.Todos[0].IsCompleted)
+	assert.Equal(suite.T(), "high", response.Todos[1].Priority)
+
+	_, activities := suite.getActivity("?todoId=" + strconv.Itoa(int(eggs.ID)))
+	suite.Require().NotEmpty(activities.Activities)
+	assert.Equal(suite.T(), models.ActionPriorityUpdated, activities.Activities[0].Action)
+
+	for _, payload := range []map[string]interface{}{
+		{"operations": []map[string]interface{}{}},
+		{"operations": []map[string]interface{}{{"title": "Milk"}}},
+	} {
+		code, _ := suite.batch(payload)
+		assert.Equal(suite.T(), http.StatusBadRequest, code)
+	}
+}
+
+func (suite *HandlerTestSuite) TestBatchNotifiesCommittedOperations() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Milk"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/other-user-id", map[string]bool{"checked": true})
+
+	// 失敗して巻き戻った操作では完了を通知しない
+	sent := suite.captureNotifications()
+	check := map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "check", "todoId": todo.ID, "userId": "test-user-id"},
+		},
+	}
+	suite.Require().NoError(database.DB.Exec("CREATE TRIGGER fail_activities BEFORE INSERT ON activities BEGIN SELECT RAISE(ABORT, 'unavailable'); END").Error)
+	code, results := suite.batch(check)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{batchFailed}, statuses(results))
+	assert.Empty(suite.T(), *sent)
+
+	suite.Require().NoError(database.DB.Exec("DROP TRIGGER fail_activities").Error)
+	code, results = suite.batch(check)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{batchSucceeded}, statuses(results))
+	assert.Len(suite.T(), *sent, 1)
+}
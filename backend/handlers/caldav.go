@@ -0,0 +1,699 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"shared-todo-backend/caldav"
+	"shared-todo-backend/database"
+	"shared-todo-backend/ical"
+	"shared-todo-backend/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// caldavMaxObjectSize is the size of the largest calendar object a client can put
+const caldavMaxObjectSize = 1 << 20
+
+// CalDAVMethods are the methods routed to CalDAV
+var CalDAVMethods = []string{
+	http.MethodOptions, "PROPFIND", "REPORT", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+}
+
+// caldavAllow is the Allow header of every CalDAV resource
+var caldavAllow = strings.Join(CalDAVMethods, ", ")
+
+// caldavCollection is the list a CalDAV request is for, and the user when the
+// token is the token of a user's calendar feed or a CalDAV token
+type caldavCollection struct {
+	list models.List
+	user models.User
+	// writable is set for a CalDAV token, which changes todos as the user
+	writable bool
+	// home is the URL path of the calendar home, which is also the principal
+	home string
+	loc  *time.Location
+}
+
+// caldavObject is a todo as a calendar object of a collection
+type caldavObject struct {
+	todo models.Todo
+	name string
+	data []byte
+	etag string
+}
+
+// GetCalDAVToken returns the CalDAV token of a user
+func GetCalDAVToken(c *gin.Context) {
+	listID, userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	var token models.CalDAVToken
+	if err := database.DB.Where("list_id = ? AND user_id = ?", listID, userID).First(&token).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "CalDAV token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, caldavTokenResponse(token))
+}
+
+// CreateCalDAVToken creates a new CalDAV token of a user. The old token stops
+// working.
+func CreateCalDAVToken(c *gin.Context) {
+	listID, userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	secret, err := newToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create CalDAV token"})
+		return
+	}
+	token := models.CalDAVToken{Token: secret, ListID: listID, UserID: userID}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ? AND user_id = ?", listID, userID).Delete(&models.CalDAVToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create CalDAV token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, caldavTokenResponse(token))
+}
+
+// DeleteCalDAVToken revokes the CalDAV token of a user
+func DeleteCalDAVToken(c *gin.Context) {
+	listID, userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("list_id = ? AND user_id = ?", listID, userID).Delete(&models.CalDAVToken{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete CalDAV token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "CalDAV token not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func caldavTokenResponse(token models.CalDAVToken) gin.H {
+	return gin.H{
+		"token":     token.Token,
+		"url":       "/api/caldav/" + token.Token + "/",
+		"createdAt": token.CreatedAt,
+	}
+}
+
+// CalDAV serves the list of a token as a CalDAV collection of to-dos at
+// /caldav/:token/<listId>/, within the calendar home /caldav/:token/ that is
+// also the principal of the client. The token of a calendar feed gives
+// read-only access. With the CalDAV token of a user, to-dos the client
+// creates, edits, completes and deletes change the todos as activity of the
+// user, and completing a to-do checks the todo for the user.
+func CalDAV(c *gin.Context) {
+	var col caldavCollection
+	var feed models.CalendarFeed
+	if err := database.DB.First(&feed, "token = ?", c.Param("token")).Error; err != nil {
+		// フィードのトークンは公開されうるため、書き込みにはCalDAVのトークンを使う
+		var token models.CalDAVToken
+		if err := database.DB.First(&token, "token = ?", c.Param("token")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+			return
+		}
+		feed = models.CalendarFeed{ListID: token.ListID, UserID: token.UserID}
+		col.writable = true
+	}
+
+	if err := database.DB.First(&col.list, "id = ?", feed.ListID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+	col.loc = models.LoadLocation(col.list.TimeZone)
+	if feed.UserID != "" {
+		if err := database.DB.Where("id = ? AND list_id = ?", feed.UserID, feed.ListID).First(&col.user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+			return
+		}
+		col.loc = models.LoadLocation(models.EffectiveTimeZone(col.list, col.user))
+	}
+	col.home = strings.TrimSuffix(c.Request.URL.Path, c.Param("path")) + "/"
+
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", caldavAllow)
+	if c.Request.Method == http.MethodOptions {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	listID, name, _ := strings.Cut(strings.Trim(c.Param("path"), "/"), "/")
+	switch {
+	case listID == "" && c.Request.Method == "PROPFIND":
+		col.propfind(c, false)
+	case listID != col.list.ID:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+	case name == "" && c.Request.Method == "PROPFIND":
+		col.propfind(c, true)
+	case name == "" && c.Request.Method == "REPORT":
+		col.report(c)
+	case name == "":
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Method not allowed"})
+	case c.Request.Method == "PROPFIND":
+		col.propfindObject(c, name)
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		col.get(c, name)
+	case c.Request.Method == http.MethodPut:
+		col.put(c, name)
+	case c.Request.Method == http.MethodDelete:
+		col.delete(c, name)
+	default:
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Method not allowed"})
+	}
+}
+
+// href returns the URL path of the collection, or of an object of it
+func (col caldavCollection) href(name string) string {
+	return (&url.URL{Path: col.home + col.list.ID + "/" + name}).EscapedPath()
+}
+
+// parseRequest parses the body of a PROPFIND or REPORT request, responding
+// with 400 when it cannot be parsed
+func (col caldavCollection) parseRequest(c *gin.Context) (caldav.Request, bool) {
+	req, err := caldav.ParseRequest(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return caldav.Request{}, false
+	}
+	return req, true
+}
+
+// propfind responds to a PROPFIND of the calendar home, or of the collection
+// when collection is set. Unless the Depth header is 0 the resources in it are
+// returned too.
+func (col caldavCollection) propfind(c *gin.Context, collection bool) {
+	req, ok := col.parseRequest(c)
+	if !ok {
+		return
+	}
+	depth := c.GetHeader("Depth") != "0"
+
+	home := (&url.URL{Path: col.home}).EscapedPath()
+	principal := caldav.Href(caldav.CurrentUserPrincipal, home)
+	collectionProps := []caldav.Property{
+		caldav.Collection(true),
+		caldav.Text(caldav.DisplayName, col.displayName()),
+		principal,
+		caldav.Components(ical.Todo),
+		caldav.Text(caldav.GetCTag, col.ctag()),
+	}
+
+	var responses []caldav.Response
+	if !collection {
+		responses = append(responses, req.Response(home, []caldav.Property{
+			caldav.Collection(false),
+			caldav.Text(caldav.DisplayName, calendarName),
+			principal,
+			caldav.Href(caldav.CalendarHomeSet, home),
+		}))
+		if depth {
+			responses = append(responses, req.Response(col.href(""), collectionProps))
+		}
+	} else {
+		responses = append(responses, req.Response(col.href(""), collectionProps))
+		if depth {
+			objects, err := col.objects(database.DB)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+				return
+			}
+			for _, object := range objects {
+				responses = append(responses, req.Response(col.href(object.name), object.props()))
+			}
+		}
+	}
+	writeMultistatus(c, responses)
+}
+
+// propfindObject responds to a PROPFIND of an object
+func (col caldavCollection) propfindObject(c *gin.Context, name string) {
+	req, ok := col.parseRequest(c)
+	if !ok {
+		return
+	}
+	object, ok := col.object(c, name)
+	if !ok {
+		return
+	}
+	writeMultistatus(c, []caldav.Response{req.Response(col.href(object.name), object.props())})
+}
+
+// report responds to a calendar-query, which returns every to-do as no
+// other component is stored, or to a calendar-multiget of the collection
+func (col caldavCollection) report(c *gin.Context) {
+	req, ok := col.parseRequest(c)
+	if !ok {
+		return
+	}
+	if req.Report == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported report"})
+		return
+	}
+
+	objects, err := col.objects(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+		return
+	}
+
+	var responses []caldav.Response
+	if req.Report == caldav.CalendarQuery {
+		if len(req.Components) < 2 || req.Components[1] == ical.Todo {
+			for _, object := range objects {
+				responses = append(responses, req.Response(col.href(object.name), object.props()))
+			}
+		}
+		writeMultistatus(c, responses)
+		return
+	}
+
+	byHref := make(map[string]caldavObject)
+	for _, object := range objects {
+		byHref[col.href(object.name)] = object
+	}
+	for _, href := range req.Hrefs {
+		// 絶対URLで指定されてもパスで比べる
+		if u, err := url.Parse(href); err == nil {
+			href = u.EscapedPath()
+		}
+		if object, ok := byHref[href]; ok {
+			responses = append(responses, req.Response(href, object.props()))
+		} else {
+			responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+		}
+	}
+	writeMultistatus(c, responses)
+}
+
+// get responds with the calendar data of an object
+func (col caldavCollection) get(c *gin.Context, name string) {
+	object, ok := col.object(c, name)
+	if !ok {
+		return
+	}
+	c.Header("ETag", object.etag)
+	c.Header("Last-Modified", object.todo.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", object.data)
+}
+
+// put creates a todo from the to-do of a calendar object, or changes the todo
+// of an existing object. A to-do without a priority gets the default one. No
+// ETag is returned as the object is stored as a todo and reads differently,
+// so clients fetch it again.
+func (col caldavCollection) put(c *gin.Context, name string) {
+	if !col.writable {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This calendar is read-only"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, caldavMaxObjectSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read calendar object"})
+		return
+	}
+	if len(data) > caldavMaxObjectSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Calendar object must be 1 MB or less"})
+		return
+	}
+	item, err := ical.ReadTodo(bytes.NewReader(data), col.loc)
+	if errors.Is(err, ical.ErrNoTodo) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only to-dos can be stored in this calendar"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar object", "details": err.Error()})
+		return
+	}
+	if item.Summary == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	object, exists, err := col.find(database.DB, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo"})
+		return
+	}
+	if !col.checkPreconditions(c, object, exists) {
+		return
+	}
+
+	db, sendNotifications := deferNotifications(database.DB)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if exists {
+			return col.updateTodo(tx, object.todo, item)
+		}
+		return col.createTodo(tx, name, item)
+	})
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		c.JSON(opErr.status, gin.H{"error": opErr.message})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save todo"})
+		return
+	}
+	sendNotifications()
+
+	if exists {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.Header("Location", col.href(name))
+	c.Status(http.StatusCreated)
+}
+
+// delete deletes the todo of an object, which can be undone like any deletion
+func (col caldavCollection) delete(c *gin.Context, name string) {
+	if !col.writable {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This calendar is read-only"})
+		return
+	}
+
+	object, ok := col.object(c, name)
+	if !ok || !col.checkPreconditions(c, object, true) {
+		return
+	}
+
+	// 名前は空くので、同じ名前で新しいToDoを作れる
+	db, sendNotifications := deferNotifications(database.DB)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTodo(tx, object.todo, 0, col.user.ID); err != nil {
+			return err
+		}
+		return tx.Where("todo_id = ?", object.todo.ID).Delete(&models.CalDAVObject{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
+		return
+	}
+	sendNotifications()
+	c.Status(http.StatusNoContent)
+}
+
+// checkPreconditions checks the If-Match and If-None-Match headers against an
+// object, responding with 412 when they fail
+func (col caldavCollection) checkPreconditions(c *gin.Context, object caldavObject, exists bool) bool {
+	if header := c.GetHeader("If-Match"); header != "" && (!exists || !matchesETag(header, object.etag)) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed: the resource was changed"})
+		return false
+	}
+	if header := c.GetHeader("If-None-Match"); header != "" && exists && matchesETag(header, object.etag) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed: the resource exists"})
+		return false
+	}
+	return true
+}
+
+// createTodo creates a todo from a to-do put at name in the transaction tx,
+// and checks it for the user when the to-do is completed
+func (col caldavCollection) createTodo(tx *gorm.DB, name string, item ical.Item) error {
+	todo, _, err := newTodo(tx, col.list, item.Summary, item.Description, item.Priority, nil, nil)
+	if err == nil {
+		todo.Labels, err = normalizeLabels(item.Categories)
+	}
+	if err != nil {
+		return &operationError{http.StatusBadRequest, err.Error()}
+	}
+	setDue(&todo, item)
+
+	var users []models.User
+	if err := tx.Where("list_id = ?", col.list.ID).Find(&users).Error; err != nil {
+		return err
+	}
+	if err := createTodo(tx, col.list, users, &todo, nil, nil, col.user.ID); err != nil {
+		return err
+	}
+
+	uid := item.UID
+	if uid == "" {
+		uid = caldavUID(todo.ID)
+	}
+	// 名前がまだ残っているのは、他の方法で削除されたToDoの名前
+	if err := tx.Where("list_id = ? AND name = ?", col.list.ID, name).Delete(&models.CalDAVObject{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&models.CalDAVObject{TodoID: todo.ID, ListID: col.list.ID, Name: name, UID: uid}).Error; err != nil {
+		return err
+	}
+
+	if item.Completed != nil {
+		_, err := setChecked(tx, todo, col.user.ID, true, time.Now().UTC())
+		return err
+	}
+	return nil
+}
+
+// updateTodo changes a todo to a to-do put in its place in the transaction tx.
+// The change of its fields is recorded as one activity, and a change of the
+// completion of the to-do checks or unchecks the todo for the user.
+func (col caldavCollection) updateTodo(tx *gorm.DB, todo models.Todo, item ical.Item) error {
+	// 入力の検証は作成時と同じ規則で行う
+	edited, _, err := newTodo(tx, col.list, item.Summary, item.Description, item.Priority, nil, nil)
+	if err == nil {
+		edited.Labels, err = normalizeLabels(item.Categories)
+	}
+	if err != nil {
+		return &operationError{http.StatusBadRequest, err.Error()}
+	}
+	setDue(&edited, item)
+
+	updates := map[string]interface{}{}
+	if edited.Title != todo.Title {
+		updates["title"] = edited.Title
+	}
+	if edited.Description != todo.Description {
+		updates["description"] = edited.Description
+	}
+	if edited.Priority != todo.Priority {
+		updates["priority"] = edited.Priority
+	}
+	if !slices.Equal(edited.Labels, todo.Labels) {
+		updates["labels"] = edited.Labels
+	}
+	if !sameDue(edited, todo) {
+		updates["due_date"] = edited.DueDate
+		updates["has_due_time"] = edited.HasDueTime
+	}
+	if len(updates) > 0 {
+		if err := updateVersioned(tx, &models.Todo{}, todo.ID, 0, updates); err != nil {
+			return err
+		}
+		if err := recordActivity(tx, models.Activity{
+			ListID:     col.list.ID,
+			ActorID:    col.user.ID,
+			Action:     models.ActionTodoUpdated,
+			TargetType: models.TargetTodo,
+			TargetID:   strconv.FormatUint(uint64(todo.ID), 10),
+		}, todoSnapshot(todo), todoSnapshot(edited)); err != nil {
+			return err
+		}
+	}
+
+	checked := false
+	for _, status := range todo.UserStatuses {
+		if status.UserID == col.user.ID {
+			checked = status.IsChecked
+		}
+	}
+	if checked != (item.Completed != nil) {
+		_, err := setChecked(tx, todo, col.user.ID, !checked, time.Now().UTC())
+		return err
+	}
+	return nil
+}
+
+// setDue sets the due date of a todo to the one of a to-do
+func setDue(todo *models.Todo, item ical.Item) {
+	todo.DueDate = nil
+	todo.HasDueTime = false
+	if !item.Due.IsZero() {
+		due := item.Due
+		todo.DueDate = &due
+		todo.HasDueTime = item.HasTime
+	}
+}
+
+// sameDue reports whether two todos have the same due date
+func sameDue(a, b models.Todo) bool {
+	if a.DueDate == nil || b.DueDate == nil {
+		return a.DueDate == b.DueDate
+	}
+	return a.DueDate.Equal(*b.DueDate) && a.HasDueTime == b.HasDueTime
+}
+
+// objects returns the todos of the collection as calendar objects, in the
+// order they were created
+func (col caldavCollection) objects(db *gorm.DB) ([]caldavObject, error) {
+	var todos []models.Todo
+	if err := db.Where("list_id = ?", col.list.ID).Preload("UserStatuses").Order("created_at, id").Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	var created []models.CalDAVObject
+	if err := db.Where("list_id = ?", col.list.ID).Find(&created).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]models.CalDAVObject)
+	for _, object := range created {
+		names[object.TodoID] = object
+	}
+
+	objects := make([]caldavObject, 0, len(todos))
+	for _, todo := range todos {
+		object, err := col.newObject(todo, names[todo.ID])
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// find returns the object at name, which is the name its client gave it or,
+// for another todo, "<id>.ics"
+func (col caldavCollection) find(db *gorm.DB, name string) (caldavObject, bool, error) {
+	var created []models.CalDAVObject
+	if err := db.Where("list_id = ? AND name = ?", col.list.ID, name).Limit(1).Find(&created).Error; err != nil {
+		return caldavObject{}, false, err
+	}
+
+	var todoID uint64
+	if len(created) > 0 {
+		todoID = uint64(created[0].TodoID)
+	} else {
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, ".ics"), 10, 64)
+		if err != nil || name != strconv.FormatUint(id, 10)+".ics" {
+			return caldavObject{}, false, nil
+		}
+		// クライアントが名前を付けたToDoは、その名前でだけ見つかる
+		if err := db.Where("todo_id = ?", id).Limit(1).Find(&created).Error; err != nil {
+			return caldavObject{}, false, err
+		}
+		if len(created) > 0 {
+			return caldavObject{}, false, nil
+		}
+		todoID = id
+	}
+
+	var todos []models.Todo
+	if err := db.Where("id = ? AND list_id = ?", todoID, col.list.ID).Preload("UserStatuses").Limit(1).Find(&todos).Error; err != nil {
+		return caldavObject{}, false, err
+	}
+	if len(todos) == 0 {
+		return caldavObject{}, false, nil
+	}
+
+	var object models.CalDAVObject
+	if len(created) > 0 {
+		object = created[0]
+	}
+	result, err := col.newObject(todos[0], object)
+	return result, err == nil, err
+}
+
+// object returns the object at name, responding with 404 when there is none
+func (col caldavCollection) object(c *gin.Context, name string) (caldavObject, bool) {
+	object, exists, err := col.find(database.DB, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo"})
+		return caldavObject{}, false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return caldavObject{}, false
+	}
+	return object, true
+}
+
+// newObject writes a todo as the calendar object of the user, with the name
+// and UID of created when a client created it
+func (col caldavCollection) newObject(todo models.Todo, created models.CalDAVObject) (caldavObject, error) {
+	object := caldavObject{todo: todo, name: created.Name}
+	item := calendarItem(todo, col.user.ID)
+	item.UID = created.UID
+	if created.Name == "" {
+		object.name = strconv.FormatUint(uint64(todo.ID), 10) + ".ics"
+		item.UID = caldavUID(todo.ID)
+	}
+
+	var buf bytes.Buffer
+	if err := ical.WriteObject(&buf, item); err != nil {
+		return caldavObject{}, err
+	}
+	object.data = buf.Bytes()
+	sum := sha256.Sum256(object.data)
+	object.etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+	return object, nil
+}
+
+// props returns the properties of an object
+func (object caldavObject) props() []caldav.Property {
+	return []caldav.Property{
+		{Name: caldav.ResourceType},
+		caldav.Text(caldav.GetETag, object.etag),
+		caldav.Text(caldav.GetContentType, "text/calendar; charset=utf-8; component=VTODO"),
+		caldav.Text(caldav.GetLastModified, object.todo.UpdatedAt.UTC().Format(http.TimeFormat)),
+		caldav.Text(caldav.CalendarData, string(object.data)),
+	}
+}
+
+// displayName returns the name of the collection, which names the user of a
+// user's calendar
+func (col caldavCollection) displayName() string {
+	if col.user.DisplayName != "" {
+		return calendarName + " - " + col.user.DisplayName
+	}
+	return calendarName
+}
+
+// ctag returns the tag of the state of the collection, which changes with
+// every change of the list
+func (col caldavCollection) ctag() string {
+	cursor, _ := latestChange(col.list)
+	return strconv.FormatUint(uint64(cursor), 10)
+}
+
+// caldavUID returns the UID of a todo that was not created by a CalDAV client
+func caldavUID(todoID uint) string {
+	return fmt.Sprintf("todo-%d@shared-todo", todoID)
+}
+
+func writeMultistatus(c *gin.Context, responses []caldav.Response) {
+	var buf bytes.Buffer
+	if err := caldav.WriteMultistatus(&buf, responses); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write response"})
+		return
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/stretchr/testify/assert"
+)
+
+// caldavClient returns a CalDAV client of the calendar feed or CalDAV token
+// created at path, and the path of its calendar home
+func (suite *HandlerTestSuite) caldavClient(path string) (*caldav.Client, string) {
+	home := suite.createCalendarFeed(path)
+	if token, ok := strings.CutPrefix(home, "/api/calendar/"); ok {
+		home = "/api/caldav/" + strings.TrimSuffix(token, ".ics") + "/"
+	}
+	server := httptest.NewServer(suite.router)
+	suite.T().Cleanup(server.Close)
+
+	client, err := caldav.NewClient(server.Client(), server.URL+home)
+	suite.Require().NoError(err)
+	return client, home
+}
+
+// caldavTodo returns a calendar of a to-do as a task application would put it
+func caldavTodo(uid, summary string, completed bool) *ical.Calendar {
+	todo := ical.NewComponent(ical.CompToDo)
+	todo.Props.SetText(ical.PropUID, uid)
+	todo.Props.SetDateTime(ical.PropDateTimeStamp, time.Now())
+	todo.Props.SetText(ical.PropSummary, summary)
+	todo.Props.SetText(ical.PropPriority, "1")
+	todo.Props.SetDate(ical.PropDue, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	categories := ical.NewProp(ical.PropCategories)
+	categories.SetTextList([]string{"home", "urgent"})
+	todo.Props.Set(categories)
+	if completed {
+		todo.Props.SetText(ical.PropStatus, "COMPLETED")
+	}
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//test//EN")
+	cal.Children = append(cal.Children, todo)
+	return cal
+}
+
+// caldavPath returns the path of a todo that was not created by a CalDAV client
+func caldavPath(home string, todoID uint) string {
+	return home + "test-list-id/" + strconv.FormatUint(uint64(todoID), 10) + ".ics"
+}
+
+func todoStatus(object caldav.CalendarObject) string {
+	status, _ := object.Data.Children[0].Props.Text(ical.PropStatus)
+	return status
+}
+
+func (suite *HandlerTestSuite) TestCalDAVDiscovery() {
+	milk, _ := suite.calendarTodos()
+	client, home := suite.caldavClient("/api/lists/test-list-id/users/test-user-id/calendar")
+	ctx := context.Background()
+
+	principal, err := client.FindCurrentUserPrincipal(ctx)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), home, principal)
+
+	homeSet, err := client.FindCalendarHomeSet(ctx, principal)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), home, homeSet)
+
+	calendars, err := client.FindCalendars(ctx, homeSet)
+	suite.Require().NoError(err)
+	suite.Require().Len(calendars, 1)
+	assert.Equal(suite.T(), home+"test-list-id/", calendars[0].Path)
+	assert.Equal(suite.T(), "Shared ToDo - Alice", calendars[0].Name)
+	assert.Equal(suite.T(), []string{"VTODO"}, calendars[0].SupportedComponentSet)
+
+	// 期限のないToDoや他の人の担当のToDoも含め、自分のチェックを完了として示す
+	objects, err := client.QueryCalendar(ctx, calendars[0].Path, &caldav.CalendarQuery{
+		CompRequest: caldav.CalendarCompRequest{Name: "VCALENDAR", AllProps: true, AllComps: true},
+		CompFilter:  caldav.CompFilter{Name: "VCALENDAR", Comps: []caldav.CompFilter{{Name: "VTODO"}}},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(objects, 3)
+	summaries := map[string]string{}
+	for _, object := range objects {
+		assert.NotEmpty(suite.T(), object.ETag)
+		summary, _ := object.Data.Children[0].Props.Text(ical.PropSummary)
+		summaries[summary] = todoStatus(object)
+	}
+	assert.Equal(suite.T(), map[string]string{"Milk": "COMPLETED", "Call": "NEEDS-ACTION", "Someday": "NEEDS-ACTION"}, summaries)
+
+	objects, err = client.QueryCalendar(ctx, calendars[0].Path, &caldav.CalendarQuery{
+		CompFilter: caldav.CompFilter{Name: "VCALENDAR", Comps: []caldav.CompFilter{{Name: "VEVENT"}}},
+	})
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), objects)
+
+	objects, err = client.MultiGetCalendar(ctx, calendars[0].Path, &caldav.CalendarMultiGet{
+		Paths: []string{caldavPath(home, milk.ID)},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(objects, 1)
+	assert.Equal(suite.T(), caldavPath(home, milk.ID), objects[0].Path)
+
+	object, err := client.GetCalendarObject(ctx, caldavPath(home, milk.ID))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), objects[0].ETag, object.ETag)
+	uid, _ := object.Data.Children[0].Props.Text(ical.PropUID)
+	assert.Equal(suite.T(), caldavUID(milk.ID), uid)
+}
+
+func (suite *HandlerTestSuite) TestCalDAVChanges() {
+	milk, _ := suite.calendarTodos()
+	client, home := suite.caldavClient("/api/lists/test-list-id/users/test-user-id/caldav")
+	ctx := context.Background()
+	path := home + "test-list-id/2F1C-task.ics"
+
+	// クライアントが作ったToDoは、その名前とUIDのまま読める
+	_, err := client.PutCalendarObject(ctx, path, caldavTodo("2F1C@client", "Paint", false))
+	suite.Require().NoError(err)
+
+	var object models.CalDAVObject
+	suite.Require().NoError(database.DB.First(&object, "name = ?", "2F1C-task.ics").Error)
+	var todo models.Todo
+	suite.Require().NoError(database.DB.Preload("UserStatuses").First(&todo, object.TodoID).Error)
+	assert.Equal(suite.T(), "Paint", todo.Title)
+	assert.Equal(suite.T(), "high", todo.Priority)
+	assert.Equal(suite.T(), models.Labels{"home", "urgent"}, todo.Labels)
+	suite.Require().NotNil(todo.DueDate)
+	assert.Equal(suite.T(), "2024-06-01", todo.DueDate.UTC().Format("2006-01-02"))
+	assert.False(suite.T(), todo.HasDueTime)
+	assert.Len(suite.T(), todo.UserStatuses, 2)
+
+	got, err := client.GetCalendarObject(ctx, path)
+	suite.Require().NoError(err)
+	uid, _ := got.Data.Children[0].Props.Text(ical.PropUID)
+	assert.Equal(suite.T(), "2F1C@client", uid)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get(caldavPath(home, todo.ID)).Code)
+
+	// 完了にするとユーザーのチェックになり、全員がチェックするまで完了にはならない
+	_, err = client.PutCalendarObject(ctx, path, caldavTodo("2F1C@client", "Paint the fence", true))
+	suite.Require().NoError(err)
+	suite.Require().NoError(database.DB.Preload("UserStatuses").First(&todo, object.TodoID).Error)
+	assert.Equal(suite.T(), "Paint the fence", todo.Title)
+	assert.Equal(suite.T(), 2, todo.Version)
+	assert.False(suite.T(), todo.IsCompleted)
+	for _, status := range todo.UserStatuses {
+		assert.Equal(suite.T(), status.UserID == "test-user-id", status.IsChecked)
+	}
+
+	_, activity := suite.getActivity("")
+	assert.Subset(suite.T(), actions(activity.Activities), []string{models.ActionTodoCreated, models.ActionTodoUpdated, models.ActionTodoChecked})
+	for _, a := range activity.Activities {
+		if a.TargetID == strconv.FormatUint(uint64(todo.ID), 10) {
+			assert.Equal(suite.T(), "test-user-id", a.ActorID)
+		}
+	}
+
+	// 既存のToDoも編集でき、完了を外すとチェックが外れる
+	milkPath := caldavPath(home, milk.ID)
+	got, err = client.GetCalendarObject(ctx, milkPath)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "COMPLETED", todoStatus(*got))
+	_, err = client.PutCalendarObject(ctx, milkPath, caldavTodo(caldavUID(milk.ID), "Milk", false))
+	suite.Require().NoError(err)
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ? AND user_id = ?", milk.ID, "test-user-id").First(&status)
+	assert.False(suite.T(), status.IsChecked)
+
+	// 削除は取り消せる削除になる
+	suite.Require().NoError(client.RemoveAll(ctx, path))
+	assert.Error(suite.T(), database.DB.First(&models.Todo{}, todo.ID).Error)
+	_, err = client.GetCalendarObject(ctx, path)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *HandlerTestSuite) TestCalDAVPutAfterDelete() {
+	suite.calendarTodos()
+	client, home := suite.caldavClient("/api/lists/test-list-id/users/test-user-id/caldav")
+	ctx := context.Background()
+	path := home + "test-list-id/abc.ics"
+
+	// 削除した名前で作り直せる
+	_, err := client.PutCalendarObject(ctx, path, caldavTodo("abc@client", "Paint", false))
+	suite.Require().NoError(err)
+	suite.Require().NoError(client.RemoveAll(ctx, path))
+	_, err = client.PutCalendarObject(ctx, path, caldavTodo("abc@client", "Paint again", false))
+	suite.Require().NoError(err)
+
+	got, err := client.GetCalendarObject(ctx, path)
+	suite.Require().NoError(err)
+	summary, _ := got.Data.Children[0].Props.Text(ical.PropSummary)
+	assert.Equal(suite.T(), "Paint again", summary)
+
+	// 他の方法で削除したToDoの名前も使える
+	var object models.CalDAVObject
+	suite.Require().NoError(database.DB.First(&object, "name = ?", "abc.ics").Error)
+	w := suite.sendAs("DELETE", "/api/lists/test-list-id/users/test-user-id/todos/"+strconv.FormatUint(uint64(object.TodoID), 10), "test-user-id", nil)
+	suite.Require().Equal(http.StatusNoContent, w.Code)
+	_, err = client.PutCalendarObject(ctx, path, caldavTodo("abc@client", "Paint once more", false))
+	suite.Require().NoError(err)
+
+	var count int64
+	database.DB.Model(&models.CalDAVObject{}).Where("name = ?", "abc.ics").Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *HandlerTestSuite) TestCalDAVRequests() {
+	milk, _ := suite.calendarTodos()
+	_, home := suite.caldavClient("/api/lists/test-list-id/users/test-user-id/caldav")
+	_, listHome := suite.caldavClient("/api/lists/test-list-id/calendar")
+	_, feedHome := suite.caldavClient("/api/lists/test-list-id/users/test-user-id/calendar")
+	object := caldavPath(home, milk.ID)
+
+	send := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+	todo := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:x\r\nSUMMARY:Milk\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+	w := send("OPTIONS", home, "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Header().Get("DAV"), "calendar-access")
+
+	w = send("PROPFIND", home+"test-list-id/", `<propfind xmlns="DAV:"><prop><getetag/><resourcetype/></prop></propfind>`, map[string]string{"Depth": "1"})
+	assert.Equal(suite.T(), http.StatusMultiStatus, w.Code)
+	assert.Equal(suite.T(), 4, strings.Count(w.Body.String(), "<d:response>"))
+	assert.Contains(suite.T(), w.Body.String(), "<d:getetag></d:getetag></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>")
+
+	// ETag が古ければ変更しない
+	etag := send("GET", object, "", nil).Header().Get("ETag")
+	assert.NotEmpty(suite.T(), etag)
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, send("PUT", object, todo, map[string]string{"If-Match": `"stale"`}).Code)
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, send("PUT", object, todo, map[string]string{"If-None-Match": "*"}).Code)
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, send("DELETE", object, "", map[string]string{"If-Match": `"stale"`}).Code)
+	assert.Equal(suite.T(), http.StatusNoContent, send("PUT", object, todo, map[string]string{"If-Match": etag}).Code)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, send("PUT", home+"test-list-id/new.ics", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:x\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, send("PUT", home+"test-list-id/new.ics", strings.ReplaceAll(todo, "VTODO", "VEVENT"), nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, send("PROPFIND", home, "<propfind", nil).Code)
+
+	// フィードのトークンでは読むだけ
+	for _, feed := range []string{listHome, feedHome} {
+		assert.Equal(suite.T(), http.StatusOK, send("GET", caldavPath(feed, milk.ID), "", nil).Code)
+		assert.Equal(suite.T(), http.StatusForbidden, send("PUT", caldavPath(feed, milk.ID), todo, nil).Code)
+		assert.Equal(suite.T(), http.StatusForbidden, send("PUT", feed+"test-list-id/new.ics", todo, nil).Code)
+		assert.Equal(suite.T(), http.StatusForbidden, send("DELETE", caldavPath(feed, milk.ID), "", nil).Code)
+	}
+
+	assert.Equal(suite.T(), http.StatusNotFound, send("GET", home+"other-list/1.ics", "", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, send("GET", home+"test-list-id/99.ics", "", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, send("PROPFIND", "/api/caldav/unknown/", "", nil).Code)
+}
+
+func (suite *HandlerTestSuite) TestCalDAVToken() {
+	suite.calendarTodos()
+	path := "/api/lists/test-list-id/users/test-user-id/caldav"
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get(path).Code)
+
+	home := suite.createCalendarFeed(path)
+	token := strings.TrimSuffix(strings.TrimPrefix(home, "/api/caldav/"), "/")
+	assert.Equal(suite.T(), http.StatusOK, suite.get(path).Code)
+	assert.Equal(suite.T(), http.StatusMultiStatus, suite.sendAs("PROPFIND", home, "", nil).Code)
+
+	// CalDAVのトークンはフィードとしては使えない
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/api/calendar/"+token+".ics").Code)
+
+	// 作り直すと古いトークンは使えなくなる
+	newHome := suite.createCalendarFeed(path)
+	assert.NotEqual(suite.T(), home, newHome)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.sendAs("PROPFIND", home, "", nil).Code)
+
+	assert.Equal(suite.T(), http.StatusNoContent, suite.sendAs("DELETE", path, "", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.sendAs("PROPFIND", newHome, "", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.sendAs("DELETE", path, "", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.postJSON("/api/lists/test-list-id/users/missing/caldav", nil).Code)
+}
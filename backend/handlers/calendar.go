@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/ical"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// calendarName is the name calendar applications show for a feed
+const calendarName = "Shared ToDo"
+
+// GetCalendarFeed returns the calendar feed of a list, or of a user of the
+// list on the /users/:userId route
+func GetCalendarFeed(c *gin.Context) {
+	listID, userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	var feed models.CalendarFeed
+	if err := database.DB.Where("list_id = ? AND user_id = ?", listID, userID).First(&feed).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarFeedResponse(feed))
+}
+
+// CreateCalendarFeed creates the calendar feed of a list or of a user with a
+// new token. The old token stops working.
+func CreateCalendarFeed(c *gin.Context) {
+	listID, userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	token, err := newToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+	feed := models.CalendarFeed{Token: token, ListID: listID, UserID: userID}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ? AND user_id = ?", listID, userID).Delete(&models.CalendarFeed{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&feed).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, calendarFeedResponse(feed))
+}
+
+// DeleteCalendarFeed revokes the token of the calendar feed of a list or of a user
+func DeleteCalendarFeed(c *gin.Context) {
+	listID, userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("list_id = ? AND user_id = ?", listID, userID).Delete(&models.CalendarFeed{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar feed"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newToken returns a new secret token for URLs
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// calendarOwner returns the list and the user, or "" for the whole list, of a
+// calendar feed route. It responds with 404 when either does not exist.
+func calendarOwner(c *gin.Context) (string, string, bool) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return "", "", false
+	}
+
+	if userID != "" {
+		var user models.User
+		if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+			return "", "", false
+		}
+	}
+	return listID, userID, true
+}
+
+func calendarFeedResponse(feed models.CalendarFeed) gin.H {
+	return gin.H{
+		"token":     feed.Token,
+		"url":       "/api/calendar/" + feed.Token + ".ics",
+		"createdAt": feed.CreatedAt,
+	}
+}
+
+// GetCalendar returns the todos with a due date of the feed of a token as an
+// iCalendar calendar, as events or, with ?type=todo, as to-dos. A list feed
+// shows whether each todo is completed. A user feed leaves out the todos
+// assigned only to others and shows whether the user checked each todo.
+func GetCalendar(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feed models.CalendarFeed
+	if err := database.DB.First(&feed, "token = ?", token).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", feed.ListID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	calendar := ical.Calendar{Name: calendarName, TimeZone: list.TimeZone, Component: ical.Event}
+	if c.Query("type") == "todo" {
+		calendar.Component = ical.Todo
+	}
+
+	// ユーザーのフィードは同じToDoでも別の予定になるようUIDを変える
+	uidSuffix := ""
+	if feed.UserID != "" {
+		var user models.User
+		if err := database.DB.Where("id = ? AND list_id = ?", feed.UserID, feed.ListID).First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+			return
+		}
+		if user.DisplayName != "" {
+			calendar.Name += " - " + user.DisplayName
+		}
+		calendar.TimeZone = models.EffectiveTimeZone(list, user)
+		sum := sha256.Sum256([]byte(feed.UserID))
+		uidSuffix = "-" + hex.EncodeToString(sum[:6])
+	}
+
+	var todos []models.Todo
+	database.DB.Where("list_id = ? AND due_date IS NOT NULL", feed.ListID).Preload("UserStatuses").Order("due_date, id").Find(&todos)
+
+	for _, todo := range todos {
+		if feed.UserID != "" && assignedToOthers(todo, feed.UserID) {
+			continue
+		}
+		item := calendarItem(todo, feed.UserID)
+		item.UID = fmt.Sprintf("todo-%d%s@shared-todo", todo.ID, uidSuffix)
+		calendar.Items = append(calendar.Items, item)
+	}
+
+	var buf bytes.Buffer
+	if err := calendar.Write(&buf, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write calendar"})
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// calendarItem returns a todo as an item of a calendar without a UID. The item
+// is completed when the todo is, or for a user when the user checked the todo.
+func calendarItem(todo models.Todo, userID string) ical.Item {
+	item := ical.Item{
+		Summary:     todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		Categories:  todo.Labels,
+		Created:     todo.CreatedAt,
+		Modified:    todo.UpdatedAt,
+	}
+	if todo.DueDate != nil {
+		item.Due = todo.DueDate.UTC()
+		item.HasTime = todo.HasDueTime
+	}
+
+	if userID == "" {
+		if todo.IsCompleted {
+			item.Completed = completedAt(todo.CompletedAt, todo.UpdatedAt)
+		}
+		return item
+	}
+	for _, status := range todo.UserStatuses {
+		if status.UserID == userID && status.IsChecked {
+			item.Completed = completedAt(status.CheckedAt, todo.UpdatedAt)
+		}
+	}
+	return item
+}
+
+// assignedToOthers reports whether a todo is assigned only to users other than userID
+func assignedToOthers(todo models.Todo, userID string) bool {
+	assigned := false
+	for _, status := range todo.UserStatuses {
+		if status.UserID == userID && status.IsAssigned {
+			return false
+		}
+		assigned = assigned || status.IsAssigned
+	}
+	return assigned
+}
+
+// completedAt returns when a todo was completed, or fallback when that is unknown
+func completedAt(at *time.Time, fallback time.Time) *time.Time {
+	if at == nil {
+		return &fallback
+	}
+	return at
+}
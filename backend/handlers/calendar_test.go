@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) createCalendarFeed(path string) string {
+	w := suite.postJSON(path, nil)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var response struct {
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(suite.T(), response.Token, 64)
+	return response.URL
+}
+
+func (suite *HandlerTestSuite) calendarTodos() (models.Todo, models.Todo) {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	due := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dueTime := time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)
+	checkedAt := time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)
+	milk := models.Todo{ListID: "test-list-id", Title: "Milk", Priority: "high", DueDate: &due}
+	call := models.Todo{ListID: "test-list-id", Title: "Call", DueDate: &dueTime, HasDueTime: true}
+	database.DB.Create(&milk)
+	database.DB.Create(&call)
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Someday"})
+	database.DB.Create(&models.TodoUserStatus{TodoID: milk.ID, UserID: "test-user-id", IsChecked: true, CheckedAt: &checkedAt})
+	database.DB.Create(&models.TodoUserStatus{TodoID: milk.ID, UserID: "other-user-id"})
+	database.DB.Create(&models.TodoUserStatus{TodoID: call.ID, UserID: "test-user-id"})
+	database.DB.Create(&models.TodoUserStatus{TodoID: call.ID, UserID: "other-user-id", IsAssigned: true})
+	return milk, call
+}
+
+func (suite *HandlerTestSuite) TestListCalendar() {
+	suite.calendarTodos()
+	url := suite.createCalendarFeed("/api/lists/test-list-id/calendar")
+
+	w := suite.get(url)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(suite.T(), body, "X-WR-CALNAME:Shared ToDo\r\n")
+	assert.Contains(suite.T(), body, "X-WR-TIMEZONE:Asia/Tokyo\r\n")
+	assert.Equal(suite.T(), 2, strings.Count(body, "BEGIN:VEVENT"))
+	assert.Contains(suite.T(), body, "SUMMARY:Milk\r\n")
+	assert.Contains(suite.T(), body, "PRIORITY:1\r\n")
+	assert.Contains(suite.T(), body, "DTSTART;VALUE=DATE:20240501\r\n")
+	assert.Contains(suite.T(), body, "DTSTART:20240502T093000Z\r\n")
+	assert.NotContains(suite.T(), body, "Someday")
+
+	w = suite.get(url + "?type=todo")
+	body = w.Body.String()
+	assert.Equal(suite.T(), 2, strings.Count(body, "BEGIN:VTODO"))
+	assert.Equal(suite.T(), 2, strings.Count(body, "STATUS:NEEDS-ACTION"))
+
+	// 作り直すと古いURLは使えなくなる
+	newURL := suite.createCalendarFeed("/api/lists/test-list-id/calendar")
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get(url).Code)
+	assert.Equal(suite.T(), http.StatusOK, suite.get(newURL).Code)
+
+	w = suite.get("/api/lists/test-list-id/calendar")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), newURL)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/lists/test-list-id/calendar", nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get(newURL).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/api/lists/test-list-id/calendar").Code)
+}
+
+func (suite *HandlerTestSuite) TestUserCalendar() {
+	suite.calendarTodos()
+	url := suite.createCalendarFeed("/api/lists/test-list-id/users/test-user-id/calendar")
+	otherURL := suite.createCalendarFeed("/api/lists/test-list-id/users/other-user-id/calendar")
+	assert.NotEqual(suite.T(), url, otherURL)
+
+	// 他の人の担当のToDoは含まず、自分のチェックを完了として示す
+	body := suite.get(url + "?type=todo").Body.String()
+	assert.Contains(suite.T(), body, "X-WR-CALNAME:Shared ToDo - Alice\r\n")
+	assert.Equal(suite.T(), 1, strings.Count(body, "BEGIN:VTODO"))
+	assert.Contains(suite.T(), body, "SUMMARY:Milk\r\n")
+	assert.Contains(suite.T(), body, "STATUS:COMPLETED\r\nCOMPLETED:20240430T120000Z\r\n")
+	assert.NotContains(suite.T(), body, "test-user-id")
+
+	body = suite.get(otherURL + "?type=todo").Body.String()
+	assert.Equal(suite.T(), 2, strings.Count(body, "BEGIN:VTODO"))
+	assert.Equal(suite.T(), 2, strings.Count(body, "STATUS:NEEDS-ACTION"))
+
+	assert.Equal(suite.T(), http.StatusNotFound, suite.postJSON("/api/lists/test-list-id/users/missing/calendar", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/api/calendar/unknown.ics").Code)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checklistItem matches an item of a Markdown checklist, such as "- [x] Milk"
+var checklistItem = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s+(.*)$`)
+
+// ExportChecklist returns the todos of a list as a GitHub-style Markdown
+// checklist. Each item has the priority as in todo.txt, the title, the labels
+// as +projects and the due date as due:, and is followed by the description
+// indented under it. Completed todos are checked. A line of a description that
+// would be read as an item or a code fence, or that starts with a backslash,
+// is escaped with a backslash. Whitespace at the end of a line is left out.
+func ExportChecklist(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	_, todos := exportData(database.DB, list.ID)
+	loc := models.LoadLocation(list.TimeZone)
+
+	var b strings.Builder
+	for _, todo := range todos {
+		box := "[ ]"
+		if todo.IsCompleted {
+			box = "[x]"
+		}
+		b.WriteString("- " + box + " (" + todoTxtPriorities[todo.Priority] + ") " + todoText(todo, loc) + "\n")
+		for _, line := range strings.Split(todo.Description, "\n") {
+			if line = strings.TrimRight(line, " \t\r"); line != "" {
+				if strings.HasPrefix(line, `\`) || isChecklistSyntax(line) {
+					line = `\` + line
+				}
+				b.WriteString("  " + line + "\n")
+			} else if todo.Description != "" {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="todos.md"`)
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(b.String()))
+}
+
+// ImportChecklist creates todos from the items of a Markdown checklist, sent
+// as the body or as the file field of a form. Other Markdown, such as the rest
+// of a README, is skipped, and so are code blocks. Items are read like the
+// tasks of todo.txt, and a checked item is imported as completed by everyone.
+// Lines indented under an item are its description, and keep the indentation
+// beyond that of the item's text.
+func ImportChecklist(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	data, err := readImport(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var users []models.User
+	database.DB.Where("list_id = ?", listID).Order("created_at, id").Find(&users)
+
+	importList(c, database.DB, list, users, parseChecklist(data), nil)
+}
+
+// parseChecklist reads the items of a Markdown checklist
+func parseChecklist(data []byte) []importedTodo {
+	var todos []importedTodo
+	// current は説明を読んでいる項目、indent はその本文の字下げ、blank はその後の空行の数
+	var current *importedTodo
+	indent, blank := 0, 0
+	fence := ""
+
+	for i, line := range importLines(data) {
+		trimmed := strings.TrimSpace(line)
+
+		// コードブロックの中の項目は取り込まない
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if isFence(trimmed) {
+			fence = trimmed[:3]
+			current = nil
+			continue
+		}
+
+		if m := checklistItem.FindStringSubmatch(line); m != nil {
+			todo := importedTodo{Row: i + 1, Completed: m[2] != " ", Checks: make(map[string]*time.Time)}
+			var text string
+			todo.Priority, text = cutPriority(strings.TrimSpace(m[3]))
+			parseTodoText(text, &todo)
+			todos = append(todos, todo)
+			current = &todos[len(todos)-1]
+			indent, blank = len(m[1])+len("- "), 0
+			continue
+		}
+
+		switch {
+		case current == nil:
+		case trimmed == "":
+			blank++
+		case line[0] == ' ' || line[0] == '\t':
+			if current.Description != "" {
+				current.Description += strings.Repeat("\n", blank+1)
+			}
+			current.Description += unescapeChecklistLine(strings.TrimRight(trimIndent(line, indent), " \t"))
+			blank = 0
+		default:
+			current = nil
+		}
+	}
+	return todos
+}
+
+// isChecklistSyntax reports whether a line would be read as an item or a code
+// fence of a checklist
+func isChecklistSyntax(line string) bool {
+	return checklistItem.MatchString(line) || isFence(strings.TrimSpace(line))
+}
+
+// isFence reports whether a line without surrounding whitespace opens or
+// closes a code block
+func isFence(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// unescapeChecklistLine reverses the escape of a description line by
+// ExportChecklist. Other backslashes are kept.
+func unescapeChecklistLine(line string) string {
+	if len(line) > 1 && line[0] == '\\' && (line[1] == '\\' || isChecklistSyntax(line[1:])) {
+		return line[1:]
+	}
+	return line
+}
+
+// trimIndent removes up to n spaces, or a tab, from the start of a line
+func trimIndent(line string, n int) string {
+	if strings.HasPrefix(line, "\t") {
+		return line[1:]
+	}
+	i := 0
+	for i < n && i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return line[i:]
+}
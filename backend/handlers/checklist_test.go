@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) TestExportChecklist() {
+	suite.textTodos()
+
+	w := suite.get("/api/lists/test-list-id/export.md")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), "- [ ] (A) Buy milk +groceries +after_work due:2024-05-01\n"+
+		"- [x] (C) Call due:2024-05-02T18:30\n"+
+		"  About the\n"+
+		"\n"+
+		"  report\n"+
+		"- [ ] (B) Someday\n", w.Body.String())
+}
+
+func (suite *HandlerTestSuite) TestImportChecklist() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.postText("/api/lists/test-list-id/import.md", "# Project\n"+
+		"\n"+
+		"Some text about the project.\n"+
+		"\n"+
+		"## TODO\n"+
+		"\n"+
+		"- [ ] (A) Write docs +docs due:2024-05-01\n"+
+		"  Cover the API\n"+
+		"\n"+
+		"  and the setup\n"+
+		"* [X] Release\n"+
+		"  - [ ] Tag the version\n"+
+		"- plain bullet\n"+
+		"\n"+
+		"```\n"+
+		"- [ ] not a todo\n"+
+		"```\n"+
+		"+ [ ] Announce\n"+
+		"Not indented\n")
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var response struct {
+		TodoIDs []uint `json:"todoIds"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	var todos []models.Todo
+	database.DB.Order("id").Find(&todos, response.TodoIDs)
+	suite.Require().Len(todos, 4)
+
+	assert.Equal(suite.T(), "Write docs", todos[0].Title)
+	assert.Equal(suite.T(), "high", todos[0].Priority)
+	assert.Equal(suite.T(), models.Labels{"docs"}, todos[0].Labels)
+	assert.Equal(suite.T(), "Cover the API\n\nand the setup", todos[0].Description)
+	assert.Equal(suite.T(), "Release", todos[1].Title)
+	assert.Equal(suite.T(), "", todos[1].Description)
+	assert.True(suite.T(), todos[1].IsCompleted)
+	assert.Equal(suite.T(), "Tag the version", todos[2].Title)
+	assert.False(suite.T(), todos[2].IsCompleted)
+	assert.Equal(suite.T(), "Announce", todos[3].Title)
+	assert.Equal(suite.T(), "", todos[3].Description)
+}
+
+func (suite *HandlerTestSuite) TestChecklistRoundTrip() {
+	suite.textTodos()
+	database.DB.Create(&models.List{ID: "other-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "other-list-id"})
+
+	exported := suite.get("/api/lists/test-list-id/export.md").Body.String()
+	w := suite.postText("/api/lists/other-list-id/import.md", exported)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	assert.Equal(suite.T(), exported, suite.get("/api/lists/other-list-id/export.md").Body.String())
+}
+
+func (suite *HandlerTestSuite) TestChecklistRoundTripEscapes() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.List{ID: "other-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "other-list-id"})
+
+	todos := []models.Todo{
+		{Title: "(A) Plan +team due:friday", Description: "Steps:\n- [ ] draft\n  - [x] outline\n\n```\ncode\n```\n\\escaped\n\tindented"},
+		{Title: `pri:C \x`, Description: "  starts indented\n* [ ] star"},
+	}
+	for i := range todos {
+		todos[i].ListID = "test-list-id"
+		todos[i].Priority = "medium"
+		database.DB.Create(&todos[i])
+	}
+
+	exported := suite.get("/api/lists/test-list-id/export.md").Body.String()
+	w := suite.postText("/api/lists/other-list-id/import.md", exported)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var imported []models.Todo
+	database.DB.Where("list_id = ?", "other-list-id").Order("id").Find(&imported)
+	suite.Require().Len(imported, len(todos))
+	for i, todo := range todos {
+		assert.Equal(suite.T(), todo.Title, imported[i].Title)
+		assert.Equal(suite.T(), todo.Description, imported[i].Description)
+		assert.Empty(suite.T(), imported[i].Labels)
+	}
+
+	// 行末の空白と説明の前後の空行は書き出さない
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Trim", Description: "\nline  \n\n", Priority: "medium"})
+	database.DB.Where("list_id = ?", "other-list-id").Delete(&models.Todo{})
+	w = suite.postText("/api/lists/other-list-id/import.md", suite.get("/api/lists/test-list-id/export.md").Body.String())
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var trimmed models.Todo
+	database.DB.Where("list_id = ? AND title = ?", "other-list-id", "Trim").First(&trimmed)
+	assert.Equal(suite.T(), "line", trimmed.Description)
+}
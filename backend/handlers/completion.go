@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"shared-todo-backend/models"
+	"shared-todo-backend/webhook"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// updateCompletion marks a todo completed when every user of its list checked
+// it, and reopens it otherwise. A change is recorded as activity of actorID, and
+// a completion is sent to webhooks and notified to the other users.
+func updateCompletion(db *gorm.DB, todoID uint, actorID string) error {
+	var todo models.Todo
+	if err := db.First(&todo, todoID).Error; err != nil {
+		return err
+	}
+	wasCompleted := todo.IsCompleted
+
+	var userCount, checkedCount int64
+	if err := db.Model(&models.User{}).Where("list_id = ?", todo.ListID).Count(&userCount).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND is_checked = ?", todoID, true).Count(&checkedCount).Error; err != nil {
+		return err
+	}
+
+	isCompleted := checkedCount == userCount
+	updates := map[string]interface{}{"is_completed": isCompleted}
+	if isCompleted && !wasCompleted {
+		updates["completed_at"] = time.Now().UTC()
+	} else if !isCompleted {
+		updates["completed_at"] = nil
+	}
+	if err := db.Model(&todo).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	if isCompleted == wasCompleted {
+		return nil
+	}
+
+	// 完了状態が変わった場合はチェックとは別のエントリとして記録する
+	action := models.ActionTodoReopened
+	if isCompleted {
+		action = models.ActionTodoCompleted
+	}
+	if err := recordActivity(db, models.Activity{
+		ListID:     todo.ListID,
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: models.TargetTodo,
+		TargetID:   strconv.FormatUint(uint64(todoID), 10),
+	}, gin.H{"isCompleted": wasCompleted}, gin.H{"isCompleted": isCompleted}); err != nil {
+		return err
+	}
+
+	if isCompleted {
+		emitEvent(db, todo.ListID, webhook.EventTodoCompleted, todo)
+
+		var list models.List
+		db.First(&list, "id = ?", todo.ListID)
+		notifyCompleted(db, list, &todo, actorID)
+	}
+	return nil
+}
+
+// checkAction returns the activity action of checking or unchecking a todo
+func checkAction(checked bool) string {
+	if checked {
+		return models.ActionTodoChecked
+	}
+	return models.ActionTodoUnchecked
+}
+
+// checkEvent returns the webhook event of checking or unchecking a todo
+func checkEvent(checked bool) string {
+	if checked {
+		return webhook.EventTodoChecked
+	}
+	return webhook.EventTodoUnchecked
+}
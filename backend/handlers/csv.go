@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// csvColumns are the columns of a CSV export. They are followed by a pair of
+// columns for each user, named with csvCheckedPrefix and csvCheckedAtPrefix
+// before the user's display name, or ID when the name does not tell the users
+// apart.
+var csvColumns = []string{"id", "title", "description", "priority", "labels", "due_date", "completed", "completed_at"}
+
+const (
+	csvCheckedPrefix   = "checked:"
+	csvCheckedAtPrefix = "checked_at:"
+
+	// utf8BOM lets spreadsheet applications open the export as UTF-8
+	utf8BOM = "\ufeff"
+)
+
+// ExportCSV returns the todos of a list as CSV, with the check state of each user
+func ExportCSV(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	users, todos := exportData(database.DB, list.ID)
+	loc := models.LoadLocation(list.TimeZone)
+
+	var buf bytes.Buffer
+	buf.WriteString(utf8BOM)
+	w := csv.NewWriter(&buf)
+
+	header := append([]string{}, csvColumns...)
+	for _, name := range csvUserNames(users) {
+		header = append(header, csvCheckedPrefix+escapeCell(name), csvCheckedAtPrefix+escapeCell(name))
+	}
+	w.Write(header)
+
+	for _, todo := range todos {
+		record := []string{
+			strconv.FormatUint(uint64(todo.ID), 10),
+			escapeCell(todo.Title),
+			escapeCell(todo.Description),
+			todo.Priority,
+			escapeCell(strings.Join(todo.Labels, ", ")),
+			formatDue(todo, loc),
+			strconv.FormatBool(todo.IsCompleted),
+			formatTimestamp(todo.CompletedAt, loc),
+		}
+		statuses := make(map[string]models.TodoUserStatus)
+		for _, status := range todo.UserStatuses {
+			statuses[status.UserID] = status
+		}
+		for _, user := range users {
+			status := statuses[user.ID]
+			record = append(record, strconv.FormatBool(status.IsChecked), formatTimestamp(status.CheckedAt, loc))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+
+	c.Header("Content-Disposition", `attachment; filename="todos.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// ImportCSV creates todos from CSV in the format of ExportCSV, sent as the
+// body or as the file field of a form. Columns are found by name, so only the
+// title column is required. The id column is ignored and each row creates a
+// new todo.
+func ImportCSV(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	data, err := readImport(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var users []models.User
+	database.DB.Where("list_id = ?", listID).Order("created_at, id").Find(&users)
+
+	todos, rowErrors, err := parseCSV(data, users, models.LoadLocation(list.TimeZone))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	importList(c, database.DB, list, users, todos, rowErrors)
+}
+
+// csvUserNames returns the names of users in the column names of an export
+func csvUserNames(users []models.User) []string {
+	count := make(map[string]int)
+	for _, user := range users {
+		count[strings.ToLower(user.DisplayName)]++
+		count[strings.ToLower(user.ID)]++
+	}
+
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.ID
+		if user.DisplayName != "" && count[strings.ToLower(user.DisplayName)] == 1 {
+			names[i] = user.DisplayName
+		}
+	}
+	return names
+}
+
+// csvUser returns the user named in a column: by ID, or by display name
+// compared case-insensitively
+func csvUser(users []models.User, name string) (string, error) {
+	var found []string
+	for _, user := range users {
+		if user.ID == name {
+			return user.ID, nil
+		}
+		if user.DisplayName != "" && strings.EqualFold(user.DisplayName, name) {
+			found = append(found, user.ID)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", errors.New("User not found in this list: " + name)
+	case 1:
+		return found[0], nil
+	}
+	return "", errors.New("More than one user is named " + name + ", use the user ID")
+}
+
+// parseCSV reads the todos of an import. A row that cannot be read is returned
+// as a rowError, while an error is returned for a file that cannot be imported.
+func parseCSV(data []byte, users []models.User, loc *time.Location) ([]importedTodo, []rowError, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte(utf8BOM))))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("No todos to import")
+	}
+	if err != nil {
+		return nil, nil, errors.New("Invalid CSV: " + err.Error())
+	}
+
+	columns := make(map[string]int)
+	checked := make(map[string]int)
+	checkedAt := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		lower := strings.ToLower(name)
+		switch {
+		case strings.HasPrefix(lower, csvCheckedAtPrefix):
+			userID, err := csvUser(users, unescapeCell(strings.TrimSpace(name[len(csvCheckedAtPrefix):])))
+			if err != nil {
+				return nil, nil, err
+			}
+			checkedAt[userID] = i
+		case strings.HasPrefix(lower, csvCheckedPrefix):
+			userID, err := csvUser(users, unescapeCell(strings.TrimSpace(name[len(csvCheckedPrefix):])))
+			if err != nil {
+				return nil, nil, err
+			}
+			checked[userID] = i
+		default:
+			columns[lower] = i
+		}
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, nil, errors.New("The title column is required")
+	}
+
+	var todos []importedTodo
+	var rowErrors []rowError
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.New("Invalid CSV: " + err.Error())
+		}
+		row, _ := r.FieldPos(0)
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		todo, err := csvTodo(record, columns, checked, checkedAt, loc)
+		if err != nil {
+			rowErrors = append(rowErrors, rowError{row, err.Error()})
+			continue
+		}
+		todo.Row = row
+		todos = append(todos, todo)
+	}
+	return todos, rowErrors, nil
+}
+
+// csvTodo reads the todo of a row
+func csvTodo(record []string, columns, checked, checkedAt map[string]int, loc *time.Location) (importedTodo, error) {
+	cell := func(i int, ok bool) string {
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	column := func(name string) string {
+		i, ok := columns[name]
+		return cell(i, ok)
+	}
+
+	todo := importedTodo{
+		Title:       unescapeCell(column("title")),
+		Description: unescapeCell(column("description")),
+		Priority:    strings.ToLower(column("priority")),
+		DueDate:     column("due_date"),
+		Labels:      strings.Split(unescapeCell(column("labels")), ","),
+		Checks:      make(map[string]*time.Time),
+	}
+
+	var err error
+	if todo.Completed, err = parseCell(column("completed")); err != nil {
+		return todo, errors.New("Invalid value in completed: " + err.Error())
+	}
+	if s := column("completed_at"); s != "" {
+		if todo.CompletedAt, err = parseCheckedAt(s, loc); err != nil {
+			return todo, errors.New("Invalid date in completed_at")
+		}
+	}
+
+	// チェック日時だけが書かれていればチェック済みとする
+	now := time.Now().UTC()
+	for userID := range mergeColumns(checked, checkedAt) {
+		i, ok := checked[userID]
+		value := cell(i, ok)
+		j, ok := checkedAt[userID]
+		at := cell(j, ok)
+
+		isChecked, err := parseCell(value)
+		if err != nil {
+			return todo, errors.New("Invalid value in checked: " + err.Error())
+		}
+		if at != "" {
+			t, err := parseCheckedAt(at, loc)
+			if err != nil {
+				return todo, errors.New("Invalid date in checked_at")
+			}
+			if value == "" || isChecked {
+				todo.Checks[userID] = t
+				continue
+			}
+		}
+		switch {
+		case isChecked:
+			todo.Checks[userID] = &now
+		case value != "":
+			todo.Checks[userID] = nil
+		}
+	}
+	return todo, nil
+}
+
+func mergeColumns(a, b map[string]int) map[string]bool {
+	keys := make(map[string]bool)
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
+
+// parseCell parses a yes or no value of a cell. An empty cell is no.
+func parseCell(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "y", "1", "x", "✓":
+		return true, nil
+	case "false", "no", "n", "0", "":
+		return false, nil
+	}
+	return false, errors.New(`"` + s + `" is not true or false`)
+}
+
+// escapedCellPrefixes are the first characters of the cells escapeCell
+// prefixes with a quote: those of a formula, and the quote itself, so that
+// text starting with a quote is not unescaped on import
+const escapedCellPrefixes = "=+-@\t\r'"
+
+// escapeCell keeps a spreadsheet application from reading text as a formula
+func escapeCell(s string) string {
+	if s != "" && strings.ContainsRune(escapedCellPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeCell reverses escapeCell
+func unescapeCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(escapedCellPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
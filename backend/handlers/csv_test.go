@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) postCSV(body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/lists/test-list-id/import.csv", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) exportCSV() [][]string {
+	w := suite.get("/api/lists/test-list-id/export.csv")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	suite.Require().True(strings.HasPrefix(w.Body.String(), utf8BOM))
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), utf8BOM))).ReadAll()
+	suite.Require().NoError(err)
+	return records
+}
+
+func (suite *HandlerTestSuite) TestExportCSV() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice", CreatedAt: time.Now().Add(-time.Hour)})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	due := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	checkedAt := time.Date(2024, 4, 30, 3, 0, 0, 0, time.UTC)
+	milk := models.Todo{ListID: "test-list-id", Title: "=Milk", Priority: "high", Labels: models.Labels{"groceries", "daily"}, DueDate: &due}
+	database.DB.Create(&milk)
+	database.DB.Create(&models.TodoUserStatus{TodoID: milk.ID, UserID: "test-user-id", IsChecked: true, CheckedAt: &checkedAt})
+	database.DB.Create(&models.TodoUserStatus{TodoID: milk.ID, UserID: "other-user-id"})
+
+	records := suite.exportCSV()
+	suite.Require().Len(records, 2)
+	assert.Equal(suite.T(), []string{
+		"id", "title", "description", "priority", "labels", "due_date", "completed", "completed_at",
+		"checked:Alice", "checked_at:Alice", "checked:other-user-id", "checked_at:other-user-id",
+	}, records[0])
+	assert.Equal(suite.T(), []string{
+		"1", "'=Milk", "", "high", "groceries, daily", "2024-05-01", "false", "",
+		"true", "2024-04-30T12:00:00+09:00", "false", "",
+	}, records[1])
+}
+
+func (suite *HandlerTestSuite) TestImportCSV() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	w := suite.postCSV(utf8BOM + strings.Join([]string{
+		"Title,Priority,Labels,Due_Date,Completed,checked:alice,checked_at:alice,checked_at:other-user-id,Notes",
+		"'=Milk,high,\"groceries, daily\",2024-05-01T18:30,,yes,2024-04-30T12:00:00+09:00,,ignored",
+		"",
+		"Eggs,,,,true,,,2024-04-29T09:00,",
+		"Bread,low,,,,,,2024-04-29,",
+	}, "\n"))
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var response struct {
+		Imported int    `json:"imported"`
+		TodoIDs  []uint `json:"todoIds"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), 3, response.Imported)
+
+	var todos []models.Todo
+	database.DB.Preload("UserStatuses").Order("id").Find(&todos)
+	suite.Require().Len(todos, 3)
+
+	assert.Equal(suite.T(), "=Milk", todos[0].Title)
+	assert.Equal(suite.T(), "high", todos[0].Priority)
+	assert.Equal(suite.T(), models.Labels{"groceries", "daily"}, todos[0].Labels)
+	assert.True(suite.T(), todos[0].HasDueTime)
+	assert.Equal(suite.T(), time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC), todos[0].DueDate.UTC())
+	assert.False(suite.T(), todos[0].IsCompleted)
+
+	// 完了済みのToDoは全員がチェックしたものとする
+	assert.Equal(suite.T(), "medium", todos[1].Priority)
+	assert.True(suite.T(), todos[1].IsCompleted)
+	suite.Require().NotNil(todos[1].CompletedAt)
+	for _, status := range todos[1].UserStatuses {
+		assert.True(suite.T(), status.IsChecked, status.UserID)
+	}
+
+	// 一人だけのチェックでは完了しない
+	assert.False(suite.T(), todos[2].IsCompleted)
+	checked := map[string]bool{}
+	for _, status := range todos[2].UserStatuses {
+		checked[status.UserID] = status.IsChecked
+	}
+	assert.Equal(suite.T(), map[string]bool{"test-user-id": false, "other-user-id": true}, checked)
+
+	// エクスポートしたものをそのまま取り込める
+	exported := suite.exportCSV()
+	var buf bytes.Buffer
+	csv.NewWriter(&buf).WriteAll(exported)
+	w = suite.postCSV(buf.String())
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	reimported := suite.exportCSV()
+	suite.Require().Len(reimported, 7)
+	for i := 1; i <= 3; i++ {
+		assert.Equal(suite.T(), exported[i][1:], reimported[i+3][1:])
+	}
+}
+
+func (suite *HandlerTestSuite) TestCSVRoundTripEscapes() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	titles := []string{"'=Milk", "'", "''Eggs", "'Bread", "-1 rice", "@home"}
+	for _, title := range titles {
+		database.DB.Create(&models.Todo{ListID: "test-list-id", Title: title, Priority: "medium"})
+	}
+
+	// 引用符で始まる文字列もエスケープするので、取り込むと元に戻る
+	exported := suite.exportCSV()
+	assert.Equal(suite.T(), "''=Milk", exported[1][1])
+	assert.Equal(suite.T(), "'-1 rice", exported[5][1])
+	database.DB.Where("1 = 1").Delete(&models.Todo{})
+
+	var buf bytes.Buffer
+	csv.NewWriter(&buf).WriteAll(exported)
+	w := suite.postCSV(buf.String())
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var todos []models.Todo
+	database.DB.Order("id").Find(&todos)
+	suite.Require().Len(todos, len(titles))
+	for i, todo := range todos {
+		assert.Equal(suite.T(), titles[i], todo.Title)
+	}
+}
+
+func (suite *HandlerTestSuite) TestImportCSVInvalid() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+
+	w := suite.postCSV(strings.Join([]string{
+		"title,priority,due_date,completed,checked:Alice",
+		"Milk,high,2024-05-01,,",
+		",low,,,",
+		strings.Repeat("x", 256) + ",,,,",
+		"Eggs,urgent,,,",
+		"Bread,,05/01/2024,,",
+		"Rice,,,maybe,",
+		"Tea,,,true,no",
+	}, "\n"))
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Rows []rowError `json:"rows"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	rows := []int{}
+	for _, row := range response.Rows {
+		rows = append(rows, row.Row)
+		assert.NotEmpty(suite.T(), row.Error)
+	}
+	assert.Equal(suite.T(), []int{3, 4, 5, 6, 7, 8}, rows)
+
+	var count int64
+	database.DB.Model(&models.Todo{}).Count(&count)
+	assert.Equal(suite.T(), int64(0), count)
+
+	for _, body := range []string{
+		"",
+		"title\n",
+		"name,priority\nMilk,high\n",
+		"title,checked:Bob\nMilk,true\n",
+		"title\n\"Milk\n",
+	} {
+		w := suite.postCSV(body)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func (suite *HandlerTestSuite) TestImportCSVForm() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "todos.csv")
+	file.Write([]byte("title\nMilk\nEggs\n"))
+	form.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/lists/test-list-id/import.csv", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	var count int64
+	database.DB.Model(&models.Todo{}).Count(&count)
+	assert.Equal(suite.T(), int64(2), count)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/digest"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDigest returns the digest of a list for a user as JSON, plain text or HTML.
+// Completed todos are those completed since the last digest sent to the user,
+// or since ?since= when given.
+func GetDigest(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be 'json', 'text', or 'html'"})
+		return
+	}
+
+	now := time.Now()
+	since := now.Add(-digest.Period(user.DigestFrequency))
+	if user.LastDigestAt != nil {
+		since = *user.LastDigestAt
+	}
+	if s := c.Query("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Since must be an RFC 3339 time"})
+			return
+		}
+		since = parsed
+	}
+
+	d, err := digest.Build(database.DB, list, user, since, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build digest"})
+		return
+	}
+
+	switch format {
+	case "text":
+		c.String(http.StatusOK, d.Text())
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(d.HTML()))
+	default:
+		c.JSON(http.StatusOK, d)
+	}
+}
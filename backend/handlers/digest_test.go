@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/digest"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) getDigest(query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/users/test-user-id/digest"+query, nil)
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) TestGetDigest() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Waiting"})
+	w = suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Done"})
+	var done models.Todo
+	json.Unmarshal(w.Body.Bytes(), &done)
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(done.ID))+"/status/test-user-id", map[string]bool{"checked": true})
+
+	var stored models.Todo
+	database.DB.First(&stored, done.ID)
+	assert.NotNil(suite.T(), stored.CompletedAt)
+
+	w = suite.getDigest("")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var response digest.Digest
+	json.Unmarshal(w.Body.Bytes(), &response)
+	suite.Require().Len(response.WaitingOnYou, 1)
+	assert.Equal(suite.T(), "Waiting", response.WaitingOnYou[0].Title)
+	suite.Require().Len(response.Completed, 1)
+	assert.Equal(suite.T(), "Done", response.Completed[0].Title)
+
+	// sinceより前に完了したToDoは含めない
+	w = suite.getDigest("?since=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(suite.T(), response.Completed)
+
+	w = suite.getDigest("?format=text")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(suite.T(), w.Body.String(), "Waiting on you (1)\n- Waiting")
+
+	w = suite.getDigest("?format=html")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(suite.T(), w.Body.String(), "<li>Waiting</li>")
+
+	w = suite.getDigest("?format=pdf")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.getDigest("?since=yesterday")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"digestFrequency": "weekly"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"digestFrequency":"weekly"`)
+	w = suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"digestFrequency": "hourly"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/digest"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExportJSON returns a list with its memo, users, todos and check states as a
+// models.ListDocument
+func ExportJSON(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	users, todos := exportData(database.DB, list.ID)
+
+	doc := models.ListDocument{
+		Format:     models.ListDocumentFormat,
+		Version:    models.ListDocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		List: models.DocumentList{
+			ID:        list.ID,
+			Memo:      list.Memo,
+			TimeZone:  list.TimeZone,
+			CreatedAt: list.CreatedAt,
+			UpdatedAt: list.UpdatedAt,
+		},
+		Users: []models.DocumentUser{},
+		Todos: []models.DocumentTodo{},
+	}
+	for _, user := range users {
+		doc.Users = append(doc.Users, models.DocumentUser{
+			ID:                  user.ID,
+			DisplayName:         user.DisplayName,
+			TimeZone:            user.TimeZone,
+			ReminderLeadMinutes: user.ReminderLeadMinutes,
+			EmailOnComplete:     &user.EmailOnComplete,
+			EmailOnAssign:       &user.EmailOnAssign,
+			EmailDigest:         &user.EmailDigest,
+			DigestFrequency:     user.DigestFrequency,
+			CreatedAt:           user.CreatedAt,
+		})
+	}
+	for _, todo := range todos {
+		statuses := make(map[string]models.TodoUserStatus)
+		for _, status := range todo.UserStatuses {
+			statuses[status.UserID] = status
+		}
+		item := models.DocumentTodo{
+			ID:          todo.ID,
+			Title:       todo.Title,
+			Description: todo.Description,
+			Priority:    todo.Priority,
+			Labels:      todo.Labels,
+			DueDate:     todo.DueDate,
+			HasDueTime:  todo.HasDueTime,
+			IsCompleted: todo.IsCompleted,
+			CompletedAt: todo.CompletedAt,
+			CreatedAt:   todo.CreatedAt,
+			UpdatedAt:   todo.UpdatedAt,
+			Statuses:    []models.DocumentStatus{},
+		}
+		for _, user := range users {
+			if status, ok := statuses[user.ID]; ok {
+				item.Statuses = append(item.Statuses, models.DocumentStatus{
+					UserID:     status.UserID,
+					IsChecked:  status.IsChecked,
+					CheckedAt:  status.CheckedAt,
+					IsAssigned: status.IsAssigned,
+				})
+			}
+		}
+		doc.Todos = append(doc.Todos, item)
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="list.json"`)
+	c.JSON(http.StatusOK, doc)
+}
+
+// ImportJSON creates a new list from a models.ListDocument, sent as the body or
+// as the file field of a form. The list, its users and its todos get new IDs,
+// and the response maps the user IDs of the document to the new ones.
+func ImportJSON(c *gin.Context) {
+	data, err := readImport(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var doc models.ListDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	if err := validateDocument(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	listID := uuid.New().String()
+	userIDs := make(map[string]string)
+	for _, user := range doc.Users {
+		userIDs[user.ID] = uuid.New().String()
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return importDocument(tx, listID, userIDs, doc)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"listId":  listID,
+		"userIds": userIDs,
+	})
+}
+
+// validateDocument checks a document with the rules used when the list is
+// edited, and fills in the defaults of empty fields
+func validateDocument(doc *models.ListDocument) error {
+	if doc.Format != models.ListDocumentFormat {
+		return errors.New("Format must be '" + models.ListDocumentFormat + "'")
+	}
+	if doc.Version < 1 || doc.Version > models.ListDocumentVersion {
+		return fmt.Errorf("Unsupported document version %d", doc.Version)
+	}
+
+	if doc.List.TimeZone == "" {
+		doc.List.TimeZone = models.DefaultTimeZone
+	}
+	if !models.ValidTimeZone(doc.List.TimeZone) {
+		return errors.New("list: Invalid time zone")
+	}
+	if len(doc.List.Memo) > 5000 {
+		return errors.New("list: Memo must be 5000 characters or less")
+	}
+
+	if len(doc.Users) == 0 {
+		return errors.New("users: A list must have at least one user")
+	}
+	users := make(map[string]bool)
+	for i := range doc.Users {
+		user := &doc.Users[i]
+		if user.DigestFrequency == "" {
+			user.DigestFrequency = digest.FrequencyOff
+		}
+		switch {
+		case user.ID == "":
+			return fmt.Errorf("users[%d]: ID is required", i)
+		case users[user.ID]:
+			return fmt.Errorf("users[%d]: Duplicate user ID %s", i, user.ID)
+		case len(user.DisplayName) > 100:
+			return fmt.Errorf("users[%d]: Display name must be 100 characters or less", i)
+		case user.TimeZone != "" && !models.ValidTimeZone(user.TimeZone):
+			return fmt.Errorf("users[%d]: Invalid time zone", i)
+		case user.ReminderLeadMinutes < 0:
+			return fmt.Errorf("users[%d]: Reminder lead time must not be negative", i)
+		case !digest.ValidFrequency(user.DigestFrequency):
+			return fmt.Errorf("users[%d]: Digest frequency must be 'off', 'daily', or 'weekly'", i)
+		}
+		users[user.ID] = true
+	}
+
+	for i := range doc.Todos {
+		todo := &doc.Todos[i]
+		if todo.Priority == "" {
+			todo.Priority = "medium"
+		}
+		switch {
+		case todo.Title == "":
+			return fmt.Errorf("todos[%d]: Title is required", i)
+		case len(todo.Title) > 255:
+			return fmt.Errorf("todos[%d]: Title must be 255 characters or less", i)
+		case len(todo.Description) > 10000:
+			return fmt.Errorf("todos[%d]: Description must be 10000 characters or less", i)
+		case todo.Priority != "high" && todo.Priority != "medium" && todo.Priority != "low":
+			return fmt.Errorf("todos[%d]: Priority must be 'high', 'medium', or 'low'", i)
+		}
+		labels, err := normalizeLabels(todo.Labels)
+		if err != nil {
+			return fmt.Errorf("todos[%d]: %s", i, err.Error())
+		}
+		todo.Labels = labels
+
+		checked := make(map[string]bool)
+		for _, status := range todo.Statuses {
+			if !users[status.UserID] {
+				return fmt.Errorf("todos[%d]: User %s is not a user of the list", i, status.UserID)
+			}
+			if checked[status.UserID] {
+				return fmt.Errorf("todos[%d]: Duplicate status of user %s", i, status.UserID)
+			}
+			checked[status.UserID] = true
+		}
+	}
+	return nil
+}
+
+// importDocument creates the list of a validated document in the transaction
+// tx, with listID and the user IDs mapped by userIDs. Every user gets a status
+// for every todo, unchecked unless the document has one.
+func importDocument(tx *gorm.DB, listID string, userIDs map[string]string, doc models.ListDocument) error {
+	list := models.List{
+		ID:        listID,
+		Memo:      doc.List.Memo,
+		TimeZone:  doc.List.TimeZone,
+		CreatedAt: doc.List.CreatedAt,
+		UpdatedAt: doc.List.UpdatedAt,
+	}
+	if err := tx.Create(&list).Error; err != nil {
+		return err
+	}
+
+	for _, user := range doc.Users {
+		created := models.User{
+			ID:                  userIDs[user.ID],
+			ListID:              listID,
+			DisplayName:         user.DisplayName,
+			TimeZone:            user.TimeZone,
+			ReminderLeadMinutes: user.ReminderLeadMinutes,
+			DigestFrequency:     user.DigestFrequency,
+			CreatedAt:           user.CreatedAt,
+		}
+		if err := tx.Create(&created).Error; err != nil {
+			return err
+		}
+		// 0 と false は作成時に既定値になるため後から設定する
+		updates := map[string]interface{}{}
+		if user.ReminderLeadMinutes == 0 {
+			updates["reminder_lead_minutes"] = 0
+		}
+		for column, value := range map[string]*bool{
+			"email_on_complete": user.EmailOnComplete,
+			"email_on_assign":   user.EmailOnAssign,
+			"email_digest":      user.EmailDigest,
+		} {
+			if value != nil && !*value {
+				updates[column] = false
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&created).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, item := range doc.Todos {
+		todo := models.Todo{
+			ListID:      listID,
+			Title:       item.Title,
+			Description: item.Description,
+			Priority:    item.Priority,
+			Labels:      item.Labels,
+			DueDate:     item.DueDate,
+			HasDueTime:  item.HasDueTime,
+			IsCompleted: item.IsCompleted,
+			CompletedAt: item.CompletedAt,
+			CreatedAt:   item.CreatedAt,
+			UpdatedAt:   item.UpdatedAt,
+		}
+		if err := tx.Create(&todo).Error; err != nil {
+			return err
+		}
+
+		statuses := make(map[string]models.DocumentStatus)
+		for _, status := range item.Statuses {
+			statuses[status.UserID] = status
+		}
+		for _, user := range doc.Users {
+			status := statuses[user.ID]
+			created := models.TodoUserStatus{
+				TodoID:     todo.ID,
+				UserID:     userIDs[user.ID],
+				IsChecked:  status.IsChecked,
+				CheckedAt:  status.CheckedAt,
+				IsAssigned: status.IsAssigned,
+				ChangedAt:  status.CheckedAt,
+			}
+			if err := tx.Create(&created).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return createActivity(tx, &models.Activity{
+		ListID:     listID,
+		Action:     models.ActionListImported,
+		TargetType: models.TargetList,
+		TargetID:   listID,
+	}, nil, gin.H{"sourceListId": doc.List.ID, "users": len(doc.Users), "todos": len(doc.Todos)})
+}
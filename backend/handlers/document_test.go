@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) exportJSON(listID string) models.ListDocument {
+	w := suite.get("/api/lists/" + listID + "/export.json")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var doc models.ListDocument
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &doc))
+	return doc
+}
+
+func (suite *HandlerTestSuite) TestExportImportJSON() {
+	created := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk", TimeZone: "Asia/Tokyo", CreatedAt: created})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice", CreatedAt: created})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id", DisplayName: "Bob", TimeZone: "UTC", CreatedAt: created.Add(time.Hour)})
+	database.DB.Model(&models.User{}).Where("id = ?", "other-user-id").Updates(map[string]interface{}{
+		"reminder_lead_minutes": 0, "email": "bob@example.com", "email_on_assign": false, "email_digest": false, "digest_frequency": "weekly",
+	})
+
+	due := time.Date(2024, 5, 1, 3, 30, 0, 0, time.UTC)
+	checkedAt := time.Date(2024, 4, 30, 3, 0, 0, 0, time.UTC)
+	milk := models.Todo{ListID: "test-list-id", Title: "Milk", Description: "**2**", Priority: "high", Labels: models.Labels{"groceries"}, DueDate: &due, HasDueTime: true, CreatedAt: created}
+	eggs := models.Todo{ListID: "test-list-id", Title: "Eggs", IsCompleted: true, CompletedAt: &checkedAt, CreatedAt: created.Add(time.Minute)}
+	trashed := models.Todo{ListID: "test-list-id", Title: "Trashed"}
+	database.DB.Create(&milk)
+	database.DB.Create(&eggs)
+	database.DB.Create(&trashed)
+	database.DB.Delete(&trashed)
+	database.DB.Create(&models.TodoUserStatus{TodoID: milk.ID, UserID: "test-user-id", IsChecked: true, CheckedAt: &checkedAt, IsAssigned: true})
+	database.DB.Create(&models.TodoUserStatus{TodoID: milk.ID, UserID: "other-user-id"})
+	database.DB.Create(&models.TodoUserStatus{TodoID: eggs.ID, UserID: "test-user-id", IsChecked: true, CheckedAt: &checkedAt})
+	database.DB.Create(&models.TodoUserStatus{TodoID: eggs.ID, UserID: "other-user-id", IsChecked: true, CheckedAt: &checkedAt})
+
+	doc := suite.exportJSON("test-list-id")
+	assert.Equal(suite.T(), models.ListDocumentFormat, doc.Format)
+	assert.Equal(suite.T(), models.ListDocumentVersion, doc.Version)
+	assert.Equal(suite.T(), "milk", doc.List.Memo)
+	suite.Require().Len(doc.Users, 2)
+	assert.Equal(suite.T(), "Alice", doc.Users[0].DisplayName)
+	// メールアドレスは本人の設定でのみ扱い、書き出さない
+	assert.NotContains(suite.T(), suite.get("/api/lists/test-list-id/export.json").Body.String(), "bob@example.com")
+	suite.Require().NotNil(doc.Users[1].EmailOnAssign)
+	assert.False(suite.T(), *doc.Users[1].EmailOnAssign)
+	suite.Require().Len(doc.Todos, 2)
+	assert.Len(suite.T(), doc.Todos[0].Statuses, 2)
+
+	w := suite.postJSON("/api/lists/import", doc)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var response struct {
+		ListID  string            `json:"listId"`
+		UserIDs map[string]string `json:"userIds"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEqual(suite.T(), "test-list-id", response.ListID)
+	suite.Require().Len(response.UserIDs, 2)
+	assert.NotEqual(suite.T(), "test-user-id", response.UserIDs["test-user-id"])
+
+	// IDを除けば同じ内容になる
+	imported := suite.exportJSON(response.ListID)
+	assert.Equal(suite.T(), response.ListID, imported.List.ID)
+	for i := range imported.Users {
+		assert.Equal(suite.T(), response.UserIDs[doc.Users[i].ID], imported.Users[i].ID)
+		imported.Users[i].ID = doc.Users[i].ID
+	}
+	for i := range imported.Todos {
+		assert.NotEqual(suite.T(), doc.Todos[i].ID, imported.Todos[i].ID)
+		imported.Todos[i].ID = doc.Todos[i].ID
+		for j := range imported.Todos[i].Statuses {
+			imported.Todos[i].Statuses[j].UserID = doc.Todos[i].Statuses[j].UserID
+		}
+	}
+	imported.List.ID = doc.List.ID
+	imported.ExportedAt = doc.ExportedAt
+	assert.Equal(suite.T(), doc, imported)
+
+	var activity models.Activity
+	database.DB.Where("list_id = ?", response.ListID).First(&activity)
+	assert.Equal(suite.T(), models.ActionListImported, activity.Action)
+}
+
+func (suite *HandlerTestSuite) TestImportJSONInvalid() {
+	valid := func() models.ListDocument {
+		return models.ListDocument{
+			Format:  models.ListDocumentFormat,
+			Version: models.ListDocumentVersion,
+			Users:   []models.DocumentUser{{ID: "u1"}},
+			Todos: []models.DocumentTodo{{
+				Title:    "Milk",
+				Statuses: []models.DocumentStatus{{UserID: "u1", IsChecked: true}},
+			}},
+		}
+	}
+
+	w := suite.postJSON("/api/lists/import", valid())
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	// 省略したメールの設定は新しいユーザーの既定値になる
+	var user models.User
+	database.DB.First(&user)
+	assert.True(suite.T(), user.EmailOnComplete)
+	assert.True(suite.T(), user.EmailDigest)
+	assert.Equal(suite.T(), "off", user.DigestFrequency)
+
+	for name, change := range map[string]func(*models.ListDocument){
+		"format":   func(d *models.ListDocument) { d.Format = "other" },
+		"version":  func(d *models.ListDocument) { d.Version = models.ListDocumentVersion + 1 },
+		"timezone": func(d *models.ListDocument) { d.List.TimeZone = "Mars/Base" },
+		"no users": func(d *models.ListDocument) { d.Users = nil },
+		"user id":  func(d *models.ListDocument) { d.Users = append(d.Users, models.DocumentUser{ID: "u1"}) },
+		"digest":   func(d *models.ListDocument) { d.Users[0].DigestFrequency = "hourly" },
+		"title":    func(d *models.ListDocument) { d.Todos[0].Title = "" },
+		"priority": func(d *models.ListDocument) { d.Todos[0].Priority = "urgent" },
+		"status":   func(d *models.ListDocument) { d.Todos[0].Statuses[0].UserID = "u2" },
+	} {
+		doc := valid()
+		change(&doc)
+		w := suite.postJSON("/api/lists/import", doc)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, name)
+	}
+
+	var count int64
+	database.DB.Model(&models.List{}).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dueDateLayouts are the accepted due date formats without a time zone
+var dueDateLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+var errInvalidDueDate = errors.New("Invalid date format. Use YYYY-MM-DD, YYYY-MM-DDTHH:MM or RFC 3339")
+
+// parseDueDate parses a due date or date-time. A date-only value is stored as
+// midnight UTC, a date-time without offset is interpreted in loc, and the result is
+// always in UTC. The second return value tells whether the due date has a time of day.
+func parseDueDate(s string, loc *time.Location) (time.Time, bool, error) {
+	if d, err := time.Parse("2006-01-02", s); err == nil {
+		return d, false, nil
+	}
+	if d, err := time.Parse(time.RFC3339, s); err == nil {
+		return d.UTC(), true, nil
+	}
+	for _, layout := range dueDateLayouts {
+		if d, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return d.UTC(), true, nil
+		}
+	}
+	return time.Time{}, false, errInvalidDueDate
+}
+
+// UpdateListTimeZone updates the default time zone of a list
+func UpdateListTimeZone(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	var req struct {
+		TimeZone string `json:"timeZone" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	if !models.ValidTimeZone(req.TimeZone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone"})
+		return
+	}
+
+	before := list.TimeZone
+	actorID := requestActor(c, listID)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&list).Update("time_zone", req.TimeZone).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, models.Activity{
+			ListID:     listID,
+			ActorID:    actorID,
+			Action:     models.ActionTimeZoneUpdated,
+			TargetType: models.TargetList,
+			TargetID:   listID,
+		}, gin.H{"timeZone": before}, gin.H{"timeZone": req.TimeZone})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update time zone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timeZone": req.TimeZone})
+}
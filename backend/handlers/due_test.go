@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) putJSON(path string, payload interface{}) *httptest.ResponseRecorder {
+	jsonPayload, _ := json.Marshal(payload)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", path, bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) postJSON(path string, payload interface{}) *httptest.ResponseRecorder {
+	jsonPayload, _ := json.Marshal(payload)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) TestCreateTodoWithDueTime() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+
+	cases := []struct {
+		dueDate    string
+		want       time.Time
+		hasDueTime bool
+	}{
+		{"2025-06-10", time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), false},
+		{"2025-06-10T09:30", time.Date(2025, 6, 10, 0, 30, 0, 0, time.UTC), true},
+		{"2025-06-10T09:30:00+02:00", time.Date(2025, 6, 10, 7, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, c := range cases {
+		w := suite.postJSON("/api/lists/test-list-id/todos", map[string]interface{}{"title": "Todo", "dueDate": c.dueDate})
+		assert.Equal(suite.T(), http.StatusCreated, w.Code, c.dueDate)
+
+		var response models.Todo
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.True(suite.T(), c.want.Equal(*response.DueDate), c.dueDate)
+		assert.Equal(suite.T(), c.hasDueTime, response.HasDueTime, c.dueDate)
+	}
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]interface{}{"title": "Todo", "dueDate": "10/06/2025"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestGetListDataDueState() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", TimeZone: "Europe/Berlin"})
+
+	berlin := models.LoadLocation("Europe/Berlin")
+	y, m, d := time.Now().In(berlin).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+	passed := time.Now().Add(-time.Minute).UTC()
+
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "today", DueDate: &today})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "yesterday", DueDate: &yesterday})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "passed", DueDate: &passed, HasDueTime: true})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "none"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/users/test-user-id", nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		TimeZone string        `json:"timeZone"`
+		Todos    []models.Todo `json:"todos"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(suite.T(), "Europe/Berlin", response.TimeZone)
+
+	states := map[string][2]bool{}
+	for _, todo := range response.Todos {
+		states[todo.Title] = [2]bool{todo.IsOverdue, todo.IsDueToday}
+	}
+	assert.Equal(suite.T(), [2]bool{false, true}, states["today"])
+	assert.Equal(suite.T(), [2]bool{true, false}, states["yesterday"])
+	assert.True(suite.T(), states["passed"][0])
+	assert.Equal(suite.T(), [2]bool{false, false}, states["none"])
+}
+
+func (suite *HandlerTestSuite) TestUpdateListTimeZone() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+
+	w := suite.putJSON("/api/lists/test-list-id/timezone", map[string]string{"timeZone": "Asia/Tokyo"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "Asia/Tokyo", list.TimeZone)
+
+	w = suite.putJSON("/api/lists/test-list-id/timezone", map[string]string{"timeZone": "Nowhere/Town"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.putJSON("/api/lists/missing/timezone", map[string]string{"timeZone": "Asia/Tokyo"})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestUpdateUserPreferencesTimeZone() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"timeZone": "Europe/Berlin"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(suite.T(), "Europe/Berlin", response["timeZone"])
+
+	// 空文字でリストの設定に戻す
+	w = suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"timeZone": ""})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var user models.User
+	database.DB.First(&user, "id = ?", "test-user-id")
+	assert.Equal(suite.T(), "", user.TimeZone)
+
+	w = suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]string{"timeZone": "Bad/Zone"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.putJSON("/api/lists/test-list-id/users/missing/preferences", map[string]string{})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"log"
+	"shared-todo-backend/webhook"
+
+	"gorm.io/gorm"
+)
+
+// emitEvent queues a list event for the webhooks of the list. A failure is
+// logged and does not fail the request that caused the event.
+func emitEvent(db *gorm.DB, listID, event string, data interface{}) {
+	if err := webhook.Enqueue(db, listID, event, data); err != nil {
+		log.Printf("Failed to queue %s event for list %s: %v", event, listID, err)
+	}
+}
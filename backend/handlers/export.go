@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"shared-todo-backend/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImportSize is the size of the largest file that can be imported
+	maxImportSize = 5 << 20
+	// maxImportTodos is the number of todos a file can import
+	maxImportTodos = 1000
+)
+
+// errInvalidImport rolls back an import with invalid rows
+var errInvalidImport = errors.New("invalid import")
+
+// exportData returns the users of a list in the order they joined and its
+// todos with their check states in the order they were created
+func exportData(db *gorm.DB, listID string) ([]models.User, []models.Todo) {
+	var users []models.User
+	db.Where("list_id = ?", listID).Order("created_at, id").Find(&users)
+	var todos []models.Todo
+	db.Where("list_id = ?", listID).Preload("UserStatuses").Order("created_at, id").Find(&todos)
+	return users, todos
+}
+
+// formatDue formats the due date of a todo as accepted when creating one: the
+// date, or the date and time in loc
+func formatDue(todo models.Todo, loc *time.Location) string {
+	if todo.DueDate == nil {
+		return ""
+	}
+	if todo.HasDueTime {
+		return todo.DueDate.In(loc).Format("2006-01-02T15:04")
+	}
+	return todo.DueDate.UTC().Format("2006-01-02")
+}
+
+// formatTimestamp formats a time in loc as RFC 3339, or nil as ""
+func formatTimestamp(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// importedTodo is a todo read from an imported file
+type importedTodo struct {
+	// Row is the line of the file the todo was read from
+	Row         int
+	Title       string
+	Description string
+	Priority    string
+	DueDate     string
+	Labels      []string
+	Completed   bool
+	CompletedAt *time.Time
+	// Checks maps the ID of a user to when the user checked the todo, or to
+	// nil when the file says the user did not check it
+	Checks map[string]*time.Time
+}
+
+// rowError is the reason a row of an imported file was rejected
+type rowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// readImport reads a file sent as the file field of a form, or as the body
+func readImport(c *gin.Context) ([]byte, error) {
+	body := io.Reader(c.Request.Body)
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("File is required")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxImportSize+1))
+	if err != nil {
+		return nil, errors.New("Failed to read file")
+	}
+	if len(data) > maxImportSize {
+		return nil, errors.New("File must be 5 MB or less")
+	}
+	return data, nil
+}
+
+// importList creates the todos read from a file in one transaction and
+// responds with their IDs. When a row is invalid nothing is imported and every
+// invalid row is reported, together with rowErrors found while reading.
+func importList(c *gin.Context, db *gorm.DB, list models.List, users []models.User, todos []importedTodo, rowErrors []rowError) {
+	if len(todos)+len(rowErrors) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No todos to import"})
+		return
+	}
+	if len(todos)+len(rowErrors) > maxImportTodos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import must contain at most " + strconv.Itoa(maxImportTodos) + " todos"})
+		return
+	}
+
+	actorID := requestActor(c, list.ID)
+	var ids []uint
+	db, sendNotifications := deferNotifications(db)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var errs []rowError
+		var err error
+		ids, errs, err = importTodos(tx, list, users, todos, actorID)
+		if err != nil {
+			return err
+		}
+		rowErrors = append(rowErrors, errs...)
+		if len(rowErrors) > 0 {
+			return errInvalidImport
+		}
+		return nil
+	})
+	if errors.Is(err, errInvalidImport) {
+		sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rows: nothing was imported", "rows": rowErrors})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import todos"})
+		return
+	}
+	sendNotifications()
+
+	c.JSON(http.StatusCreated, gin.H{"imported": len(ids), "todoIds": ids})
+}
+
+// importTodos validates todos with the rules of CreateTodo and creates them in
+// the transaction tx as activity of actorID. The rows that are invalid are
+// returned, and the todos after the first invalid one are only validated.
+func importTodos(tx *gorm.DB, list models.List, users []models.User, todos []importedTodo, actorID string) ([]uint, []rowError, error) {
+	var ids []uint
+	var rowErrors []rowError
+	now := time.Now().UTC()
+
+	for _, imported := range todos {
+		if imported.Title == "" {
+			rowErrors = append(rowErrors, rowError{imported.Row, "Title is required"})
+			continue
+		}
+		todo, _, err := newTodo(tx, list, imported.Title, imported.Description, imported.Priority, &imported.DueDate, nil)
+		if err == nil {
+			todo.Labels, err = normalizeLabels(imported.Labels)
+		}
+		if err == nil && imported.Completed {
+			for _, at := range imported.Checks {
+				if at == nil {
+					err = errors.New("A completed todo must be checked by every user")
+				}
+			}
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, rowError{imported.Row, err.Error()})
+			continue
+		}
+		if len(rowErrors) > 0 {
+			continue
+		}
+
+		if err := createTodo(tx, list, users, &todo, nil, nil, actorID); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, todo.ID)
+
+		// 完了済みのToDoは、チェック日時のないユーザーも完了日時にチェックしたものとする
+		completedAt := now
+		if imported.CompletedAt != nil {
+			completedAt = *imported.CompletedAt
+		}
+		checked := 0
+		var lastChecked time.Time
+		for _, user := range users {
+			at, ok := imported.Checks[user.ID]
+			if !ok && imported.Completed {
+				at = &completedAt
+			}
+			if at == nil {
+				continue
+			}
+			err := tx.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND user_id = ?", todo.ID, user.ID).
+				Updates(map[string]interface{}{"is_checked": true, "checked_at": *at, "changed_at": *at}).Error
+			if err != nil {
+				return nil, nil, err
+			}
+			checked++
+			if at.After(lastChecked) {
+				lastChecked = *at
+			}
+		}
+
+		// 全員がチェックしていれば完了とする
+		if checked > 0 && checked == len(users) {
+			if imported.CompletedAt == nil {
+				completedAt = lastChecked
+			}
+			err := tx.Model(&models.Todo{}).Where("id = ?", todo.ID).
+				Updates(map[string]interface{}{"is_completed": true, "completed_at": completedAt}).Error
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return ids, rowErrors, nil
+}
+
+// parseCheckedAt parses the time a todo was checked or completed, as written
+// by an export or as a due date
+func parseCheckedAt(s string, loc *time.Location) (*time.Time, error) {
+	t, _, err := parseDueDate(s, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
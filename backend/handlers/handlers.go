@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 	"shared-todo-backend/database"
+	"shared-todo-backend/markdown"
 	"shared-todo-backend/models"
+	"shared-todo-backend/quickadd"
+	"shared-todo-backend/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateList creates a new list and user
@@ -27,23 +32,34 @@ func CreateList(c *gin.Context) {
 		DisplayName: "",
 	}
 
-	if err := database.DB.Create(&list).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&list).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, models.Activity{
+			ListID:     listID,
+			ActorID:    userID,
+			Action:     models.ActionListCreated,
+			TargetType: models.TargetList,
+			TargetID:   listID,
+		}, nil, gin.H{"userId": userID})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create list"})
 		return
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-		return
-	}
-
 	c.JSON(http.StatusCreated, gin.H{
 		"listId": listID,
 		"userId": userID,
 	})
 }
 
-// GetListData gets list information and user information
+// GetListData gets list information and user information. With ?since= it
+// returns only the changes after a sync cursor of an earlier response.
 func GetListData(c *gin.Context) {
 	listID := c.Param("listId")
 	userID := c.Param("userId")
@@ -62,19 +78,66 @@ func GetListData(c *gin.Context) {
 		return
 	}
 
+	// 変更の目印を先に読み、その後の変更は次の差分に含める
+	syncCursor, lastModified := latestChange(list)
+	if c.Query("since") != "" {
+		getListChanges(c, list, user, syncCursor, lastModified)
+		return
+	}
+
+	query, err := parseTodoQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Get all users in the list
 	var users []models.User
 	database.DB.Where("list_id = ?", listID).Find(&users)
 
-	// Get all todos with user statuses
-	var todos []models.Todo
-	database.DB.Where("list_id = ?", listID).Preload("UserStatuses").Find(&todos)
+	timeZone := models.EffectiveTimeZone(list, user)
+	loc := models.LoadLocation(timeZone)
 
-	c.JSON(http.StatusOK, gin.H{
-		"users": users,
-		"todos": todos,
-		"memo":  list.Memo,
-	})
+	// Get the matching todos in order, then load them with user statuses
+	ids, nextCursor, err := query.pageKeys(database.DB, listID, userID, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get todos"})
+		return
+	}
+
+	todos := []models.Todo{}
+	if len(ids) > 0 {
+		var found []models.Todo
+		database.DB.Where("id IN ?", ids).Preload("UserStatuses").Find(&found)
+
+		byID := make(map[uint]models.Todo, len(found))
+		for _, todo := range found {
+			byID[todo.ID] = todo
+		}
+		for _, id := range ids {
+			if todo, ok := byID[id]; ok {
+				todos = append(todos, todo)
+			}
+		}
+	}
+
+	renderTodos(todos, loc)
+
+	var cursor interface{}
+	if nextCursor != "" {
+		cursor = nextCursor
+	}
+
+	respondCached(c, gin.H{
+		"users":      users,
+		"todos":      todos,
+		"memo":       list.Memo,
+		"memoHtml":   markdown.Render(list.Memo),
+		"version":    list.Version,
+		"timeZone":   timeZone,
+		"nextCursor": cursor,
+		"syncCursor": strconv.FormatUint(uint64(syncCursor), 10),
+	}, lastModified)
 }
 
 // UpdateListMemo updates the memo of a list
@@ -89,7 +152,8 @@ func UpdateListMemo(c *gin.Context) {
 	}
 
 	var req struct {
-		Memo string `json:"memo"`
+		Memo    string `json:"memo"`
+		Version *int   `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -103,12 +167,23 @@ func UpdateListMemo(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Model(&models.List{}).Where("id = ?", listID).Update("memo", req.Memo).Error; err != nil {
+	version, ok := checkVersion(c, list.Version, req.Version, memoState(listID))
+	if !ok {
+		return
+	}
+
+	revision, err := saveMemo(database.DB, list, req.Memo, requestActor(c, listID), version, nil)
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, memoState(listID))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update memo"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"memo": req.Memo})
+	c.Header("ETag", etag(revision.Version))
+	c.JSON(http.StatusOK, gin.H{"memo": req.Memo, "memoHtml": markdown.Render(req.Memo), "version": revision.Version})
 }
 
 // InviteUser creates a new user for the list
@@ -129,22 +204,42 @@ func InviteUser(c *gin.Context) {
 		DisplayName: "",
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-		return
-	}
+	actorID := requestActor(c, listID)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
 
-	// Create todo user status records for existing todos
-	var todos []models.Todo
-	database.DB.Where("list_id = ?", listID).Find(&todos)
+		// Create todo user status records for existing todos
+		var todos []models.Todo
+		tx.Where("list_id = ?", listID).Find(&todos)
+
+		for _, todo := range todos {
+			status := models.TodoUserStatus{
+				TodoID:    todo.ID,
+				UserID:    userID,
+				IsChecked: false,
+			}
+			if err := tx.Create(&status).Error; err != nil {
+				return err
+			}
+		}
 
-	for _, todo := range todos {
-		status := models.TodoUserStatus{
-			TodoID:    todo.ID,
-			UserID:    userID,
-			IsChecked: false,
+		if err := recordActivity(tx, models.Activity{
+			ListID:     listID,
+			ActorID:    actorID,
+			Action:     models.ActionUserJoined,
+			TargetType: models.TargetUser,
+			TargetID:   userID,
+		}, nil, gin.H{"userId": userID}); err != nil {
+			return err
 		}
-		database.DB.Create(&status)
+		emitEvent(tx, listID, webhook.EventUserJoined, gin.H{"userId": userID})
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -180,7 +275,20 @@ func UpdateUserName(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Model(&user).Update("display_name", req.Name).Error; err != nil {
+	before := user.DisplayName
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("display_name", req.Name).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, models.Activity{
+			ListID:     listID,
+			ActorID:    userID,
+			Action:     models.ActionUserRenamed,
+			TargetType: models.TargetUser,
+			TargetID:   userID,
+		}, gin.H{"displayName": before}, gin.H{"displayName": req.Name})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user name"})
 		return
 	}
@@ -200,9 +308,14 @@ func CreateTodo(c *gin.Context) {
 	}
 
 	var req struct {
-		Title    string  `json:"title" binding:"required"`
-		Priority string  `json:"priority"`
-		DueDate  *string `json:"dueDate"`
+		Title       string  `json:"title"`
+		Description string  `json:"description"`
+		Priority    string  `json:"priority"`
+		DueDate     *string  `json:"dueDate"`
+		AssigneeIDs []string `json:"assigneeIds"`
+		Labels      []string `json:"labels"`
+		// QuickAdd is a line of free text parsed into the fields above
+		QuickAdd string `json:"quickAdd"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -210,59 +323,147 @@ func CreateTodo(c *gin.Context) {
 		return
 	}
 
-	// Validate title
-	if len(req.Title) > 255 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Title must be 255 characters or less"})
+	var users []models.User
+	database.DB.Where("list_id = ?", listID).Find(&users)
+
+	// 入力された項目は、クイック入力から読み取った項目より優先する
+	if req.QuickAdd != "" {
+		parsed := quickadd.Parse(req.QuickAdd, time.Now().In(models.LoadLocation(list.TimeZone)))
+		mentioned, err := mentionedUserIDs(users, parsed.Mentions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Title == "" {
+			req.Title = parsed.Title
+		}
+		if req.Priority == "" {
+			req.Priority = parsed.Priority
+		}
+		if (req.DueDate == nil || *req.DueDate == "") && parsed.DueDate != "" {
+			req.DueDate = &parsed.DueDate
+		}
+		req.Labels = append(req.Labels, parsed.Labels...)
+		req.AssigneeIDs = mergeIDs(req.AssigneeIDs, mentioned)
+	}
+	if req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
 		return
 	}
 
-	// Validate priority
-	if req.Priority == "" {
-		req.Priority = "medium"
+	todo, assignees, err := newTodo(database.DB, list, req.Title, req.Description, req.Priority, req.DueDate, req.AssigneeIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if todo.Labels, err = normalizeLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if req.Priority != "high" && req.Priority != "medium" && req.Priority != "low" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Priority must be 'high', 'medium', or 'low'"})
+
+	actorID := requestActor(c, listID)
+	db, sendNotifications := deferNotifications(database.DB)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return createTodo(tx, list, users, &todo, assignees, req.AssigneeIDs, actorID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
 		return
 	}
+	sendNotifications()
+
+	c.JSON(http.StatusCreated, todo)
+}
+
+// newTodo validates the fields of a new todo of list and returns the todo and
+// the set of its assignees
+func newTodo(db *gorm.DB, list models.List, title, description, priority string, dueDateStr *string, assigneeIDs []string) (models.Todo, map[string]bool, error) {
+	// Validate title
+	if len(title) > 255 {
+		return models.Todo{}, nil, errors.New("Title must be 255 characters or less")
+	}
+
+	// Validate description
+	if len(description) > 10000 {
+		return models.Todo{}, nil, errors.New("Description must be 10000 characters or less")
+	}
+
+	// Validate priority
+	if priority == "" {
+		priority = "medium"
+	}
+	if priority != "high" && priority != "medium" && priority != "low" {
+		return models.Todo{}, nil, errors.New("Priority must be 'high', 'medium', or 'low'")
+	}
 
 	// Parse due date
 	var dueDate *time.Time
-	if req.DueDate != nil && *req.DueDate != "" {
-		parsedDate, err := time.Parse("2006-01-02", *req.DueDate)
+	hasDueTime := false
+	if dueDateStr != nil && *dueDateStr != "" {
+		parsedDate, withTime, err := parseDueDate(*dueDateStr, models.LoadLocation(list.TimeZone))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
-			return
+			return models.Todo{}, nil, err
 		}
 		dueDate = &parsedDate
+		hasDueTime = withTime
 	}
 
-	todo := models.Todo{
-		ListID:      listID,
-		Title:       req.Title,
-		Priority:    req.Priority,
+	// Validate assignees
+	assignees, ok := listUserIDs(db, list.ID, assigneeIDs)
+	if !ok {
+		return models.Todo{}, nil, errors.New("Assignee not found in this list")
+	}
+
+	return models.Todo{
+		ListID:      list.ID,
+		Title:       title,
+		Description: description,
+		Priority:    priority,
 		DueDate:     dueDate,
+		HasDueTime:  hasDueTime,
 		IsCompleted: false,
-	}
+	}, assignees, nil
+}
 
-	if err := database.DB.Create(&todo).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
-		return
+// createTodo stores a new todo with a status for each of users, the users of
+// the list, records it as activity of actorID and notifies the assignees. The
+// todo is rendered in the time zone of the list.
+func createTodo(db *gorm.DB, list models.List, users []models.User, todo *models.Todo, assignees map[string]bool, assigneeIDs []string, actorID string) error {
+	if err := db.Create(todo).Error; err != nil {
+		return err
 	}
 
 	// Create todo user status records for all users in the list
-	var users []models.User
-	database.DB.Where("list_id = ?", listID).Find(&users)
-
 	for _, user := range users {
-		status := models.TodoUserStatus{
-			TodoID:    todo.ID,
-			UserID:    user.ID,
-			IsChecked: false,
+		todo.UserStatuses = append(todo.UserStatuses, models.TodoUserStatus{
+			TodoID:     todo.ID,
+			UserID:     user.ID,
+			IsChecked:  false,
+			IsAssigned: assignees[user.ID],
+		})
+	}
+	if len(todo.UserStatuses) > 0 {
+		if err := db.Create(&todo.UserStatuses).Error; err != nil {
+			return err
 		}
-		database.DB.Create(&status)
 	}
 
-	c.JSON(http.StatusCreated, todo)
+	after := todoSnapshot(*todo)
+	after["assigneeIds"] = assigneeIDs
+	if err := recordActivity(db, models.Activity{
+		ListID:     list.ID,
+		ActorID:    actorID,
+		Action:     models.ActionTodoCreated,
+		TargetType: models.TargetTodo,
+		TargetID:   strconv.FormatUint(uint64(todo.ID), 10),
+	}, nil, after); err != nil {
+		return err
+	}
+
+	renderTodo(todo, time.Now(), models.LoadLocation(list.TimeZone))
+	emitEvent(db, list.ID, webhook.EventTodoCreated, *todo)
+	notifyAssigned(db, list, todo, assigneeIDs)
+	return nil
 }
 
 // UpdateTodoUserStatus updates user's check status for a todo
@@ -292,6 +493,7 @@ func UpdateTodoUserStatus(c *gin.Context) {
 
 	var req struct {
 		Checked bool `json:"checked"`
+		Version *int `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -299,45 +501,146 @@ func UpdateTodoUserStatus(c *gin.Context) {
 		return
 	}
 
-	// Update user status
-	var status models.TodoUserStatus
-	result := database.DB.Where("todo_id = ? AND user_id = ?", todoID, userID).First(&status)
-	
-	if result.Error != nil {
-		// Create new status if not exists
-		status = models.TodoUserStatus{
-			TodoID:    uint(todoID),
-			UserID:    userID,
-			IsChecked: req.Checked,
-		}
-		if req.Checked {
-			now := time.Now()
-			status.CheckedAt = &now
+	version, ok := checkVersion(c, todo.Version, req.Version, todoState(todo.ID))
+	if !ok {
+		return
+	}
+
+	// 取り消される可能性があるため通知はコミット後に送る
+	db, sendNotifications := deferNotifications(database.DB)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, &models.Todo{}, todo.ID, version, nil); err != nil {
+			return err
 		}
-		database.DB.Create(&status)
+		_, err := setChecked(tx, todo, userID, req.Checked, time.Now())
+		return err
+	})
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, todoState(todo.ID))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
+		return
+	}
+	sendNotifications()
+
+	version = todoVersion(todo.ID)
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusOK, gin.H{"checked": req.Checked, "version": version})
+}
+
+// memberTodo finds the todo of the todoId parameter for the user of the userId
+// parameter. The user must belong to the list of the listId parameter, and the
+// todo to that list. Otherwise it responds with an error and reports false.
+func memberTodo(c *gin.Context) (models.Todo, bool) {
+	listID := c.Param("listId")
+	todoID, err := strconv.ParseUint(c.Param("todoId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID format"})
+		return models.Todo{}, false
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", c.Param("userId"), listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User not authorized to update this todo"})
+		return models.Todo{}, false
+	}
+
+	var todo models.Todo
+	if err := database.DB.First(&todo, todoID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return models.Todo{}, false
+	}
+	if todo.ListID != listID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User not authorized to update this todo"})
+		return models.Todo{}, false
+	}
+	return todo, true
+}
+
+// setChecked sets the check state of a todo for a user, changed at the given
+// time, records it and updates the completion of the todo
+func setChecked(db *gorm.DB, todo models.Todo, userID string, checked bool, at time.Time) (models.TodoUserStatus, error) {
+	var status models.TodoUserStatus
+	result := db.Where("todo_id = ? AND user_id = ?", todo.ID, userID).First(&status)
+	before := statusSnapshot(models.TodoUserStatus{UserID: userID})
+	if result.Error == nil {
+		before = statusSnapshot(status)
 	} else {
-		// Update existing status
-		status.IsChecked = req.Checked
-		if req.Checked {
-			now := time.Now()
-			status.CheckedAt = &now
-		} else {
-			status.CheckedAt = nil
-		}
-		database.DB.Save(&status)
+		// Create new status if not exists
+		status = models.TodoUserStatus{TodoID: todo.ID, UserID: userID}
 	}
 
+	status.IsChecked = checked
+	status.CheckedAt = nil
+	if checked {
+		status.CheckedAt = &at
+	}
+	status.ChangedAt = &at
+	if err := db.Save(&status).Error; err != nil {
+		return status, err
+	}
+
+	if err := recordActivity(db, models.Activity{
+		ListID:     todo.ListID,
+		ActorID:    userID,
+		Action:     checkAction(checked),
+		TargetType: models.TargetTodo,
+		TargetID:   strconv.FormatUint(uint64(todo.ID), 10),
+	}, before, statusSnapshot(status)); err != nil {
+		return status, err
+	}
+	emitEvent(db, todo.ListID, checkEvent(checked), gin.H{"todoId": todo.ID, "userId": userID, "checked": checked})
+
 	// Check if all users have checked this todo
-	database.DB.First(&todo, todoID)
+	return status, updateCompletion(db, todo.ID, userID)
+}
 
-	var users []models.User
-	database.DB.Where("list_id = ?", todo.ListID).Find(&users)
+// DeleteTodo moves a todo to the trash on behalf of a user of its list. It
+// can be restored with undo.
+func DeleteTodo(c *gin.Context) {
+	todo, ok := memberTodo(c)
+	if !ok {
+		return
+	}
+
+	version, ok := checkVersion(c, todo.Version, nil, todoState(todo.ID))
+	if !ok {
+		return
+	}
+	actorID := requestActor(c, todo.ListID)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTodo(tx, todo, version, actorID)
+	})
+	if err != nil {
+		if errors.Is(err, errVersionConflict) {
+			respondVersionConflict(c, todoState(todo.ID))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
+		return
+	}
 
-	var checkedCount int64
-	database.DB.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND is_checked = ?", todoID, true).Count(&checkedCount)
+	c.Status(http.StatusNoContent)
+}
 
-	isCompleted := int(checkedCount) == len(users)
-	database.DB.Model(&todo).Update("is_completed", isCompleted)
+// deleteTodo moves a todo to the trash, unless its version is no longer
+// version when that is not 0, and records it as activity of actorID
+func deleteTodo(db *gorm.DB, todo models.Todo, version int, actorID string) error {
+	if err := updateVersioned(db, &models.Todo{}, todo.ID, version, map[string]interface{}{"deleted_at": time.Now()}); err != nil {
+		return err
+	}
 
-	c.JSON(http.StatusOK, gin.H{"checked": req.Checked})
-}
\ No newline at end of file
+	if err := recordActivity(db, models.Activity{
+		ListID:     todo.ListID,
+		ActorID:    actorID,
+		Action:     models.ActionTodoDeleted,
+		TargetType: models.TargetTodo,
+		TargetID:   strconv.FormatUint(uint64(todo.ID), 10),
+	}, todoSnapshot(todo), nil); err != nil {
+		return err
+	}
+	emitEvent(db, todo.ListID, webhook.EventTodoDeleted, gin.H{"todoId": todo.ID})
+	return nil
+}
@@ -32,12 +32,59 @@ func (suite *HandlerTestSuite) SetupTest() {
 	// Ginルーターをセットアップ
 	suite.router = gin.New()
 	suite.router.POST("/api/lists", CreateList)
+	suite.router.POST("/api/lists/import", ImportJSON)
 	suite.router.GET("/api/lists/:listId/users/:userId", GetListData)
 	suite.router.PUT("/api/lists/:listId/memo", UpdateListMemo)
+	suite.router.GET("/api/lists/:listId/memo/revisions", GetMemoRevisions)
+	suite.router.GET("/api/lists/:listId/memo/revisions/:revisionId", GetMemoRevision)
+	suite.router.POST("/api/lists/:listId/memo/revisions/:revisionId/restore", RestoreMemoRevision)
+	suite.router.GET("/api/lists/:listId/memo/diff", GetMemoDiff)
+	suite.router.POST("/api/lists/:listId/memo/operations", ApplyMemoOperation)
+	suite.router.GET("/api/lists/:listId/memo/operations", GetMemoOperations)
+	suite.router.GET("/api/lists/:listId/memo/stream", StreamMemo)
 	suite.router.POST("/api/lists/:listId/users", InviteUser)
 	suite.router.PUT("/api/lists/:listId/users/:userId/name", UpdateUserName)
 	suite.router.POST("/api/lists/:listId/todos", CreateTodo)
+	suite.router.POST("/api/lists/:listId/todos/batch", BatchTodos)
 	suite.router.PUT("/api/todos/:todoId/status/:userId", UpdateTodoUserStatus)
+	suite.router.PUT("/api/lists/:listId/users/:userId/todos/:todoId/assignees", UpdateTodoAssignees)
+	suite.router.DELETE("/api/lists/:listId/users/:userId/todos/:todoId", DeleteTodo)
+	suite.router.POST("/api/lists/:listId/users/:userId/undo", UndoActions)
+	suite.router.POST("/api/lists/:listId/users/:userId/sync", SyncList)
+	suite.router.GET("/api/lists/:listId/search", SearchList)
+	suite.router.PUT("/api/lists/:listId/timezone", UpdateListTimeZone)
+	suite.router.GET("/api/lists/:listId/users/:userId/preferences", GetUserPreferences)
+	suite.router.PUT("/api/lists/:listId/users/:userId/preferences", UpdateUserPreferences)
+	suite.router.GET("/api/lists/:listId/users/:userId/digest", GetDigest)
+	suite.router.GET("/api/lists/:listId/users/:userId/notifications", GetNotifications)
+	suite.router.POST("/api/lists/:listId/users/:userId/notifications/read", MarkNotificationsRead)
+	suite.router.GET("/api/lists/:listId/activity", GetActivity)
+	suite.router.GET("/api/lists/:listId/calendar", GetCalendarFeed)
+	suite.router.POST("/api/lists/:listId/calendar", CreateCalendarFeed)
+	suite.router.DELETE("/api/lists/:listId/calendar", DeleteCalendarFeed)
+	suite.router.GET("/api/lists/:listId/users/:userId/calendar", GetCalendarFeed)
+	suite.router.POST("/api/lists/:listId/users/:userId/calendar", CreateCalendarFeed)
+	suite.router.DELETE("/api/lists/:listId/users/:userId/calendar", DeleteCalendarFeed)
+	suite.router.GET("/api/lists/:listId/users/:userId/caldav", GetCalDAVToken)
+	suite.router.POST("/api/lists/:listId/users/:userId/caldav", CreateCalDAVToken)
+	suite.router.DELETE("/api/lists/:listId/users/:userId/caldav", DeleteCalDAVToken)
+	suite.router.GET("/api/calendar/:token", GetCalendar)
+	for _, method := range CalDAVMethods {
+		suite.router.Handle(method, "/api/caldav/:token/*path", CalDAV)
+	}
+	suite.router.GET("/api/lists/:listId/export.csv", ExportCSV)
+	suite.router.GET("/api/lists/:listId/export.json", ExportJSON)
+	suite.router.POST("/api/lists/:listId/import.csv", ImportCSV)
+	suite.router.GET("/api/lists/:listId/export.txt", ExportTodoTxt)
+	suite.router.POST("/api/lists/:listId/import.txt", ImportTodoTxt)
+	suite.router.GET("/api/lists/:listId/export.md", ExportChecklist)
+	suite.router.POST("/api/lists/:listId/import.md", ImportChecklist)
+	suite.router.GET("/api/lists/:listId/report", GetReport)
+	suite.router.GET("/api/lists/:listId/stats", GetStats)
+	suite.router.POST("/api/lists/:listId/webhooks", CreateWebhook)
+	suite.router.GET("/api/lists/:listId/webhooks", GetWebhooks)
+	suite.router.DELETE("/api/lists/:listId/webhooks/:webhookId", DeleteWebhook)
+	suite.router.GET("/api/lists/:listId/webhooks/:webhookId/deliveries", GetWebhookDeliveries)
 }
 
 func (suite *HandlerTestSuite) TearDownTest() {
@@ -84,6 +131,7 @@ func (suite *HandlerTestSuite) TestGetListData() {
 	assert.Contains(suite.T(), response, "todos")
 	assert.Contains(suite.T(), response, "memo")
 	assert.Equal(suite.T(), "test memo", response["memo"])
+	assert.Equal(suite.T(), "<p>test memo</p>", response["memoHtml"])
 }
 
 func (suite *HandlerTestSuite) TestGetListDataNotFound() {
@@ -109,10 +157,13 @@ func (suite *HandlerTestSuite) TestUpdateListMemo() {
 
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 
-	var response map[string]string
+	var response map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), "Updated memo", response["memo"])
+	assert.Equal(suite.T(), "<p>Updated memo</p>", response["memoHtml"])
+	assert.Equal(suite.T(), float64(2), response["version"])
+	assert.Equal(suite.T(), `"2"`, w.Header().Get("ETag"))
 
 	// データベースで確認
 	var updatedList models.List
@@ -151,6 +202,37 @@ func (suite *HandlerTestSuite) TestCreateTodo() {
 	assert.False(suite.T(), response.IsCompleted)
 }
 
+func (suite *HandlerTestSuite) TestCreateTodoWithDescription() {
+	// テストデータを作成
+	list := models.List{ID: "test-list-id", Memo: ""}
+	database.DB.Create(&list)
+
+	payload := map[string]interface{}{
+		"title":       "Test Todo",
+		"description": "See **notes** at https://example.com <script>",
+	}
+	jsonPayload, _ := json.Marshal(payload)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/lists/test-list-id/todos", bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response models.Todo
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "See **notes** at https://example.com <script>", response.Description)
+	assert.Equal(suite.T(), `<p>See <strong>notes</strong> at <a href="https://example.com" rel="nofollow noopener noreferrer">https://example.com</a> &lt;script&gt;</p>`, response.DescriptionHTML)
+
+	// 一覧取得でもHTMLが返ること
+	user := models.User{ID: "test-user-id", ListID: "test-list-id"}
+	database.DB.Create(&user)
+	_, data := suite.getListData("")
+	assert.Equal(suite.T(), response.DescriptionHTML, data.Todos[0].DescriptionHTML)
+}
+
 func (suite *HandlerTestSuite) TestCreateTodoInvalidData() {
 	// テストデータを作成
 	list := models.List{ID: "test-list-id", Memo: ""}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"shared-todo-backend/models"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxLabels      = 20
+	maxLabelLength = 50
+)
+
+// normalizeLabels trims the labels of a todo, drops a leading # and empty or
+// repeated labels, and checks their number and length
+func normalizeLabels(labels []string) (models.Labels, error) {
+	result := models.Labels{}
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(label), "#"))
+		if label == "" || seen[label] {
+			continue
+		}
+		if utf8.RuneCountInString(label) > maxLabelLength {
+			return nil, errors.New("Labels must be 50 characters or less")
+		}
+		seen[label] = true
+		result = append(result, label)
+	}
+	if len(result) > maxLabels {
+		return nil, errors.New("A todo can have at most 20 labels")
+	}
+	return result, nil
+}
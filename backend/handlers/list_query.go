@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"shared-todo-backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTodoPageSize = 50
+	maxTodoPageSize     = 200
+)
+
+// todoSortKeys maps the sort parameter to the SQL expression used for ordering and
+// for keyset pagination. Every expression is cast to TEXT so that the cursor value
+// can be compared without knowing the column type.
+var todoSortKeys = map[string]string{
+	"created":  "",
+	"priority": "CAST(CASE todos.priority WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END AS TEXT)",
+	"dueDate":  "CAST(COALESCE(todos.due_date, '%s') AS TEXT)",
+}
+
+// todoQuery holds the filtering, sorting and pagination options of GetListData
+type todoQuery struct {
+	Status     string
+	Priorities []string
+	DueFrom    *time.Time
+	DueTo      *time.Time
+	Sort       string
+	Desc       bool
+	Limit      int
+	Cursor     *todoCursor
+	// Params are the sort and filter parameters, which a cursor is only
+	// valid for
+	Params string
+}
+
+// todoCursor points at the last todo of the previous page, for the sort and
+// filter parameters it was issued with
+type todoCursor struct {
+	Key    string `json:"k,omitempty"`
+	ID     uint   `json:"id"`
+	Params string `json:"p,omitempty"`
+}
+
+func (cur todoCursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTodoCursor(s string) (*todoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur todoCursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// parseTodoQuery reads the query parameters of GetListData
+func parseTodoQuery(c *gin.Context) (*todoQuery, error) {
+	q := &todoQuery{Sort: "created"}
+
+	switch status := c.Query("status"); status {
+	case "", "open", "completed", "checked", "unchecked":
+		q.Status = status
+	default:
+		return nil, errors.New("Status must be 'open', 'completed', 'checked' or 'unchecked'")
+	}
+
+	if p := c.Query("priority"); p != "" {
+		for _, priority := range strings.Split(p, ",") {
+			if priority != "high" && priority != "medium" && priority != "low" {
+				return nil, errors.New("Priority must be 'high', 'medium', or 'low'")
+			}
+			q.Priorities = append(q.Priorities, priority)
+		}
+	}
+
+	if s := c.Query("dueFrom"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, errors.New("Invalid dueFrom format. Use YYYY-MM-DD")
+		}
+		q.DueFrom = &d
+	}
+	if s := c.Query("dueTo"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, errors.New("Invalid dueTo format. Use YYYY-MM-DD")
+		}
+		// dueToはその日の終わりまでを含む
+		d = d.AddDate(0, 0, 1)
+		q.DueTo = &d
+	}
+
+	if s := c.Query("sort"); s != "" {
+		if strings.HasPrefix(s, "-") {
+			q.Desc = true
+			s = s[1:]
+		}
+		if _, ok := todoSortKeys[s]; !ok {
+			return nil, errors.New("Sort must be 'created', 'priority' or 'dueDate'")
+		}
+		q.Sort = s
+	}
+
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxTodoPageSize {
+			return nil, errors.New("Limit must be between 1 and " + strconv.Itoa(maxTodoPageSize))
+		}
+		q.Limit = limit
+	}
+
+	// カーソルは発行時と同じ並び順と絞り込みでのみ使える
+	params := url.Values{}
+	for _, key := range []string{"status", "priority", "dueFrom", "dueTo", "sort"} {
+		if s := c.Query(key); s != "" {
+			params.Set(key, s)
+		}
+	}
+	q.Params = params.Encode()
+
+	if s := c.Query("cursor"); s != "" {
+		cur, err := decodeTodoCursor(s)
+		if err != nil {
+			return nil, errors.New("Invalid cursor")
+		}
+		if cur.Params != q.Params {
+			return nil, errors.New("Cursor does not match the sort and filter parameters")
+		}
+		q.Cursor = cur
+		if q.Limit == 0 {
+			q.Limit = defaultTodoPageSize
+		}
+	}
+
+	return q, nil
+}
+
+// sortExpr returns the ordering expression, or "" when ordering by ID only.
+// Todos without a due date are always placed last.
+func (q *todoQuery) sortExpr() string {
+	expr := todoSortKeys[q.Sort]
+	if q.Sort == "dueDate" {
+		if q.Desc {
+			return strings.Replace(expr, "%s", "", 1)
+		}
+		return strings.Replace(expr, "%s", "9999-12-31", 1)
+	}
+	return expr
+}
+
+// apply adds the filter, order and page conditions for the given user to tx.
+// Due date ranges are calendar days in loc.
+func (q *todoQuery) apply(tx *gorm.DB, userID string, loc *time.Location) *gorm.DB {
+	switch q.Status {
+	case "open":
+		tx = tx.Where("todos.is_completed = ?", false)
+	case "completed":
+		tx = tx.Where("todos.is_completed = ?", true)
+	case "checked":
+		tx = tx.Where("EXISTS (SELECT 1 FROM todo_user_statuses s WHERE s.todo_id = todos.id AND s.user_id = ? AND s.is_checked = ?)", userID, true)
+	case "unchecked":
+		tx = tx.Where("NOT EXISTS (SELECT 1 FROM todo_user_statuses s WHERE s.todo_id = todos.id AND s.user_id = ? AND s.is_checked = ?)", userID, true)
+	}
+
+	if len(q.Priorities) > 0 {
+		tx = tx.Where("todos.priority IN ?", q.Priorities)
+	}
+	// 日付のみの期限はUTCの0時で保存されているため、時刻付きの期限のみ利用者のタイムゾーンで比較する
+	if q.DueFrom != nil {
+		tx = tx.Where("(todos.has_due_time = ? AND todos.due_date >= ?) OR (todos.has_due_time = ? AND todos.due_date >= ?)",
+			false, *q.DueFrom, true, inLocation(*q.DueFrom, loc))
+	}
+	if q.DueTo != nil {
+		tx = tx.Where("(todos.has_due_time = ? AND todos.due_date < ?) OR (todos.has_due_time = ? AND todos.due_date < ?)",
+			false, *q.DueTo, true, inLocation(*q.DueTo, loc))
+	}
+
+	op, dir := ">", "ASC"
+	if q.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	expr := q.sortExpr()
+	if q.Cursor != nil {
+		if expr == "" {
+			tx = tx.Where("todos.id "+op+" ?", q.Cursor.ID)
+		} else {
+			tx = tx.Where("("+expr+" "+op+" ?) OR ("+expr+" = ? AND todos.id "+op+" ?)", q.Cursor.Key, q.Cursor.Key, q.Cursor.ID)
+		}
+	}
+
+	if expr != "" {
+		tx = tx.Order(expr + " " + dir)
+	}
+	tx = tx.Order("todos.id " + dir)
+
+	if q.Limit > 0 {
+		// 次のページの有無を判定するため1件多く取得する
+		tx = tx.Limit(q.Limit + 1)
+	}
+	return tx
+}
+
+// pageKeys fetches the IDs and sort keys of the todos matching q, in order.
+// The returned cursor is empty when there is no further page.
+func (q *todoQuery) pageKeys(db *gorm.DB, listID, userID string, loc *time.Location) ([]uint, string, error) {
+	expr := q.sortExpr()
+	if expr == "" {
+		expr = "''"
+	}
+
+	var rows []struct {
+		ID      uint
+		SortKey string
+	}
+	tx := db.Model(&models.Todo{}).Select("todos.id AS id, "+expr+" AS sort_key").Where("todos.list_id = ?", listID)
+	if err := q.apply(tx, userID, loc).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if q.Limit > 0 && len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+		last := rows[len(rows)-1]
+		cur := todoCursor{ID: last.ID, Params: q.Params}
+		if q.sortExpr() != "" {
+			cur.Key = last.SortKey
+		}
+		nextCursor = cur.encode()
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids, nextCursor, nil
+}
+
+// inLocation returns midnight of the UTC calendar day d in loc, as UTC
+func inLocation(d time.Time, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc).UTC()
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type listDataResponse struct {
+	Todos      []models.Todo `json:"todos"`
+	NextCursor *string       `json:"nextCursor"`
+}
+
+func (suite *HandlerTestSuite) createQueryFixtures() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	due1 := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	due2 := time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC)
+	todos := []models.Todo{
+		{ListID: "test-list-id", Title: "A", Priority: "low", DueDate: &due1},
+		{ListID: "test-list-id", Title: "B", Priority: "high"},
+		{ListID: "test-list-id", Title: "C", Priority: "medium", DueDate: &due2, IsCompleted: true},
+		{ListID: "test-list-id", Title: "D", Priority: "high", DueDate: &due1},
+	}
+	for i := range todos {
+		database.DB.Create(&todos[i])
+		database.DB.Create(&models.TodoUserStatus{TodoID: todos[i].ID, UserID: "test-user-id", IsChecked: i%2 == 0})
+		database.DB.Create(&models.TodoUserStatus{TodoID: todos[i].ID, UserID: "other-user-id", IsChecked: i == 2})
+	}
+}
+
+func (suite *HandlerTestSuite) getListData(query string) (int, listDataResponse) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/users/test-user-id"+query, nil)
+	suite.router.ServeHTTP(w, req)
+
+	var response listDataResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func titles(todos []models.Todo) []string {
+	result := make([]string, len(todos))
+	for i, todo := range todos {
+		result[i] = todo.Title
+	}
+	return result
+}
+
+func (suite *HandlerTestSuite) TestGetListDataFilters() {
+	suite.createQueryFixtures()
+
+	code, response := suite.getListData("")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), []string{"A", "B", "C", "D"}, titles(response.Todos))
+	assert.Nil(suite.T(), response.NextCursor)
+	assert.Len(suite.T(), response.Todos[0].UserStatuses, 2)
+
+	_, response = suite.getListData("?status=open")
+	assert.Equal(suite.T(), []string{"A", "B", "D"}, titles(response.Todos))
+
+	_, response = suite.getListData("?status=completed")
+	assert.Equal(suite.T(), []string{"C"}, titles(response.Todos))
+
+	_, response = suite.getListData("?status=checked")
+	assert.Equal(suite.T(), []string{"A", "C"}, titles(response.Todos))
+
+	_, response = suite.getListData("?status=unchecked")
+	assert.Equal(suite.T(), []string{"B", "D"}, titles(response.Todos))
+
+	_, response = suite.getListData("?priority=high,low")
+	assert.Equal(suite.T(), []string{"A", "B", "D"}, titles(response.Todos))
+
+	_, response = suite.getListData("?dueFrom=2025-06-06&dueTo=2025-06-10")
+	assert.Equal(suite.T(), []string{"A", "D"}, titles(response.Todos))
+}
+
+func (suite *HandlerTestSuite) TestGetListDataSort() {
+	suite.createQueryFixtures()
+
+	_, response := suite.getListData("?sort=priority")
+	assert.Equal(suite.T(), []string{"B", "D", "C", "A"}, titles(response.Todos))
+
+	_, response = suite.getListData("?sort=dueDate")
+	assert.Equal(suite.T(), []string{"C", "A", "D", "B"}, titles(response.Todos))
+
+	_, response = suite.getListData("?sort=-dueDate")
+	assert.Equal(suite.T(), []string{"D", "A", "C", "B"}, titles(response.Todos))
+
+	_, response = suite.getListData("?sort=-created")
+	assert.Equal(suite.T(), []string{"D", "C", "B", "A"}, titles(response.Todos))
+}
+
+func (suite *HandlerTestSuite) TestGetListDataPagination() {
+	suite.createQueryFixtures()
+
+	var seen []string
+	query := "?sort=dueDate&limit=3"
+	for page := 0; page < 3; page++ {
+		code, response := suite.getListData(query)
+		assert.Equal(suite.T(), http.StatusOK, code)
+		seen = append(seen, titles(response.Todos)...)
+		if response.NextCursor == nil {
+			break
+		}
+		query = "?sort=dueDate&limit=3&cursor=" + *response.NextCursor
+	}
+	assert.Equal(suite.T(), []string{"C", "A", "D", "B"}, seen)
+}
+
+func (suite *HandlerTestSuite) TestGetListDataCursorParams() {
+	suite.createQueryFixtures()
+
+	_, response := suite.getListData("?sort=dueDate&status=open&limit=1")
+	suite.Require().NotNil(response.NextCursor)
+	cursor := *response.NextCursor
+
+	code, _ := suite.getListData("?status=open&sort=dueDate&limit=2&cursor=" + cursor)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	for _, query := range []string{"?sort=-dueDate", "?sort=priority&status=open", "?sort=dueDate", "?sort=dueDate&status=open&priority=high", "?sort=dueDate&status=open&dueFrom=2024-06-01"} {
+		code, _ := suite.getListData(query + "&limit=1&cursor=" + cursor)
+		assert.Equal(suite.T(), http.StatusBadRequest, code, query)
+	}
+}
+
+func (suite *HandlerTestSuite) TestGetListDataInvalidQuery() {
+	suite.createQueryFixtures()
+
+	for _, query := range []string{"?status=done", "?priority=urgent", "?dueFrom=06/10", "?sort=title", "?limit=0", "?cursor=xyz"} {
+		code, _ := suite.getListData(query)
+		assert.Equal(suite.T(), http.StatusBadRequest, code, query)
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/diff"
+	"shared-todo-backend/markdown"
+	"shared-todo-backend/models"
+	"shared-todo-backend/ot"
+	"shared-todo-backend/webhook"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultRevisionLimit = 50
+	maxRevisionLimit     = 200
+)
+
+// memoState returns the current memo of a list as sent in a version conflict
+func memoState(listID string) func() interface{} {
+	return func() interface{} {
+		var list models.List
+		if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+			return nil
+		}
+		return gin.H{"memo": list.Memo, "memoHtml": markdown.Render(list.Memo), "version": list.Version}
+	}
+}
+
+// saveMemo replaces the memo of a list and stores it as a new revision of
+// actorID. A version other than 0 makes the save fail with errVersionConflict
+// unless the list still has that version. restoreOf is the revision being
+// restored, if any. The change is broadcast to live editors as an operation.
+func saveMemo(db *gorm.DB, list models.List, memo, actorID string, version int, restoreOf *uint) (models.MemoRevision, error) {
+	revision := models.MemoRevision{ListID: list.ID, AuthorID: actorID, Memo: memo, RestoreOf: restoreOf}
+	var operation models.MemoOperation
+	before := list.Memo
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 同時に保存された場合も実際の変更を記録するため最新のメモを読み直す
+		var current models.List
+		if err := tx.First(&current, "id = ?", list.ID).Error; err != nil {
+			return err
+		}
+		if version != 0 && current.Version != version {
+			return errVersionConflict
+		}
+		before = current.Memo
+
+		if err := updateVersioned(tx, &models.List{}, list.ID, current.Version, map[string]interface{}{"memo": memo}); err != nil {
+			return err
+		}
+		var err error
+		if operation, err = createOperation(tx, current, actorID, "", ot.Diff(current.Memo, memo)); err != nil {
+			return err
+		}
+		if err := createRevision(tx, current, &revision); err != nil {
+			return err
+		}
+
+		if err := recordActivity(tx, models.Activity{
+			ListID:     list.ID,
+			ActorID:    actorID,
+			Action:     models.ActionMemoUpdated,
+			TargetType: models.TargetList,
+			TargetID:   list.ID,
+		}, gin.H{"memo": before}, gin.H{"memo": memo}); err != nil {
+			return err
+		}
+		emitEvent(tx, list.ID, webhook.EventMemoUpdated, gin.H{"memo": memo})
+		return nil
+	})
+	if err != nil {
+		return revision, err
+	}
+	publishOperation(operation)
+
+	return revision, nil
+}
+
+// createRevision stores a revision of the memo of list, which holds the memo
+// before the change, after the list was updated
+func createRevision(db *gorm.DB, list models.List, revision *models.MemoRevision) error {
+	// 履歴がない時点のメモは作成者不明の最初の版として残す
+	var count int64
+	if err := db.Model(&models.MemoRevision{}).Where("list_id = ?", list.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 && list.Memo != "" {
+		initial := models.MemoRevision{ListID: list.ID, Memo: list.Memo, Version: list.Version, CreatedAt: list.UpdatedAt}
+		if err := db.Create(&initial).Error; err != nil {
+			return err
+		}
+	}
+
+	// 同時に保存された場合でも版と一致するよう更新後のバージョンを読み直す
+	if err := db.Model(&models.List{}).Where("id = ?", list.ID).Select("version").Scan(&revision.Version).Error; err != nil {
+		return err
+	}
+	return db.Create(revision).Error
+}
+
+// findRevision returns the revision of the list with the given ID, or writes an
+// error response
+func findRevision(c *gin.Context, listID, revisionID string) (models.MemoRevision, bool) {
+	var revision models.MemoRevision
+	id, err := strconv.ParseUint(revisionID, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID format"})
+		return revision, false
+	}
+	if err := database.DB.Where("id = ? AND list_id = ?", id, listID).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return revision, false
+	}
+	return revision, true
+}
+
+// GetMemoRevisions returns the revisions of the memo of a list, newest first.
+// Pass the returned nextCursor as ?cursor= to get older revisions.
+func GetMemoRevisions(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	limit := defaultRevisionLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxRevisionLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxRevisionLimit)})
+			return
+		}
+		limit = n
+	}
+
+	tx := database.DB.Where("list_id = ?", listID)
+	if s := c.Query("cursor"); s != "" {
+		cursor, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		tx = tx.Where("id < ?", cursor)
+	}
+
+	revisions := []models.MemoRevision{}
+	if err := tx.Order("id DESC").Limit(limit + 1).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+
+	var nextCursor *string
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+		cursor := strconv.FormatUint(uint64(revisions[limit-1].ID), 10)
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":  revisions,
+		"nextCursor": nextCursor,
+	})
+}
+
+// GetMemoRevision returns a revision of the memo of a list
+func GetMemoRevision(c *gin.Context) {
+	revision, ok := findRevision(c, c.Param("listId"), c.Param("revisionId"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "memoHtml": markdown.Render(revision.Memo)})
+}
+
+// GetMemoDiff returns the line-based unified diff between the revisions ?from=
+// and ?to= of the memo of a list. Without ?to= the diff is against the current
+// memo. ?format=text returns the diff as plain text.
+func GetMemoDiff(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be 'json' or 'text'"})
+		return
+	}
+
+	if c.Query("from") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "From revision is required"})
+		return
+	}
+	from, ok := findRevision(c, listID, c.Query("from"))
+	if !ok {
+		return
+	}
+
+	var toID *uint
+	toName, toMemo := "current", list.Memo
+	if s := c.Query("to"); s != "" {
+		to, ok := findRevision(c, listID, s)
+		if !ok {
+			return
+		}
+		toID = &to.ID
+		toName, toMemo = "revision "+strconv.FormatUint(uint64(to.ID), 10), to.Memo
+	}
+
+	unified := diff.Unified("revision "+strconv.FormatUint(uint64(from.ID), 10), toName, from.Memo, toMemo)
+
+	if format == "text" {
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(unified))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from": from.ID,
+		"to":   toID,
+		"diff": unified,
+	})
+}
+
+// RestoreMemoRevision makes an old revision the current memo of a list. The
+// restore is stored as a new revision, so it can be reverted in turn.
+func RestoreMemoRevision(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	restored, ok := findRevision(c, listID, c.Param("revisionId"))
+	if !ok {
+		return
+	}
+
+	version, ok := checkVersion(c, list.Version, nil, memoState(listID))
+	if !ok {
+		return
+	}
+
+	revision, err := saveMemo(database.DB, list, restored.Memo, requestActor(c, listID), version, &restored.ID)
+	if errors.Is(err, errVersionConflict) {
+		respondVersionConflict(c, memoState(listID))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	c.Header("ETag", etag(revision.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"memo":     revision.Memo,
+		"memoHtml": markdown.Render(revision.Memo),
+		"version":  revision.Version,
+		"revision": revision,
+	})
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/ot"
+	"shared-todo-backend/realtime"
+	"shared-todo-backend/webhook"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxMemoOperations is the number of operations GetMemoOperations returns at most
+	maxMemoOperations = 500
+	// revisionSessionTimeout is how long consecutive edits of a user are kept in
+	// the same revision
+	revisionSessionTimeout = 5 * time.Minute
+	// streamKeepAlive is the interval of comments sent on an idle event stream
+	streamKeepAlive = 30 * time.Second
+)
+
+// Hub broadcasts memo operations to the editors connected to StreamMemo
+var Hub = realtime.NewHub()
+
+// operationError is a client error of an operation applied in a transaction,
+// such as a memo operation or an operation of a batch
+type operationError struct {
+	status  int
+	message string
+}
+
+func (e *operationError) Error() string {
+	return e.message
+}
+
+// createOperation logs op as the change of the memo of list, which holds the
+// list before the change
+func createOperation(db *gorm.DB, list models.List, authorID, clientID string, op ot.Operation) (models.MemoOperation, error) {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return models.MemoOperation{}, err
+	}
+	operation := models.MemoOperation{
+		ListID:    list.ID,
+		Version:   list.Version + 1,
+		AuthorID:  authorID,
+		ClientID:  clientID,
+		Operation: data,
+	}
+	return operation, db.Create(&operation).Error
+}
+
+// publishOperation sends a committed operation to the live editors of its list
+func publishOperation(operation models.MemoOperation) {
+	Hub.Publish(operation.ListID, realtime.Event{Name: "operation", Data: operation})
+}
+
+// operationsSince returns the operations of a list after version up to the
+// current version, in order. It reports false when the log does not cover
+// them, e.g. for changes made before operations were logged.
+func operationsSince(db *gorm.DB, list models.List, version int) ([]models.MemoOperation, bool, error) {
+	operations := []models.MemoOperation{}
+	err := db.Where("list_id = ? AND version > ? AND version <= ?", list.ID, version, list.Version).
+		Order("version").
+		Find(&operations).Error
+	return operations, len(operations) == list.Version-version, err
+}
+
+// ApplyMemoOperation applies an edit operation of a live editor to the memo. An
+// operation based on an older version is transformed against the operations
+// applied since, so concurrent edits are merged instead of overwritten. The
+// merged operation is returned and broadcast to the other editors.
+func ApplyMemoOperation(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	var req struct {
+		BaseVersion *int          `json:"baseVersion" binding:"required"`
+		Operation   *ot.Operation `json:"operation" binding:"required"`
+		ClientID    string        `json:"clientId"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	actorID := requestActor(c, listID)
+	var operation models.MemoOperation
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		operation, err = applyOperation(tx, listID, *req.BaseVersion, *req.Operation, actorID, req.ClientID)
+		return err
+	})
+
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		if opErr.status == http.StatusConflict {
+			respondVersionConflict(c, memoState(listID))
+			return
+		}
+		c.JSON(opErr.status, gin.H{"error": opErr.message})
+		return
+	}
+	if errors.Is(err, errVersionConflict) {
+		// 同時に別の操作が適用された場合はクライアントが再送する
+		respondVersionConflict(c, memoState(listID))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply operation"})
+		return
+	}
+	publishOperation(operation)
+
+	c.Header("ETag", etag(operation.Version))
+	c.JSON(http.StatusOK, gin.H{"operation": operation})
+}
+
+// applyOperation applies op, based on baseVersion of the memo of a list, in
+// the transaction tx and records it as activity of actorID. It returns the
+// logged operation after transformation, to be published once tx commits.
+func applyOperation(tx *gorm.DB, listID string, baseVersion int, op ot.Operation, actorID, clientID string) (models.MemoOperation, error) {
+	var current models.List
+	if err := tx.First(&current, "id = ?", listID).Error; err != nil {
+		return models.MemoOperation{}, err
+	}
+	if baseVersion < 1 || baseVersion > current.Version {
+		return models.MemoOperation{}, &operationError{http.StatusBadRequest, "Unknown base version"}
+	}
+
+	concurrent, ok, err := operationsSince(tx, current, baseVersion)
+	if err != nil {
+		return models.MemoOperation{}, err
+	}
+	if !ok {
+		return models.MemoOperation{}, &operationError{http.StatusConflict, "Base version is too old to merge"}
+	}
+
+	// 後から適用された操作に合わせて変換する
+	for _, applied := range concurrent {
+		var appliedOp ot.Operation
+		if err := json.Unmarshal(applied.Operation, &appliedOp); err != nil {
+			return models.MemoOperation{}, err
+		}
+		if op, _, err = ot.Transform(op, appliedOp); err != nil {
+			return models.MemoOperation{}, &operationError{http.StatusBadRequest, "Operation does not match the base version"}
+		}
+	}
+
+	memo, err := op.Apply(current.Memo)
+	if err != nil {
+		return models.MemoOperation{}, &operationError{http.StatusBadRequest, "Operation does not match the base version"}
+	}
+	if len(memo) > 5000 {
+		return models.MemoOperation{}, &operationError{http.StatusBadRequest, "Memo must be 5000 characters or less"}
+	}
+
+	if err := updateVersioned(tx, &models.List{}, listID, current.Version, map[string]interface{}{"memo": memo}); err != nil {
+		return models.MemoOperation{}, err
+	}
+	operation, err := createOperation(tx, current, actorID, clientID, op)
+	if err != nil {
+		return models.MemoOperation{}, err
+	}
+	if err := saveOperationRevision(tx, current, memo, actorID); err != nil {
+		return models.MemoOperation{}, err
+	}
+
+	if err := recordActivity(tx, models.Activity{
+		ListID:     listID,
+		ActorID:    actorID,
+		Action:     models.ActionMemoEdited,
+		TargetType: models.TargetList,
+		TargetID:   listID,
+	}, nil, gin.H{"version": operation.Version, "operation": operation.Operation}); err != nil {
+		return models.MemoOperation{}, err
+	}
+	if current.Memo != memo {
+		emitEvent(tx, listID, webhook.EventMemoUpdated, gin.H{"memo": memo})
+	}
+	return operation, nil
+}
+
+// saveOperationRevision stores the memo after a live edit as a revision.
+// Consecutive edits of the same user are kept in one revision.
+func saveOperationRevision(tx *gorm.DB, list models.List, memo, authorID string) error {
+	var latest models.MemoRevision
+	err := tx.Where("list_id = ?", list.ID).Order("id DESC").First(&latest).Error
+	if err == nil && latest.AuthorID == authorID && latest.RestoreOf == nil && time.Since(latest.CreatedAt) < revisionSessionTimeout {
+		return tx.Model(&latest).Updates(map[string]interface{}{"memo": memo, "version": list.Version + 1}).Error
+	}
+	return createRevision(tx, list, &models.MemoRevision{ListID: list.ID, AuthorID: authorID, Memo: memo})
+}
+
+// GetMemoOperations returns the operations applied to the memo after the
+// version ?since=, for an editor catching up after a reconnect. It responds
+// 409 with the current memo when they are no longer available.
+func GetMemoOperations(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	since, err := strconv.Atoi(c.Query("since"))
+	if err != nil || since < 1 || since > list.Version {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Since must be a version of the memo"})
+		return
+	}
+	if list.Version-since > maxMemoOperations {
+		respondVersionConflict(c, memoState(listID))
+		return
+	}
+
+	operations, ok, err := operationsSince(database.DB, list, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get operations"})
+		return
+	}
+	if !ok {
+		respondVersionConflict(c, memoState(listID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"operations": operations, "version": list.Version})
+}
+
+// StreamMemo streams the memo to a live editor as server-sent events: first a
+// "snapshot" event with the memo and its version, then an "operation" event
+// for every change. An editor ignores operations of versions it already has
+// and reconnects when the stream ends.
+func StreamMemo(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	// 購読してからスナップショットを読み、その間の変更を取りこぼさない
+	sub := Hub.Subscribe(listID)
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("snapshot", memoState(listID)())
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Name, event.Data)
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
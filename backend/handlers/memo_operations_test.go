@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type operationResponse struct {
+	Operation struct {
+		Version   int             `json:"version"`
+		AuthorID  string          `json:"authorId"`
+		ClientID  string          `json:"clientId"`
+		Operation json.RawMessage `json:"operation"`
+	} `json:"operation"`
+}
+
+func (suite *HandlerTestSuite) applyOperation(userID string, baseVersion int, operation string) (int, operationResponse) {
+	w := suite.sendAs("POST", "/api/lists/test-list-id/memo/operations", userID, map[string]interface{}{
+		"baseVersion": baseVersion,
+		"operation":   json.RawMessage(operation),
+		"clientId":    userID + "-client",
+	})
+
+	var response operationResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func (suite *HandlerTestSuite) memo() models.List {
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	return list
+}
+
+func (suite *HandlerTestSuite) TestMergeMemoOperations() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "alice", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "bob", ListID: "test-list-id"})
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk eggs"})
+
+	// 2人が同じバージョンを元に同時に編集する
+	code, alice := suite.applyOperation("alice", 2, `[4, " tea", 5]`)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), 3, alice.Operation.Version)
+	assert.Equal(suite.T(), "alice", alice.Operation.AuthorID)
+	assert.Equal(suite.T(), "alice-client", alice.Operation.ClientID)
+
+	code, bob := suite.applyOperation("bob", 2, `[5, -4, "bread"]`)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), 4, bob.Operation.Version)
+	assert.JSONEq(suite.T(), `[9, "bread", -4]`, string(bob.Operation.Operation))
+
+	list := suite.memo()
+	assert.Equal(suite.T(), "milk tea bread", list.Memo)
+	assert.Equal(suite.T(), 4, list.Version)
+
+	// 続けての編集は同じユーザーの版にまとめられる
+	code, _ = suite.applyOperation("bob", 4, `[14, "!"]`)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	revisions := suite.getRevisions("")
+	suite.Require().Len(revisions, 3)
+	assert.Equal(suite.T(), "bob", revisions[0].AuthorID)
+	assert.Equal(suite.T(), "milk tea bread!", revisions[0].Memo)
+	assert.Equal(suite.T(), 5, revisions[0].Version)
+
+	w := suite.get("/api/lists/test-list-id/memo/operations?since=3")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var response struct {
+		Operations []models.MemoOperation `json:"operations"`
+		Version    int                    `json:"version"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(suite.T(), 5, response.Version)
+	suite.Require().Len(response.Operations, 2)
+	assert.Equal(suite.T(), 4, response.Operations[0].Version)
+	assert.Equal(suite.T(), 5, response.Operations[1].Version)
+}
+
+func (suite *HandlerTestSuite) TestMemoOperationErrors() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "old"})
+	database.DB.Model(&models.List{}).Where("id = ?", "test-list-id").Update("version", 3)
+
+	// 操作の記録がないバージョンからは合成できない
+	code, _ := suite.applyOperation("", 2, `[3, "!"]`)
+	assert.Equal(suite.T(), http.StatusConflict, code)
+	w := suite.get("/api/lists/test-list-id/memo/operations?since=2")
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"memo":"old"`)
+
+	code, _ = suite.applyOperation("", 3, `[2, "!"]`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = suite.applyOperation("", 4, `[3, "!"]`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = suite.applyOperation("", 3, `[3, 0]`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = suite.applyOperation("", 3, `[3, "`+strings.Repeat("a", 5000)+`"]`)
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+
+	assert.Equal(suite.T(), "old", suite.memo().Memo)
+	code, _ = suite.applyOperation("", 3, `[3, "!"]`)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), "old!", suite.memo().Memo)
+}
+
+func (suite *HandlerTestSuite) TestStreamMemo() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/lists/test-list-id/memo/stream")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string, 100)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func(prefix string) string {
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					suite.FailNow("stream closed")
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-time.After(5 * time.Second):
+				suite.FailNow("timed out waiting for " + prefix)
+			}
+		}
+	}
+
+	assert.Equal(suite.T(), "event:snapshot", next("event:"))
+	assert.JSONEq(suite.T(), `{"memo": "milk", "memoHtml": "<p>milk</p>", "version": 1}`, strings.TrimPrefix(next("data:"), "data:"))
+
+	// 全体の保存も操作として配信される
+	body, _ := json.Marshal(map[string]string{"memo": "milk tea"})
+	req, _ := http.NewRequest("PUT", server.URL+"/api/lists/test-list-id/memo", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	put, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	put.Body.Close()
+
+	assert.Equal(suite.T(), "event:operation", next("event:"))
+	var operation models.MemoOperation
+	json.Unmarshal([]byte(strings.TrimPrefix(next("data:"), "data:")), &operation)
+	assert.Equal(suite.T(), 2, operation.Version)
+	assert.JSONEq(suite.T(), `[4, " tea"]`, string(operation.Operation))
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) get(path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) getRevisions(query string) []models.MemoRevision {
+	w := suite.get("/api/lists/test-list-id/memo/revisions" + query)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var response struct {
+		Revisions []models.MemoRevision `json:"revisions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Revisions
+}
+
+func (suite *HandlerTestSuite) TestMemoRevisions() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "test-user-id", map[string]string{"memo": "milk\neggs"})
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "other-user-id", map[string]string{"memo": "bread"})
+
+	// 履歴がない時点のメモも最初の版として残る
+	revisions := suite.getRevisions("")
+	suite.Require().Len(revisions, 3)
+	assert.Equal(suite.T(), "bread", revisions[0].Memo)
+	assert.Equal(suite.T(), "other-user-id", revisions[0].AuthorID)
+	assert.Equal(suite.T(), "test-user-id", revisions[1].AuthorID)
+	assert.Equal(suite.T(), "milk", revisions[2].Memo)
+	assert.Equal(suite.T(), "", revisions[2].AuthorID)
+
+	assert.Len(suite.T(), suite.getRevisions("?limit=1&cursor="+strconv.Itoa(int(revisions[0].ID))), 1)
+
+	w := suite.get("/api/lists/test-list-id/memo/revisions/" + strconv.Itoa(int(revisions[1].ID)))
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"memo":"milk\neggs"`)
+
+	w = suite.get("/api/lists/test-list-id/memo/revisions/999")
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.get("/api/lists/other-list-id/memo/revisions/" + strconv.Itoa(int(revisions[1].ID)))
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestMemoDiff() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk\neggs\nrice"})
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk\nbread\nrice"})
+	revisions := suite.getRevisions("")
+	suite.Require().Len(revisions, 2)
+	from := strconv.Itoa(int(revisions[1].ID))
+	to := strconv.Itoa(int(revisions[0].ID))
+
+	w := suite.get("/api/lists/test-list-id/memo/diff?from=" + from + "&to=" + to)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var response struct {
+		From uint   `json:"from"`
+		To   *uint  `json:"to"`
+		Diff string `json:"diff"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(suite.T(), "--- revision "+from+"\n+++ revision "+to+"\n@@ -1,3 +1,3 @@\n milk\n-eggs\n+bread\n rice\n", response.Diff)
+
+	// to を省略すると現在のメモと比較する
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk\nbread\nrice\ntea"})
+	w = suite.get("/api/lists/test-list-id/memo/diff?format=text&from=" + to)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/x-diff; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), "--- revision "+to+"\n+++ current\n@@ -1,3 +1,4 @@\n milk\n bread\n rice\n+tea\n", w.Body.String())
+
+	w = suite.get("/api/lists/test-list-id/memo/diff")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.get("/api/lists/test-list-id/memo/diff?from=abc")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.get("/api/lists/test-list-id/memo/diff?from=" + from + "&format=html")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestRestoreMemoRevision() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "mine"})
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "theirs"})
+	revisions := suite.getRevisions("")
+	suite.Require().Len(revisions, 2)
+
+	w := suite.sendAs("POST", "/api/lists/test-list-id/memo/revisions/"+strconv.Itoa(int(revisions[1].ID))+"/restore", "test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"memo":"mine"`)
+
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "mine", list.Memo)
+
+	// 復元も新しい版として記録され、元に戻せる
+	revisions = suite.getRevisions("")
+	suite.Require().Len(revisions, 3)
+	assert.Equal(suite.T(), "test-user-id", revisions[0].AuthorID)
+	suite.Require().NotNil(revisions[0].RestoreOf)
+	assert.Equal(suite.T(), revisions[2].ID, *revisions[0].RestoreOf)
+
+	_, activity := suite.getActivity("")
+	suite.Require().NotEmpty(activity.Activities)
+	assert.Equal(suite.T(), models.ActionMemoUpdated, activity.Activities[0].Action)
+	assert.JSONEq(suite.T(), `{"memo": "theirs"}`, string(activity.Activities[0].Before))
+
+	w = suite.postJSON("/api/lists/test-list-id/memo/revisions/999/restore", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/ot"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSyncMutations is the number of mutations a sync request can carry
+const maxSyncMutations = 100
+
+// Outcomes of a client mutation. Applied, superseded and rejected mutations are
+// final and recorded; a failed mutation is not recorded and may be sent again.
+const (
+	mutationApplied    = "applied"
+	mutationSuperseded = "superseded"
+	mutationRejected   = "rejected"
+	mutationFailed     = "failed"
+)
+
+// clientMutation is a change queued by a client while offline. Its ID is
+// generated by the client and identifies the mutation across retries.
+type clientMutation struct {
+	ID        string     `json:"id" binding:"required,max=100"`
+	Type      string     `json:"type" binding:"required"`
+	Timestamp *time.Time `json:"timestamp" binding:"required"`
+
+	// todo.create
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Priority    string   `json:"priority"`
+	DueDate     *string  `json:"dueDate"`
+	AssigneeIDs []string `json:"assigneeIds"`
+
+	// todo.check and todo.uncheck: the todo, or the ID of the todo.create
+	// mutation that created it
+	TodoID  uint   `json:"todoId"`
+	TodoRef string `json:"todoRef"`
+
+	// memo.edit
+	BaseVersion int           `json:"baseVersion"`
+	Operation   *ot.Operation `json:"operation"`
+}
+
+// mutationResult is the outcome of a client mutation sent back to the client
+type mutationResult struct {
+	ID        string                `json:"id"`
+	Status    string                `json:"status"`
+	Error     string                `json:"error,omitempty"`
+	TodoID    uint                  `json:"todoId,omitempty"`
+	Operation *models.MemoOperation `json:"operation,omitempty"`
+}
+
+// SyncList applies the mutations a client queued while offline, in order, and
+// returns their results with the state of the list after them: the changes
+// after the sync cursor ?since= of the request, or the whole list without it.
+// A mutation sent again gets its recorded result and is not applied twice.
+func SyncList(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	var req struct {
+		Since     string           `json:"since"`
+		ClientID  string           `json:"clientId"`
+		Mutations []clientMutation `json:"mutations" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	if len(req.Mutations) > maxSyncMutations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many mutations, send at most " + strconv.Itoa(maxSyncMutations)})
+		return
+	}
+
+	var since uint
+	if req.Since != "" {
+		cursor, _ := latestChange(list)
+		var ok bool
+		if since, ok = parseSyncCursor(req.Since, cursor); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since cursor"})
+			return
+		}
+	}
+
+	results := make([]mutationResult, 0, len(req.Mutations))
+	for _, mutation := range req.Mutations {
+		results = append(results, applyMutation(list, userID, req.ClientID, mutation))
+	}
+
+	// 適用後の状態を返す
+	database.DB.First(&list, "id = ?", listID)
+	cursor, _ := latestChange(list)
+	state := listState(list, user, cursor)
+	if req.Since != "" {
+		var err error
+		if state, err = listChanges(list, user, since, cursor); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get changes"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "state": state})
+}
+
+// applyMutation applies a client mutation of a user, or returns its recorded
+// result when it was already applied. A mutation and its record are stored in
+// one transaction.
+func applyMutation(list models.List, userID, clientID string, mutation clientMutation) mutationResult {
+	// 再送された操作は記録した結果をそのまま返す
+	if result, ok := recordedMutation(database.DB, list.ID, userID, mutation.ID); ok {
+		return result
+	}
+
+	// 端末の時計が進んでいても現在より後の変更にはしない
+	at := mutation.Timestamp.UTC()
+	if now := time.Now().UTC(); at.After(now) {
+		at = now
+	}
+
+	result := mutationResult{ID: mutation.ID, Status: mutationApplied}
+	var applied func()
+	// 取り消される可能性があるため通知はコミット後に送る
+	db, sendNotifications := deferNotifications(database.DB)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch mutation.Type {
+		case models.MutationTodoCreate:
+			err = applyTodoCreate(tx, list, userID, mutation, &result)
+		case models.MutationTodoCheck, models.MutationTodoUncheck:
+			err = applyTodoCheck(tx, list, userID, mutation, at, &result)
+		case models.MutationMemoEdit:
+			applied, err = applyMemoEdit(tx, list, userID, clientID, mutation, &result)
+		default:
+			result = rejectMutation(mutation, "Unknown mutation type")
+		}
+		if err != nil {
+			return err
+		}
+		return recordMutation(tx, list.ID, userID, mutation, at, result)
+	})
+	if err != nil {
+		// 同時に同じ操作が送られた場合は先に記録された結果を返す
+		if recorded, ok := recordedMutation(database.DB, list.ID, userID, mutation.ID); ok {
+			return recorded
+		}
+		log.Printf("Failed to apply mutation %s for list %s: %v", mutation.ID, list.ID, err)
+		return mutationResult{ID: mutation.ID, Status: mutationFailed, Error: "Failed to apply mutation"}
+	}
+	sendNotifications()
+	if applied != nil {
+		applied()
+	}
+	return result
+}
+
+func rejectMutation(mutation clientMutation, message string) mutationResult {
+	return mutationResult{ID: mutation.ID, Status: mutationRejected, Error: message}
+}
+
+// applyTodoCreate creates the todo of a todo.create mutation
+func applyTodoCreate(tx *gorm.DB, list models.List, userID string, mutation clientMutation, result *mutationResult) error {
+	if mutation.Title == "" {
+		*result = rejectMutation(mutation, "Title is required")
+		return nil
+	}
+	todo, assignees, err := newTodo(tx, list, mutation.Title, mutation.Description, mutation.Priority, mutation.DueDate, mutation.AssigneeIDs)
+	if err != nil {
+		*result = rejectMutation(mutation, err.Error())
+		return nil
+	}
+	var users []models.User
+	tx.Where("list_id = ?", list.ID).Find(&users)
+	if err := createTodo(tx, list, users, &todo, assignees, mutation.AssigneeIDs, userID); err != nil {
+		return err
+	}
+	result.TodoID = todo.ID
+	return nil
+}
+
+// applyTodoCheck checks or unchecks a todo for the user. The latest change by
+// timestamp wins: a mutation older than the current check state is superseded.
+func applyTodoCheck(tx *gorm.DB, list models.List, userID string, mutation clientMutation, at time.Time, result *mutationResult) error {
+	todoID := mutation.TodoID
+	if mutation.TodoRef != "" {
+		created, ok := recordedMutation(tx, list.ID, userID, mutation.TodoRef)
+		if !ok || created.TodoID == 0 {
+			*result = rejectMutation(mutation, "Todo reference not found")
+			return nil
+		}
+		todoID = created.TodoID
+	}
+
+	var todo models.Todo
+	if err := tx.Where("id = ? AND list_id = ?", todoID, list.ID).First(&todo).Error; err != nil {
+		*result = rejectMutation(mutation, "Todo not found")
+		return nil
+	}
+	result.TodoID = todo.ID
+
+	var status models.TodoUserStatus
+	if err := tx.Where("todo_id = ? AND user_id = ?", todo.ID, userID).First(&status).Error; err == nil {
+		if status.ChangedAt != nil && status.ChangedAt.After(at) {
+			result.Status = mutationSuperseded
+			return nil
+		}
+	}
+
+	if err := updateVersioned(tx, &models.Todo{}, todo.ID, 0, nil); err != nil {
+		return err
+	}
+	_, err := setChecked(tx, todo, userID, mutation.Type == models.MutationTodoCheck, at)
+	return err
+}
+
+// applyMemoEdit applies the operation of a memo.edit mutation, merged with
+// the edits made since its base version. It returns the function publishing
+// the operation once committed.
+func applyMemoEdit(tx *gorm.DB, list models.List, userID, clientID string, mutation clientMutation, result *mutationResult) (func(), error) {
+	if mutation.Operation == nil {
+		*result = rejectMutation(mutation, "Operation is required")
+		return nil, nil
+	}
+
+	operation, err := applyOperation(tx, list.ID, mutation.BaseVersion, *mutation.Operation, userID, clientID)
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		*result = rejectMutation(mutation, opErr.message)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Operation = &operation
+	return func() { publishOperation(operation) }, nil
+}
+
+// recordedMutation returns the recorded result of a mutation of a user
+func recordedMutation(db *gorm.DB, listID, userID, mutationID string) (mutationResult, bool) {
+	var records []models.ClientMutation
+	db.Where("list_id = ? AND user_id = ? AND mutation_id = ?", listID, userID, mutationID).Limit(1).Find(&records)
+	if len(records) == 0 {
+		return mutationResult{}, false
+	}
+
+	var result mutationResult
+	if err := json.Unmarshal(records[0].Result, &result); err != nil {
+		return mutationResult{}, false
+	}
+	return result, true
+}
+
+// recordMutation stores the result of a mutation. It fails when the mutation
+// was recorded meanwhile, which rolls back the second application.
+func recordMutation(tx *gorm.DB, listID, userID string, mutation clientMutation, at time.Time, result mutationResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.ClientMutation{
+		ListID:     listID,
+		UserID:     userID,
+		MutationID: mutation.ID,
+		Type:       mutation.Type,
+		Timestamp:  at,
+		Result:     data,
+	}).Error
+}
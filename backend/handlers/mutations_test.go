@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type syncResponse struct {
+	Results []mutationResult `json:"results"`
+	State   changesResponse  `json:"state"`
+}
+
+func (suite *HandlerTestSuite) sync(payload interface{}) (int, syncResponse) {
+	w := suite.postJSON("/api/lists/test-list-id/users/test-user-id/sync", payload)
+
+	var response syncResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func (suite *HandlerTestSuite) TestSyncMutations() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	now := time.Now().UTC()
+	payload := map[string]interface{}{
+		"clientId": "phone",
+		"mutations": []map[string]interface{}{
+			{"id": "m1", "type": "todo.create", "timestamp": now.Add(-3 * time.Minute), "title": "Eggs"},
+			{"id": "m2", "type": "todo.check", "timestamp": now.Add(-2 * time.Minute), "todoRef": "m1"},
+			{"id": "m3", "type": "memo.edit", "timestamp": now.Add(-time.Minute), "baseVersion": 1, "operation": json.RawMessage(`[4, " tea"]`)},
+		},
+	}
+
+	code, response := suite.sync(payload)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	suite.Require().Len(response.Results, 3)
+	for _, result := range response.Results {
+		assert.Equal(suite.T(), mutationApplied, result.Status, result.ID)
+	}
+	todoID := response.Results[0].TodoID
+	assert.NotZero(suite.T(), todoID)
+	assert.Equal(suite.T(), todoID, response.Results[1].TodoID)
+	suite.Require().NotNil(response.Results[2].Operation)
+	assert.Equal(suite.T(), "phone", response.Results[2].Operation.ClientID)
+
+	suite.Require().Len(response.State.Todos, 1)
+	assert.True(suite.T(), response.State.Todos[0].IsCompleted)
+	suite.Require().NotNil(response.State.Memo)
+	assert.Equal(suite.T(), "milk tea", *response.State.Memo)
+
+	// 再送しても二重に適用されない
+	code, retried := suite.sync(payload)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), response.Results, retried.Results)
+	assert.Equal(suite.T(), response.State.SyncCursor, retried.State.SyncCursor)
+
+	var count int64
+	database.DB.Model(&models.Todo{}).Where("list_id = ?", "test-list-id").Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+	assert.Equal(suite.T(), "milk tea", suite.memo().Memo)
+}
+
+func (suite *HandlerTestSuite) TestSyncCheckConflict() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	todo := models.Todo{ListID: "test-list-id", Title: "Eggs"}
+	database.DB.Create(&todo)
+	database.DB.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "test-user-id"})
+
+	// 別の端末でオンラインのままチェックした
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/test-user-id", map[string]bool{"checked": true})
+	cursor := suite.getChanges("0").SyncCursor
+
+	// それより前にオフラインで外したチェックは上書きしない
+	code, response := suite.sync(map[string]interface{}{
+		"since": cursor,
+		"mutations": []map[string]interface{}{
+			{"id": "m1", "type": "todo.uncheck", "timestamp": time.Now().Add(-time.Hour), "todoId": todo.ID},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), mutationSuperseded, response.Results[0].Status)
+	assert.Empty(suite.T(), response.State.Todos)
+
+	// 端末の時計が進んでいても現在の変更として扱う
+	code, response = suite.sync(map[string]interface{}{
+		"since": cursor,
+		"mutations": []map[string]interface{}{
+			{"id": "m2", "type": "todo.uncheck", "timestamp": time.Now().Add(time.Hour), "todoId": todo.ID},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), mutationApplied, response.Results[0].Status)
+	suite.Require().Len(response.State.Todos, 1)
+	assert.False(suite.T(), response.State.Todos[0].UserStatuses[0].IsChecked)
+
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ?", todo.ID).First(&status)
+	assert.False(suite.T(), status.ChangedAt.After(time.Now()))
+}
+
+func (suite *HandlerTestSuite) TestSyncRejectedMutations() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	now := time.Now()
+	code, response := suite.sync(map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"id": "m1", "type": "todo.rename", "timestamp": now},
+			{"id": "m2", "type": "todo.check", "timestamp": now, "todoId": 999},
+			{"id": "m3", "type": "todo.check", "timestamp": now, "todoRef": "unknown"},
+			{"id": "m4", "type": "todo.create", "timestamp": now, "title": "Eggs", "priority": "urgent"},
+			{"id": "m5", "type": "memo.edit", "timestamp": now, "baseVersion": 1, "operation": json.RawMessage(`[2, "!"]`)},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	suite.Require().Len(response.Results, 5)
+	for _, result := range response.Results {
+		assert.Equal(suite.T(), mutationRejected, result.Status, result.ID)
+		assert.NotEmpty(suite.T(), result.Error, result.ID)
+	}
+	assert.Equal(suite.T(), "milk", suite.memo().Memo)
+
+	tooMany := []map[string]interface{}{}
+	for i := 0; i <= maxSyncMutations; i++ {
+		tooMany = append(tooMany, map[string]interface{}{"id": "n" + strconv.Itoa(i), "type": "todo.check", "timestamp": now, "todoId": 1})
+	}
+	for _, payload := range []map[string]interface{}{
+		{"since": "100"},
+		{"mutations": []map[string]interface{}{{"id": "m6", "type": "todo.check"}}},
+		{"mutations": tooMany},
+	} {
+		code, _ := suite.sync(payload)
+		assert.Equal(suite.T(), http.StatusBadRequest, code)
+	}
+}
+
+func (suite *HandlerTestSuite) TestSyncFailedMutationSendsNoNotifications() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Eggs"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/other-user-id", map[string]bool{"checked": true})
+
+	// 操作を記録できずに巻き戻ったチェックでは完了を通知しない
+	suite.Require().NoError(database.DB.Exec("CREATE TRIGGER fail_client_mutations BEFORE INSERT ON client_mutations BEGIN SELECT RAISE(ABORT, 'unavailable'); END").Error)
+	sent := suite.captureNotifications()
+	code, response := suite.sync(map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"id": "m1", "type": "todo.check", "timestamp": time.Now(), "todoId": todo.ID},
+		},
+	})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	suite.Require().Len(response.Results, 1)
+	assert.Equal(suite.T(), mutationFailed, response.Results[0].Status)
+	assert.Empty(suite.T(), *sent)
+
+	database.DB.First(&todo, todo.ID)
+	assert.False(suite.T(), todo.IsCompleted)
+}
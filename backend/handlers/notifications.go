@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+// GetNotifications lists the in-app notifications of a user, newest first
+func GetNotifications(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	limit := defaultNotificationLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxNotificationLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxNotificationLimit)})
+			return
+		}
+		limit = n
+	}
+
+	tx := database.DB.Where("list_id = ? AND user_id = ?", listID, userID)
+	if c.Query("unread") == "true" {
+		tx = tx.Where("read_at IS NULL")
+	}
+
+	notifications := []models.Notification{}
+	if err := tx.Order("id DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+
+	var unread int64
+	database.DB.Model(&models.Notification{}).Where("list_id = ? AND user_id = ? AND read_at IS NULL", listID, userID).Count(&unread)
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unreadCount":   unread,
+	})
+}
+
+// MarkNotificationsRead marks notifications of a user as read.
+// Without IDs every notification of the user is marked.
+func MarkNotificationsRead(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	var req struct {
+		IDs []uint `json:"ids"`
+	}
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+			return
+		}
+	}
+
+	tx := database.DB.Model(&models.Notification{}).Where("list_id = ? AND user_id = ? AND read_at IS NULL", listID, userID)
+	if len(req.IDs) > 0 {
+		tx = tx.Where("id IN ?", req.IDs)
+	}
+
+	result := tx.Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
+
+// Notifier delivers notifications caused by requests, such as a todo being
+// completed or assigned. Nothing is sent when it is nil.
+var Notifier notify.Notifier
+
+// sendNotification delivers msg through Notifier. A failure is logged and does
+// not fail the request that caused the notification.
+func sendNotification(msg notify.Message) {
+	if Notifier == nil {
+		return
+	}
+	if err := Notifier.Notify(context.Background(), msg); err != nil {
+		log.Printf("Failed to send %s notification to user %s: %v", msg.Type, msg.User.ID, err)
+	}
+}
+
+// pendingNotifications is the context key of the notifications held back by
+// deferNotifications
+type pendingNotifications struct{}
+
+// deferNotifications returns db with the notifications of changes made through
+// it held back until send is called, for changes that may still be rolled back
+func deferNotifications(db *gorm.DB) (*gorm.DB, func()) {
+	pending := &[]notify.Message{}
+	ctx := context.WithValue(db.Statement.Context, pendingNotifications{}, pending)
+	return db.WithContext(ctx), func() {
+		for _, msg := range *pending {
+			sendNotification(msg)
+		}
+	}
+}
+
+// queueNotification sends msg, or holds it back when db defers notifications
+func queueNotification(db *gorm.DB, msg notify.Message) {
+	if ctx := db.Statement.Context; ctx != nil {
+		if pending, ok := ctx.Value(pendingNotifications{}).(*[]notify.Message); ok {
+			*pending = append(*pending, msg)
+			return
+		}
+	}
+	sendNotification(msg)
+}
+
+// notifyCompleted tells every user of the list except the one who made the last
+// check that todo was completed
+func notifyCompleted(db *gorm.DB, list models.List, todo *models.Todo, actorID string) {
+	var users []models.User
+	db.Where("list_id = ? AND id <> ?", list.ID, actorID).Find(&users)
+
+	for _, user := range users {
+		queueNotification(db, notify.Message{
+			Type:    notify.TypeCompleted,
+			List:    list,
+			User:    user,
+			Todo:    todo,
+			Subject: fmt.Sprintf("Completed: %s", todo.Title),
+			Body:    fmt.Sprintf("\"%s\" was checked by everyone and is now complete.", todo.Title),
+		})
+	}
+}
+
+// notifyAssigned tells users that they were assigned todo
+func notifyAssigned(db *gorm.DB, list models.List, todo *models.Todo, userIDs []string) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	var users []models.User
+	db.Where("list_id = ? AND id IN ?", list.ID, userIDs).Find(&users)
+
+	for _, user := range users {
+		queueNotification(db, notify.Message{
+			Type:    notify.TypeAssigned,
+			List:    list,
+			User:    user,
+			Todo:    todo,
+			Subject: fmt.Sprintf("Assigned: %s", todo.Title),
+			Body:    fmt.Sprintf("You were assigned \"%s\".", todo.Title),
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) getNotifications(query string) (int, []models.Notification, int64) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/users/test-user-id/notifications"+query, nil)
+	suite.router.ServeHTTP(w, req)
+
+	var response struct {
+		Notifications []models.Notification `json:"notifications"`
+		UnreadCount   int64                 `json:"unreadCount"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Notifications, response.UnreadCount
+}
+
+func (suite *HandlerTestSuite) TestNotifications() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+	first := models.Notification{ListID: "test-list-id", UserID: "test-user-id", Type: "reminder", Subject: "first"}
+	database.DB.Create(&first)
+	database.DB.Create(&models.Notification{ListID: "test-list-id", UserID: "test-user-id", Type: "overdue", Subject: "second"})
+	database.DB.Create(&models.Notification{ListID: "test-list-id", UserID: "other-user-id", Type: "overdue", Subject: "other"})
+
+	code, notifications, unread := suite.getNotifications("")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Len(suite.T(), notifications, 2)
+	assert.Equal(suite.T(), "second", notifications[0].Subject)
+	assert.Equal(suite.T(), int64(2), unread)
+
+	w := suite.postJSON("/api/lists/test-list-id/users/test-user-id/notifications/read", map[string][]uint{"ids": {first.ID}})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	_, notifications, unread = suite.getNotifications("?unread=true")
+	assert.Len(suite.T(), notifications, 1)
+	assert.Equal(suite.T(), "second", notifications[0].Subject)
+	assert.Equal(suite.T(), int64(1), unread)
+
+	// IDを指定しない場合はすべて既読にする
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/lists/test-list-id/users/test-user-id/notifications/read", nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	_, _, unread = suite.getNotifications("")
+	assert.Equal(suite.T(), int64(0), unread)
+
+	var other models.Notification
+	database.DB.First(&other, "user_id = ?", "other-user-id")
+	assert.Nil(suite.T(), other.ReadAt)
+}
+
+func (suite *HandlerTestSuite) TestUpdateUserPreferencesReminderLeadTime() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	var user models.User
+	database.DB.First(&user, "id = ?", "test-user-id")
+	assert.Equal(suite.T(), 60, user.ReminderLeadMinutes)
+
+	w := suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]int{"reminderLeadMinutes": 0})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	database.DB.First(&user, "id = ?", "test-user-id")
+	assert.Equal(suite.T(), 0, user.ReminderLeadMinutes)
+
+	w = suite.putJSON("/api/lists/test-list-id/users/test-user-id/preferences", map[string]int{"reminderLeadMinutes": -5})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"net/mail"
+	"shared-todo-backend/database"
+	"shared-todo-backend/digest"
+	"shared-todo-backend/models"
+	"shared-todo-backend/scheduler"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateUserPreferences updates the personal settings of a user.
+// Only the fields present in the request are changed.
+func UpdateUserPreferences(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	var req struct {
+		TimeZone            *string `json:"timeZone"`
+		ReminderLeadMinutes *int    `json:"reminderLeadMinutes"`
+		Email               *string `json:"email"`
+		EmailOnComplete     *bool   `json:"emailOnComplete"`
+		EmailOnAssign       *bool   `json:"emailOnAssign"`
+		EmailDigest         *bool   `json:"emailDigest"`
+		DigestFrequency     *string `json:"digestFrequency"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+
+	// 空文字はリストのタイムゾーンを使う設定に戻す
+	if req.TimeZone != nil {
+		if *req.TimeZone != "" && !models.ValidTimeZone(*req.TimeZone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone"})
+			return
+		}
+		updates["time_zone"] = *req.TimeZone
+	}
+
+	// 0は期限前のリマインダーを送らない
+	if req.ReminderLeadMinutes != nil {
+		if *req.ReminderLeadMinutes < 0 || *req.ReminderLeadMinutes > scheduler.MaxLeadMinutes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reminder lead time must be between 0 and " + strconv.Itoa(scheduler.MaxLeadMinutes) + " minutes"})
+			return
+		}
+		updates["reminder_lead_minutes"] = *req.ReminderLeadMinutes
+	}
+
+	// 空文字はメール通知を停止する
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if email != "" && !validEmail(email) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+			return
+		}
+		updates["email"] = email
+	}
+	if req.EmailOnComplete != nil {
+		updates["email_on_complete"] = *req.EmailOnComplete
+	}
+	if req.EmailOnAssign != nil {
+		updates["email_on_assign"] = *req.EmailOnAssign
+	}
+	if req.EmailDigest != nil {
+		updates["email_digest"] = *req.EmailDigest
+	}
+
+	if req.DigestFrequency != nil {
+		if !digest.ValidFrequency(*req.DigestFrequency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Digest frequency must be 'off', 'daily', or 'weekly'"})
+			return
+		}
+		updates["digest_frequency"] = *req.DigestFrequency
+	}
+
+	if len(updates) > 0 {
+		before := preferencesActivity(user)
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+				return err
+			}
+			return recordActivity(tx, models.Activity{
+				ListID:     listID,
+				ActorID:    userID,
+				Action:     models.ActionPreferencesUpdated,
+				TargetType: models.TargetUser,
+				TargetID:   userID,
+			}, before, preferencesActivity(user))
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, preferencesResponse(user))
+}
+
+// GetUserPreferences returns the personal settings of a user
+func GetUserPreferences(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferencesResponse(user))
+}
+
+// preferencesActivity returns the preferences of a user as recorded in the
+// activity log, which other users can read. The email address is left out.
+func preferencesActivity(user models.User) gin.H {
+	values := preferencesResponse(user)
+	delete(values, "email")
+	values["hasEmail"] = user.Email != ""
+	return values
+}
+
+// preferencesResponse returns the preferences of a user as sent to clients
+func preferencesResponse(user models.User) gin.H {
+	return gin.H{
+		"timeZone":            user.TimeZone,
+		"reminderLeadMinutes": user.ReminderLeadMinutes,
+		"email":               user.Email,
+		"emailOnComplete":     user.EmailOnComplete,
+		"emailOnAssign":       user.EmailOnAssign,
+		"emailDigest":         user.EmailDigest,
+		"digestFrequency":     user.DigestFrequency,
+	}
+}
+
+// validEmail reports whether email is a bare email address
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && len(email) <= 254
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) TestCreateTodoQuickAdd() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id", DisplayName: "Bob"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]interface{}{
+		"quickAdd": "Buy milk tomorrow !high #groceries @alice",
+		"labels":   []string{"#shopping", "groceries"},
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var todo models.Todo
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &todo))
+	assert.Equal(suite.T(), "Buy milk", todo.Title)
+	assert.Equal(suite.T(), "high", todo.Priority)
+	assert.Equal(suite.T(), models.Labels{"shopping", "groceries"}, todo.Labels)
+	suite.Require().NotNil(todo.DueDate)
+	tomorrow := time.Now().In(models.LoadLocation("Asia/Tokyo")).AddDate(0, 0, 1).Format("2006-01-02")
+	assert.Equal(suite.T(), tomorrow, todo.DueDate.Format("2006-01-02"))
+	assert.False(suite.T(), todo.HasDueTime)
+
+	var count int64
+	database.DB.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND user_id = ? AND is_assigned = ?", todo.ID, "test-user-id", true).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// 入力された項目が優先される
+	w = suite.postJSON("/api/lists/test-list-id/todos", map[string]interface{}{
+		"quickAdd": "明日 牛乳 !high",
+		"title":    "Milk",
+		"priority": "low",
+		"dueDate":  "2030-01-01",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &todo))
+	assert.Equal(suite.T(), "Milk", todo.Title)
+	assert.Equal(suite.T(), "low", todo.Priority)
+	assert.Equal(suite.T(), "2030-01-01", todo.DueDate.Format("2006-01-02"))
+	assert.Equal(suite.T(), models.Labels{}, todo.Labels)
+}
+
+func (suite *HandlerTestSuite) TestCreateTodoQuickAddInvalid() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+
+	for _, payload := range []map[string]interface{}{
+		{"quickAdd": "Buy milk @carol"},
+		{"quickAdd": "#groceries !high"},
+		{"title": "Milk", "labels": []string{"this label is much longer than fifty characters in total"}},
+	} {
+		w := suite.postJSON("/api/lists/test-list-id/todos", payload)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, payload)
+	}
+
+	var count int64
+	database.DB.Model(&models.Todo{}).Count(&count)
+	assert.Equal(suite.T(), int64(0), count)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"shared-todo-backend/database"
+	"shared-todo-backend/markdown"
+	"shared-todo-backend/models"
+	"time"
+)
+
+// renderTodos fills the rendered HTML and the due state of todos as seen in loc
+func renderTodos(todos []models.Todo, loc *time.Location) {
+	now := time.Now()
+	for i := range todos {
+		renderTodo(&todos[i], now, loc)
+	}
+}
+
+func renderTodo(todo *models.Todo, now time.Time, loc *time.Location) {
+	todo.DescriptionHTML = markdown.Render(todo.Description)
+	todo.UpdateDueState(now, loc)
+}
+
+// todoState returns a todo with its check states as sent in a version conflict
+func todoState(todoID uint) func() interface{} {
+	return func() interface{} {
+		var todo models.Todo
+		if err := database.DB.Preload("UserStatuses").First(&todo, todoID).Error; err != nil {
+			return nil
+		}
+		var list models.List
+		database.DB.First(&list, "id = ?", todo.ListID)
+		renderTodo(&todo, time.Now(), models.LoadLocation(list.TimeZone))
+		return todo
+	}
+}
+
+// todoVersion returns the current version of a todo
+func todoVersion(todoID uint) int {
+	var version int
+	database.DB.Model(&models.Todo{}).Where("id = ?", todoID).Select("version").Scan(&version)
+	return version
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/markdown"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reportPriorities are the priorities in the order the report groups todos by
+var reportPriorities = []struct{ Priority, Title string }{
+	{"high", "High priority"},
+	{"medium", "Medium priority"},
+	{"low", "Low priority"},
+}
+
+// reportTodo is a todo as shown in a report
+type reportTodo struct {
+	Title           string
+	Labels          string
+	Due             string
+	IsOverdue       bool
+	Completed       string
+	DescriptionHTML template.HTML
+}
+
+// reportGroup is the todos of one priority within a section of a report
+type reportGroup struct {
+	Title string
+	Todos []reportTodo
+}
+
+// reportSection is the open or the completed todos of a report
+type reportSection struct {
+	Title  string
+	Count  int
+	Groups []reportGroup
+	Empty  string
+}
+
+// reportCheck is a cell of the check matrix: whether and when a user checked
+// a todo
+type reportCheck struct {
+	IsChecked  bool
+	IsAssigned bool
+	CheckedAt  string
+}
+
+// reportRow is a todo in the check matrix
+type reportRow struct {
+	Title       string
+	IsCompleted bool
+	Checks      []reportCheck
+}
+
+type reportData struct {
+	ListID      string
+	TimeZone    string
+	GeneratedAt string
+	MemoHTML    template.HTML
+	Total       int
+	Completed   int
+	Sections    []reportSection
+	Users       []string
+	Rows        []reportRow
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Shared ToDo report</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; color: #222; margin: 2em; }
+h1 { font-size: 1.5em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; border-bottom: 1px solid #ccc; padding-bottom: 0.2em; margin-top: 1.5em; }
+h3 { font-size: 1em; margin-bottom: 0.3em; }
+.meta, small { color: #666; }
+.memo { border-left: 3px solid #ccc; padding-left: 1em; }
+.overdue { color: #c00; }
+.description { color: #444; margin: 0.2em 0 0.5em; }
+.description p { margin: 0.2em 0; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.5em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+td.check { white-space: nowrap; }
+tr.completed td:first-child { text-decoration: line-through; color: #666; }
+@media print {
+  body { margin: 0; font-size: 11px; }
+  h2 { page-break-after: avoid; }
+  tr, li { page-break-inside: avoid; }
+}
+</style>
+</head>
+<body>
+<h1>Shared ToDo report</h1>
+<p class="meta">List {{.ListID}} &middot; {{.Completed}} of {{.Total}} completed &middot; Generated {{.GeneratedAt}} ({{.TimeZone}})</p>
+<h2>Memo</h2>
+{{- if .MemoHTML}}
+<div class="memo">{{.MemoHTML}}</div>
+{{- else}}
+<p>No memo.</p>
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Title}} ({{.Count}})</h2>
+{{- range .Groups}}
+<h3>{{.Title}}</h3>
+<ul>
+{{- range .Todos}}
+<li>{{.Title}}
+{{- if .Labels}} <small>[{{.Labels}}]</small>{{end}}
+{{- if .Due}} <small{{if .IsOverdue}} class="overdue"{{end}}>(due {{.Due}}{{if .IsOverdue}}, overdue{{end}})</small>{{end}}
+{{- if .Completed}} <small>(completed {{.Completed}})</small>{{end}}
+{{- if .DescriptionHTML}}
+<div class="description">{{.DescriptionHTML}}</div>
+{{- end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>{{.Empty}}</p>
+{{- end}}
+{{- end}}
+<h2>Checks</h2>
+{{- if .Rows}}
+<table>
+<tr><th>Todo</th>{{range .Users}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr{{if .IsCompleted}} class="completed"{{end}}><td>{{.Title}}</td>
+{{- range .Checks}}<td class="check">{{if .IsChecked}}&#10003; {{.CheckedAt}}{{else}}&ndash;{{end}}{{if .IsAssigned}} <small>(assigned)</small>{{end}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No todos.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// GetReport returns a printable HTML report of a list for meeting minutes:
+// the memo, the todos grouped by status and priority, and when each user
+// checked each todo. Times are shown in the time zone of the list.
+func GetReport(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	users, todos := exportData(database.DB, list.ID)
+	loc := models.LoadLocation(list.TimeZone)
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, buildReport(list, users, todos, time.Now(), loc)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render report"})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+func buildReport(list models.List, users []models.User, todos []models.Todo, now time.Time, loc *time.Location) reportData {
+	data := reportData{
+		ListID:      list.ID,
+		TimeZone:    loc.String(),
+		GeneratedAt: now.In(loc).Format("2006-01-02 15:04"),
+		// markdown.Render はHTMLをエスケープ済み
+		MemoHTML: template.HTML(markdown.Render(list.Memo)),
+		Total:    len(todos),
+		Users:    csvUserNames(users),
+	}
+
+	open := reportSection{Title: "Open", Empty: "Nothing is open."}
+	completed := reportSection{Title: "Completed", Empty: "Nothing is completed."}
+	for _, p := range reportPriorities {
+		openGroup := reportGroup{Title: p.Title}
+		completedGroup := reportGroup{Title: p.Title}
+		for _, todo := range todos {
+			if todo.Priority != p.Priority {
+				continue
+			}
+			if todo.IsCompleted {
+				completedGroup.Todos = append(completedGroup.Todos, newReportTodo(todo, now, loc))
+			} else {
+				openGroup.Todos = append(openGroup.Todos, newReportTodo(todo, now, loc))
+			}
+		}
+		if len(openGroup.Todos) > 0 {
+			open.Groups = append(open.Groups, openGroup)
+			open.Count += len(openGroup.Todos)
+		}
+		if len(completedGroup.Todos) > 0 {
+			completed.Groups = append(completed.Groups, completedGroup)
+			completed.Count += len(completedGroup.Todos)
+		}
+	}
+	data.Sections = []reportSection{open, completed}
+	data.Completed = completed.Count
+
+	for _, todo := range todos {
+		statuses := make(map[string]models.TodoUserStatus)
+		for _, status := range todo.UserStatuses {
+			statuses[status.UserID] = status
+		}
+		row := reportRow{Title: todo.Title, IsCompleted: todo.IsCompleted}
+		for _, user := range users {
+			status := statuses[user.ID]
+			check := reportCheck{IsChecked: status.IsChecked, IsAssigned: status.IsAssigned}
+			if status.IsChecked && status.CheckedAt != nil {
+				check.CheckedAt = status.CheckedAt.In(loc).Format("2006-01-02 15:04")
+			}
+			row.Checks = append(row.Checks, check)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return data
+}
+
+func newReportTodo(todo models.Todo, now time.Time, loc *time.Location) reportTodo {
+	todo.UpdateDueState(now, loc)
+	item := reportTodo{
+		Title:           todo.Title,
+		Labels:          strings.Join(todo.Labels, ", "),
+		Due:             strings.Replace(formatDue(todo, loc), "T", " ", 1),
+		IsOverdue:       todo.IsOverdue,
+		DescriptionHTML: template.HTML(markdown.Render(todo.Description)),
+	}
+	if todo.IsCompleted {
+		item.Completed = completedAt(todo.CompletedAt, todo.UpdatedAt).In(loc).Format("2006-01-02 15:04")
+	}
+	return item
+}
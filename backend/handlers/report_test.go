@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) TestGetReport() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo", Memo: "# Agenda\n\n<script>alert(1)</script>"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	checkedAt := time.Date(2024, 4, 30, 3, 0, 0, 0, time.UTC)
+	todo := models.Todo{ListID: "test-list-id", Title: "<b>Budget</b>", Priority: "high"}
+	database.DB.Create(&todo)
+	database.DB.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "test-user-id", IsChecked: true, CheckedAt: &checkedAt})
+	database.DB.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "other-user-id", IsAssigned: true})
+
+	w := suite.get("/api/lists/test-list-id/report")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(suite.T(), body, "<h1>Agenda</h1>")
+	assert.NotContains(suite.T(), body, "<script>")
+	assert.NotContains(suite.T(), body, "<b>Budget</b>")
+	assert.Contains(suite.T(), body, "&lt;b&gt;Budget&lt;/b&gt;")
+	assert.Contains(suite.T(), body, "<th>Alice</th><th>other-user-id</th>")
+	assert.Contains(suite.T(), body, "&#10003; 2024-04-30 12:00")
+	assert.Contains(suite.T(), body, "(assigned)")
+
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/api/lists/missing/report").Code)
+}
+
+func (suite *HandlerTestSuite) TestBuildReport() {
+	tokyo := models.LoadLocation("Asia/Tokyo")
+	now := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)
+	checkedAt := time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC)
+
+	list := models.List{ID: "test-list-id"}
+	users := []models.User{{ID: "user-a", DisplayName: "Alice"}, {ID: "bob"}}
+	todos := []models.Todo{
+		{Title: "Late", Priority: "low", DueDate: &due, Labels: models.Labels{"a", "b"}},
+		{Title: "Done", Priority: "high", IsCompleted: true, CompletedAt: &completed, UserStatuses: []models.TodoUserStatus{
+			{UserID: "user-a", IsChecked: true, CheckedAt: &checkedAt},
+			{UserID: "bob", IsChecked: true, CheckedAt: &completed},
+		}},
+		{Title: "Urgent", Priority: "high"},
+		{Title: "Soon", Priority: "low"},
+	}
+
+	data := buildReport(list, users, todos, now, tokyo)
+	assert.Equal(suite.T(), "Asia/Tokyo", data.TimeZone)
+	assert.Equal(suite.T(), "2024-05-10 09:00", data.GeneratedAt)
+	assert.Equal(suite.T(), 4, data.Total)
+	assert.Equal(suite.T(), 1, data.Completed)
+	assert.Equal(suite.T(), []string{"Alice", "bob"}, data.Users)
+
+	// 状態ごと、その中で優先度ごとにまとめる
+	open := data.Sections[0]
+	assert.Equal(suite.T(), 3, open.Count)
+	suite.Require().Len(open.Groups, 2)
+	assert.Equal(suite.T(), "High priority", open.Groups[0].Title)
+	assert.Equal(suite.T(), "Urgent", open.Groups[0].Todos[0].Title)
+	assert.Equal(suite.T(), "Low priority", open.Groups[1].Title)
+	assert.Equal(suite.T(), reportTodo{Title: "Late", Labels: "a, b", Due: "2024-05-01", IsOverdue: true}, open.Groups[1].Todos[0])
+	assert.Equal(suite.T(), "Soon", open.Groups[1].Todos[1].Title)
+
+	done := data.Sections[1]
+	assert.Equal(suite.T(), 1, done.Count)
+	assert.Equal(suite.T(), "2024-05-02 12:00", done.Groups[0].Todos[0].Completed)
+
+	suite.Require().Len(data.Rows, 4)
+	assert.Equal(suite.T(), []reportCheck{{}, {}}, data.Rows[0].Checks)
+	assert.True(suite.T(), data.Rows[1].IsCompleted)
+	assert.Equal(suite.T(), []reportCheck{{IsChecked: true, CheckedAt: "2024-05-02 11:00"}, {IsChecked: true, CheckedAt: "2024-05-02 12:00"}}, data.Rows[1].Checks)
+}
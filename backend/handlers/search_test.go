@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/search"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) searchList(q string) (int, []search.Result) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/search?q="+url.QueryEscape(q), nil)
+	suite.router.ServeHTTP(w, req)
+
+	var response struct {
+		Results []search.Result `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Results
+}
+
+func (suite *HandlerTestSuite) TestSearchList() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "Remember to bring the receipt"})
+	database.DB.Create(&models.List{ID: "other-list-id", Memo: "receipt"})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Buy milk", Priority: "medium"})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Print the receipt", Priority: "medium"})
+	database.DB.Create(&models.Todo{ListID: "other-list-id", Title: "Print receipt", Priority: "medium"})
+
+	code, results := suite.searchList("receipt")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Len(suite.T(), results, 2)
+	// タイトルの一致はメモより上位
+	assert.Equal(suite.T(), "todo", results[0].Type)
+	assert.Equal(suite.T(), "Print the <mark>receipt</mark>", results[0].Title)
+	assert.Equal(suite.T(), "memo", results[1].Type)
+	assert.Contains(suite.T(), results[1].Snippet, "<mark>receipt</mark>")
+
+	// 短い語はLIKE検索にフォールバックする
+	code, results = suite.searchList("mi")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Len(suite.T(), results, 1)
+	assert.Equal(suite.T(), "Buy <mark>mi</mark>lk", results[0].Title)
+}
+
+func (suite *HandlerTestSuite) TestSearchListInvalidRequest() {
+	code, _ := suite.searchList("milk")
+	assert.Equal(suite.T(), http.StatusNotFound, code)
+
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	code, _ = suite.searchList("  ")
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+}
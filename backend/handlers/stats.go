@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultStatsDays is the number of days in the completion series of stats
+	defaultStatsDays = 30
+	// maxStatsDays is the largest number of days that can be requested
+	maxStatsDays = 365
+)
+
+// statusCounts counts todos by status
+type statusCounts struct {
+	Total     int `json:"total"`
+	Open      int `json:"open"`
+	Completed int `json:"completed"`
+}
+
+func (s *statusCounts) add(todo models.Todo) {
+	s.Total++
+	if todo.IsCompleted {
+		s.Completed++
+	} else {
+		s.Open++
+	}
+}
+
+// userStats is how far a user of a list has checked its todos
+type userStats struct {
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+	Checked     int    `json:"checked"`
+	Assigned    int    `json:"assigned"`
+	// CheckRate is the percentage of the todos of the list the user checked
+	CheckRate float64 `json:"checkRate"`
+	// AverageCheckMinutes is the average time from the creation of a todo to
+	// the user checking it, or nil when the user checked none. Checks made
+	// before the todo was created, which were imported, are left out.
+	AverageCheckMinutes *float64 `json:"averageCheckMinutes"`
+}
+
+// dailyCompletions is the number of todos completed on a day
+type dailyCompletions struct {
+	Date      string `json:"date"`
+	Completed int    `json:"completed"`
+}
+
+// listStats is the progress of a list as returned by GetStats
+type listStats struct {
+	ListID      string    `json:"listId"`
+	TimeZone    string    `json:"timeZone"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	statusCounts
+	Overdue int `json:"overdue"`
+	// CompletionRate is the percentage of todos completed
+	CompletionRate float64                 `json:"completionRate"`
+	ByPriority     map[string]statusCounts `json:"byPriority"`
+	Users          []userStats             `json:"users"`
+	// AverageCompletionMinutes is the average time from the creation of a
+	// todo to its completion, or nil when none is completed. Like checks,
+	// completions before the creation of a todo are left out.
+	AverageCompletionMinutes *float64           `json:"averageCompletionMinutes"`
+	Daily                    []dailyCompletions `json:"daily"`
+}
+
+// GetStats returns the progress of a list: todos by status and priority, the
+// overdue todos, how far each user has checked and how long todos take to
+// complete. Daily lists the todos completed on each of the last ?days= days
+// (30 by default), ending today in the time zone of the list.
+func GetStats(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	days := defaultStatsDays
+	if s := c.Query("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxStatsDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Days must be between 1 and " + strconv.Itoa(maxStatsDays)})
+			return
+		}
+		days = n
+	}
+
+	users, todos := exportData(database.DB, list.ID)
+	c.JSON(http.StatusOK, buildStats(list, users, todos, days, time.Now()))
+}
+
+func buildStats(list models.List, users []models.User, todos []models.Todo, days int, now time.Time) listStats {
+	loc := models.LoadLocation(list.TimeZone)
+	stats := listStats{
+		ListID:      list.ID,
+		TimeZone:    loc.String(),
+		GeneratedAt: now.UTC(),
+		ByPriority:  map[string]statusCounts{},
+		Users:       []userStats{},
+		Daily:       []dailyCompletions{},
+	}
+	for _, priority := range []string{"high", "medium", "low"} {
+		stats.ByPriority[priority] = statusCounts{}
+	}
+
+	// 日ごとの完了数は今日までの days 日分
+	y, m, d := now.In(loc).Date()
+	first := time.Date(y, m, d-days+1, 0, 0, 0, 0, loc)
+	daily := make(map[string]int)
+	for i := 0; i < days; i++ {
+		daily[first.AddDate(0, 0, i).Format("2006-01-02")] = 0
+	}
+
+	checks := make(map[string][]time.Duration)
+	checked := make(map[string]int)
+	assigned := make(map[string]int)
+	var completionTotal time.Duration
+	completionCount := 0
+
+	for _, todo := range todos {
+		stats.add(todo)
+		counts := stats.ByPriority[todo.Priority]
+		counts.add(todo)
+		stats.ByPriority[todo.Priority] = counts
+
+		todo.UpdateDueState(now, loc)
+		if todo.IsOverdue {
+			stats.Overdue++
+		}
+
+		// 最後のチェックで完了するので、完了日時はチェック日時の最大値
+		var last *time.Time
+		for _, status := range todo.UserStatuses {
+			if status.IsAssigned {
+				assigned[status.UserID]++
+			}
+			if !status.IsChecked {
+				continue
+			}
+			checked[status.UserID]++
+			if status.CheckedAt != nil {
+				// 取り込んだToDoは作成より前にチェックされていることがあり、かかった時間は分からない
+				if !status.CheckedAt.Before(todo.CreatedAt) {
+					checks[status.UserID] = append(checks[status.UserID], status.CheckedAt.Sub(todo.CreatedAt))
+				}
+				if last == nil || status.CheckedAt.After(*last) {
+					last = status.CheckedAt
+				}
+			}
+		}
+		if !todo.IsCompleted {
+			continue
+		}
+		if last == nil {
+			last = todo.CompletedAt
+		}
+		if last == nil {
+			continue
+		}
+		if !last.Before(todo.CreatedAt) {
+			completionTotal += last.Sub(todo.CreatedAt)
+			completionCount++
+		}
+		day := last.In(loc).Format("2006-01-02")
+		if _, ok := daily[day]; ok {
+			daily[day]++
+		}
+	}
+
+	stats.CompletionRate = percentage(stats.Completed, stats.Total)
+	if completionCount > 0 {
+		stats.AverageCompletionMinutes = averageMinutes(completionTotal, completionCount)
+	}
+
+	for _, user := range users {
+		s := userStats{
+			UserID:      user.ID,
+			DisplayName: user.DisplayName,
+			Checked:     checked[user.ID],
+			Assigned:    assigned[user.ID],
+			CheckRate:   percentage(checked[user.ID], stats.Total),
+		}
+		if durations := checks[user.ID]; len(durations) > 0 {
+			var total time.Duration
+			for _, duration := range durations {
+				total += duration
+			}
+			s.AverageCheckMinutes = averageMinutes(total, len(durations))
+		}
+		stats.Users = append(stats.Users, s)
+	}
+
+	for i := 0; i < days; i++ {
+		day := first.AddDate(0, 0, i).Format("2006-01-02")
+		stats.Daily = append(stats.Daily, dailyCompletions{Date: day, Completed: daily[day]})
+	}
+	return stats
+}
+
+// percentage returns n of total as a percentage rounded to one decimal, or 0
+// when total is 0
+func percentage(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
+
+// averageMinutes returns the average of count durations totalling total, in
+// minutes rounded to one decimal
+func averageMinutes(total time.Duration, count int) *float64 {
+	minutes := math.Round(total.Minutes()*10/float64(count)) / 10
+	return &minutes
+}
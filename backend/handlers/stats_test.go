@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) TestGetStats() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id", DisplayName: "Alice"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	created := time.Now().Add(-time.Hour)
+	checkedAt := time.Now()
+	done := models.Todo{ListID: "test-list-id", Title: "Done", Priority: "high", IsCompleted: true, CompletedAt: &checkedAt, CreatedAt: created}
+	database.DB.Create(&done)
+	database.DB.Create(&models.TodoUserStatus{TodoID: done.ID, UserID: "test-user-id", IsChecked: true, CheckedAt: &checkedAt})
+	database.DB.Create(&models.TodoUserStatus{TodoID: done.ID, UserID: "other-user-id", IsChecked: true, CheckedAt: &checkedAt})
+	late := models.Todo{ListID: "test-list-id", Title: "Late", Priority: "low", DueDate: &yesterday, HasDueTime: true}
+	database.DB.Create(&late)
+	database.DB.Create(&models.TodoUserStatus{TodoID: late.ID, UserID: "other-user-id", IsAssigned: true})
+
+	w := suite.get("/api/lists/test-list-id/stats?days=7")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var stats listStats
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(suite.T(), 2, stats.Total)
+	assert.Equal(suite.T(), 1, stats.Completed)
+	assert.Equal(suite.T(), 1, stats.Overdue)
+	assert.Equal(suite.T(), 50.0, stats.CompletionRate)
+	assert.Equal(suite.T(), statusCounts{Total: 1, Completed: 1}, stats.ByPriority["high"])
+	assert.Equal(suite.T(), statusCounts{}, stats.ByPriority["medium"])
+	assert.Equal(suite.T(), 60.0, *stats.AverageCompletionMinutes)
+	suite.Require().Len(stats.Users, 2)
+	assert.Equal(suite.T(), "Alice", stats.Users[0].DisplayName)
+	assert.Equal(suite.T(), 1, stats.Users[1].Assigned)
+	suite.Require().Len(stats.Daily, 7)
+	assert.Equal(suite.T(), dailyCompletions{Date: time.Now().UTC().Format("2006-01-02"), Completed: 1}, stats.Daily[6])
+
+	// 平坦なJSONで返す
+	var raw map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &raw)
+	assert.Equal(suite.T(), 1.0, raw["open"])
+
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.get("/api/lists/test-list-id/stats?days=0").Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.get("/api/lists/test-list-id/stats?days=366").Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/api/lists/missing/stats").Code)
+}
+
+func (suite *HandlerTestSuite) TestBuildStats() {
+	now := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC) // 東京では5月10日9時
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	list := models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"}
+	users := []models.User{{ID: "user-a"}, {ID: "user-b"}}
+	todos := []models.Todo{
+		// 最後のチェックが完了日時になる
+		{Priority: "high", IsCompleted: true, CompletedAt: at(9, 23), CreatedAt: created, UserStatuses: []models.TodoUserStatus{
+			{UserID: "user-a", IsChecked: true, CheckedAt: at(2, 0)},
+			{UserID: "user-b", IsChecked: true, CheckedAt: at(3, 0)},
+		}},
+		// チェック日時がなければ CompletedAt を使う。東京では5月9日
+		{Priority: "medium", IsCompleted: true, CompletedAt: at(8, 20), CreatedAt: created},
+		// 期間より前の完了は日ごとの数に含めない
+		{Priority: "medium", IsCompleted: true, CompletedAt: at(1, 6), CreatedAt: created},
+		{Priority: "low", CreatedAt: created, UserStatuses: []models.TodoUserStatus{
+			{UserID: "user-a", IsChecked: true, CheckedAt: at(1, 12), IsAssigned: true},
+		}},
+	}
+
+	stats := buildStats(list, users, todos, 3, now)
+	assert.Equal(suite.T(), "Asia/Tokyo", stats.TimeZone)
+	assert.Equal(suite.T(), statusCounts{Total: 4, Open: 1, Completed: 3}, stats.statusCounts)
+	assert.Equal(suite.T(), 75.0, stats.CompletionRate)
+	assert.Equal(suite.T(), statusCounts{Total: 2, Completed: 2}, stats.ByPriority["medium"])
+	assert.Equal(suite.T(), 0, stats.Overdue)
+
+	// (2日 + 7日20時間 + 6時間) / 3
+	assert.Equal(suite.T(), float64(2*24*60+(7*24+20)*60+6*60)/3, *stats.AverageCompletionMinutes)
+
+	assert.Equal(suite.T(), []userStats{
+		{UserID: "user-a", Checked: 2, Assigned: 1, CheckRate: 50, AverageCheckMinutes: ptrFloat(float64(24*60+12*60) / 2)},
+		{UserID: "user-b", Checked: 1, CheckRate: 25, AverageCheckMinutes: ptrFloat(2 * 24 * 60)},
+	}, stats.Users)
+
+	assert.Equal(suite.T(), []dailyCompletions{
+		{Date: "2024-05-08", Completed: 0},
+		{Date: "2024-05-09", Completed: 1},
+		{Date: "2024-05-10", Completed: 0},
+	}, stats.Daily)
+
+	empty := buildStats(list, users, nil, 1, now)
+	assert.Equal(suite.T(), 0.0, empty.CompletionRate)
+	assert.Nil(suite.T(), empty.AverageCompletionMinutes)
+	assert.Nil(suite.T(), empty.Users[0].AverageCheckMinutes)
+}
+
+func (suite *HandlerTestSuite) TestBuildStatsImported() {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	checkedAt := time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC)
+	list := models.List{ID: "test-list-id"}
+	users := []models.User{{ID: "user-a"}}
+
+	// 取り込んだToDoは作成より前にチェックされていて、かかった時間は平均に含めない
+	todos := []models.Todo{{Priority: "medium", IsCompleted: true, CompletedAt: &checkedAt, CreatedAt: now, UserStatuses: []models.TodoUserStatus{
+		{UserID: "user-a", IsChecked: true, CheckedAt: &checkedAt},
+	}}}
+
+	stats := buildStats(list, users, todos, 2, now)
+	assert.Equal(suite.T(), 1, stats.Completed)
+	assert.Nil(suite.T(), stats.AverageCompletionMinutes)
+	assert.Equal(suite.T(), 1, stats.Users[0].Checked)
+	assert.Nil(suite.T(), stats.Users[0].AverageCheckMinutes)
+	assert.Equal(suite.T(), []dailyCompletions{
+		{Date: "2024-05-09", Completed: 1},
+		{Date: "2024-05-10", Completed: 0},
+	}, stats.Daily)
+}
+
+func ptrFloat(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/markdown"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// todoQueryParams are the parameters of GetListData that cannot be combined
+// with ?since=
+var todoQueryParams = []string{"status", "priority", "dueFrom", "dueTo", "sort", "limit", "cursor"}
+
+// latestChange returns the ID of the latest activity of a list, which is the
+// sync cursor of its current state, and the time of its latest change
+func latestChange(list models.List) (uint, time.Time) {
+	lastModified := list.UpdatedAt
+	// 履歴のないリストでログを出さないようFirstではなくFindを使う
+	var latest []models.Activity
+	database.DB.Where("list_id = ?", list.ID).Order("id DESC").Limit(1).Find(&latest)
+	if len(latest) == 0 {
+		return 0, lastModified
+	}
+	activity := latest[0]
+	if activity.CreatedAt.After(lastModified) {
+		lastModified = activity.CreatedAt
+	}
+	return activity.ID, lastModified
+}
+
+// getListChanges responds to GetListData with ?since=
+func getListChanges(c *gin.Context, list models.List, user models.User, cursor uint, lastModified time.Time) {
+	for _, param := range todoQueryParams {
+		if c.Query(param) != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Since cannot be combined with " + param})
+			return
+		}
+	}
+	since, ok := parseSyncCursor(c.Query("since"), cursor)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since cursor"})
+		return
+	}
+
+	changes, err := listChanges(list, user, since, cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get changes"})
+		return
+	}
+	respondCached(c, changes, lastModified)
+}
+
+// parseSyncCursor parses a sync cursor given by a client, which cannot be
+// after the current cursor
+func parseSyncCursor(s string, cursor uint) (uint, bool) {
+	since, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || since > uint64(cursor) {
+		return 0, false
+	}
+	return uint(since), true
+}
+
+// listChanges returns the users, todos and memo of a list changed after the
+// sync cursor since up to cursor, and the IDs of the todos deleted in between.
+// Changes are found in the activity log, so a cursor is the ID of the latest
+// activity a client has seen. A change is only made together with its entry,
+// so none is missed.
+func listChanges(list models.List, user models.User, since, cursor uint) (gin.H, error) {
+	var activities []models.Activity
+	if err := database.DB.Where("list_id = ? AND id > ? AND id <= ?", list.ID, since, cursor).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	todoIDs := []uint{}
+	userIDs := []string{}
+	memoChanged, allTodos := false, false
+	for _, activity := range activities {
+		switch activity.TargetType {
+		case models.TargetTodo:
+			if id, err := strconv.ParseUint(activity.TargetID, 10, 64); err == nil {
+				todoIDs = append(todoIDs, uint(id))
+			}
+		case models.TargetUser:
+			userIDs = append(userIDs, activity.TargetID)
+			// 参加したユーザーのチェック状態はすべてのToDoに追加される
+			if activity.Action == models.ActionUserJoined {
+				allTodos = true
+			}
+			// タイムゾーンが変わると期限の表示がすべて変わる
+			if activity.Action == models.ActionPreferencesUpdated && activity.TargetID == user.ID {
+				allTodos = true
+			}
+		case models.TargetList:
+			switch activity.Action {
+			case models.ActionMemoUpdated, models.ActionMemoEdited:
+				memoChanged = true
+			case models.ActionTimeZoneUpdated:
+				allTodos = true
+			}
+		}
+	}
+
+	users := []models.User{}
+	if len(userIDs) > 0 {
+		database.DB.Where("list_id = ? AND id IN ?", list.ID, userIDs).Find(&users)
+	}
+
+	// 削除済みのToDoも読み込み、削除として返す
+	var found []models.Todo
+	if allTodos || len(todoIDs) > 0 {
+		tx := database.DB.Unscoped().Where("list_id = ?", list.ID)
+		if allTodos {
+			tx = tx.Where("id IN ? OR deleted_at IS NULL", todoIDs)
+		} else {
+			tx = tx.Where("id IN ?", todoIDs)
+		}
+		tx.Preload("UserStatuses").Order("id").Find(&found)
+	}
+
+	todos := []models.Todo{}
+	deleted := []uint{}
+	present := make(map[uint]bool, len(found))
+	for _, todo := range found {
+		present[todo.ID] = true
+		if todo.DeletedAt.Valid {
+			deleted = append(deleted, todo.ID)
+		} else {
+			todos = append(todos, todo)
+		}
+	}
+	for _, id := range todoIDs {
+		if !present[id] {
+			present[id] = true
+			deleted = append(deleted, id)
+		}
+	}
+
+	timeZone := models.EffectiveTimeZone(list, user)
+	renderTodos(todos, models.LoadLocation(timeZone))
+
+	body := gin.H{
+		"users":          users,
+		"todos":          todos,
+		"deletedTodoIds": deleted,
+		"version":        list.Version,
+		"timeZone":       timeZone,
+		"syncCursor":     strconv.FormatUint(uint64(cursor), 10),
+	}
+	if memoChanged {
+		body["memo"] = list.Memo
+		body["memoHtml"] = markdown.Render(list.Memo)
+	}
+	return body, nil
+}
+
+// listState returns every user and todo and the memo of a list as of the sync
+// cursor, for a client without local data
+func listState(list models.List, user models.User, cursor uint) gin.H {
+	users := []models.User{}
+	database.DB.Where("list_id = ?", list.ID).Find(&users)
+
+	todos := []models.Todo{}
+	database.DB.Where("list_id = ?", list.ID).Preload("UserStatuses").Order("id").Find(&todos)
+
+	timeZone := models.EffectiveTimeZone(list, user)
+	renderTodos(todos, models.LoadLocation(timeZone))
+
+	return gin.H{
+		"users":      users,
+		"todos":      todos,
+		"memo":       list.Memo,
+		"memoHtml":   markdown.Render(list.Memo),
+		"version":    list.Version,
+		"timeZone":   timeZone,
+		"syncCursor": strconv.FormatUint(uint64(cursor), 10),
+	}
+}
+
+// respondCached responds with body as JSON, tagged with a hash of its content
+// and the time of the latest change. When the client already has the same
+// content, as told by If-None-Match or else If-Modified-Since, it responds 304
+// without a body.
+func respondCached(c *gin.Context, body interface{}, lastModified time.Time) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	sum := sha256.Sum256(data)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", tag)
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-cache")
+
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		if matchesETag(header, tag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil {
+		// Last-Modifiedは秒単位なので切り捨てて比較する
+		if !lastModified.Truncate(time.Second).After(since) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
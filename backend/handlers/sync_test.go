@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type changesResponse struct {
+	Users          []models.User `json:"users"`
+	Todos          []models.Todo `json:"todos"`
+	DeletedTodoIDs []uint        `json:"deletedTodoIds"`
+	Memo           *string       `json:"memo"`
+	SyncCursor     string        `json:"syncCursor"`
+}
+
+// getConditional gets the list data with a conditional request header
+func (suite *HandlerTestSuite) getConditional(query, header, value string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/users/test-user-id"+query, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) getChanges(since string) changesResponse {
+	w := suite.getConditional("?since="+since, "", "")
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	var response changesResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func (suite *HandlerTestSuite) TestListDataNotModified() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.getConditional("", "", "")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	tag := w.Header().Get("ETag")
+	assert.NotEmpty(suite.T(), tag)
+	lastModified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+	assert.NoError(suite.T(), err)
+
+	w = suite.getConditional("", "If-None-Match", tag)
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+	assert.Empty(suite.T(), w.Body.String())
+	w = suite.getConditional("", "If-Modified-Since", lastModified.Format(http.TimeFormat))
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+
+	// 変更後は新しい内容を返す
+	time.Sleep(time.Second)
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk tea"})
+	w = suite.getConditional("", "If-None-Match", tag)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.NotEqual(suite.T(), tag, w.Header().Get("ETag"))
+	w = suite.getConditional("", "If-Modified-Since", lastModified.Format(http.TimeFormat))
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestListDataChanges() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var report models.Todo
+	json.Unmarshal(w.Body.Bytes(), &report)
+	w = suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Slides"})
+	var slides models.Todo
+	json.Unmarshal(w.Body.Bytes(), &slides)
+
+	_, full := suite.getListData("")
+	cursor := suite.getChanges("0").SyncCursor
+	assert.NotEqual(suite.T(), "0", cursor)
+
+	// 変更がなければ空の差分を返す
+	changes := suite.getChanges(cursor)
+	assert.Empty(suite.T(), changes.Todos)
+	assert.Empty(suite.T(), changes.Users)
+	assert.Empty(suite.T(), changes.DeletedTodoIDs)
+	assert.Nil(suite.T(), changes.Memo)
+	assert.Equal(suite.T(), cursor, changes.SyncCursor)
+	assert.Len(suite.T(), full.Todos, 2)
+
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(report.ID))+"/status/test-user-id", map[string]bool{"checked": true})
+	suite.sendAs("DELETE", "/api/lists/test-list-id/users/test-user-id/todos/"+strconv.Itoa(int(slides.ID)), "test-user-id", nil)
+	suite.putJSON("/api/lists/test-list-id/users/test-user-id/name", map[string]string{"name": "Alice"})
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk tea"})
+
+	changes = suite.getChanges(cursor)
+	suite.Require().Len(changes.Todos, 1)
+	assert.Equal(suite.T(), report.ID, changes.Todos[0].ID)
+	assert.True(suite.T(), changes.Todos[0].IsCompleted)
+	suite.Require().Len(changes.Todos[0].UserStatuses, 1)
+	assert.True(suite.T(), changes.Todos[0].UserStatuses[0].IsChecked)
+	assert.Equal(suite.T(), []uint{slides.ID}, changes.DeletedTodoIDs)
+	suite.Require().Len(changes.Users, 1)
+	assert.Equal(suite.T(), "Alice", changes.Users[0].DisplayName)
+	suite.Require().NotNil(changes.Memo)
+	assert.Equal(suite.T(), "milk tea", *changes.Memo)
+	assert.NotEqual(suite.T(), cursor, changes.SyncCursor)
+
+	// 同じ差分は304で返せる
+	w = suite.getConditional("?since="+cursor, "", "")
+	w = suite.getConditional("?since="+cursor, "If-None-Match", w.Header().Get("ETag"))
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+
+	// 新しいユーザーのチェック状態はすべてのToDoに加わる
+	cursor = changes.SyncCursor
+	suite.postJSON("/api/lists/test-list-id/users", nil)
+	changes = suite.getChanges(cursor)
+	assert.Len(suite.T(), changes.Users, 1)
+	suite.Require().Len(changes.Todos, 1)
+	assert.Len(suite.T(), changes.Todos[0].UserStatuses, 2)
+	assert.Empty(suite.T(), changes.DeletedTodoIDs)
+}
+
+func (suite *HandlerTestSuite) TestListDataChangesActivityFailure() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "milk"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var report models.Todo
+	json.Unmarshal(w.Body.Bytes(), &report)
+	cursor := suite.getChanges("0").SyncCursor
+
+	// 差分は操作履歴から作るので、履歴を書き込めない変更は行わない
+	suite.Require().NoError(database.DB.Exec("CREATE TRIGGER fail_activities BEFORE INSERT ON activities BEGIN SELECT RAISE(ABORT, 'activity log unavailable'); END").Error)
+	w = suite.sendAs("DELETE", "/api/lists/test-list-id/users/test-user-id/todos/"+strconv.Itoa(int(report.ID)), "test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	w = suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "milk tea"})
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	suite.Require().NoError(database.DB.Exec("DROP TRIGGER fail_activities").Error)
+
+	changes := suite.getChanges(cursor)
+	assert.Empty(suite.T(), changes.Todos)
+	assert.Empty(suite.T(), changes.DeletedTodoIDs)
+	assert.Nil(suite.T(), changes.Memo)
+	assert.Equal(suite.T(), cursor, changes.SyncCursor)
+
+	_, full := suite.getListData("")
+	assert.Len(suite.T(), full.Todos, 1)
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "milk", list.Memo)
+}
+
+func (suite *HandlerTestSuite) TestListDataChangesInvalid() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	for _, query := range []string{"?since=abc", "?since=-1", "?since=100", "?since=0&status=open", "?since=0&limit=10"} {
+		w := suite.getConditional(query, "", "")
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, query)
+	}
+}
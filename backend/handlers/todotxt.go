@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// todoTxtPriorities maps the priorities of todos to the priorities of todo.txt
+var todoTxtPriorities = map[string]string{"high": "A", "medium": "B", "low": "C"}
+
+// ExportTodoTxt returns the todos of a list in the todo.txt format: completed
+// todos start with x and the completion date, and the others with their
+// priority. Each line has the creation date, the title, the labels as
+// +projects and the due date as due:. Descriptions are left out, as a task is
+// a single line.
+func ExportTodoTxt(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	_, todos := exportData(database.DB, list.ID)
+	loc := models.LoadLocation(list.TimeZone)
+
+	var b strings.Builder
+	for _, todo := range todos {
+		created := todo.CreatedAt.In(loc).Format("2006-01-02")
+		priority := todoTxtPriorities[todo.Priority]
+		if todo.IsCompleted {
+			// 完了したタスクの優先度は pri: で書くのが todo.txt の慣例
+			completed := completedAt(todo.CompletedAt, todo.UpdatedAt).In(loc).Format("2006-01-02")
+			b.WriteString("x " + completed + " " + created + " " + todoText(todo, loc) + " pri:" + priority + "\n")
+		} else {
+			b.WriteString("(" + priority + ") " + created + " " + todoText(todo, loc) + "\n")
+		}
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="todo.txt"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+}
+
+// ImportTodoTxt creates todos from a file in the todo.txt format, sent as the
+// body or as the file field of a form. A task marked x is imported as
+// completed by everyone. Priorities (A), (B) and (C) are high, medium and low,
+// and later letters are low. Both +projects and @contexts become labels, and
+// due: is the due date. Creation dates are not kept.
+func ImportTodoTxt(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	data, err := readImport(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var users []models.User
+	database.DB.Where("list_id = ?", listID).Order("created_at, id").Find(&users)
+
+	loc := models.LoadLocation(list.TimeZone)
+	var todos []importedTodo
+	for i, line := range importLines(data) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		todo := parseTodoTxt(line, loc)
+		todo.Row = i + 1
+		todos = append(todos, todo)
+	}
+
+	importList(c, database.DB, list, users, todos, nil)
+}
+
+// todoText returns the title of a todo followed by its labels as +projects and
+// its due date as due:, as written by todo.txt and checklist exports. Words of
+// the title that would be read as a +project, @context, due: or pri:, or that
+// start with a backslash, are escaped with a backslash. Whitespace within a
+// label is written as _, and runs of whitespace in the title as a space.
+func todoText(todo models.Todo, loc *time.Location) string {
+	var words []string
+	for _, word := range strings.Fields(todo.Title) {
+		if word[0] == '\\' || isTodoTxtTag(word) {
+			word = `\` + word
+		}
+		words = append(words, word)
+	}
+	for _, label := range todo.Labels {
+		words = append(words, "+"+strings.Join(strings.Fields(label), "_"))
+	}
+	if due := formatDue(todo, loc); due != "" {
+		words = append(words, "due:"+due)
+	}
+	return strings.Join(words, " ")
+}
+
+// parseTodoTxt reads a task of a todo.txt file
+func parseTodoTxt(line string, loc *time.Location) importedTodo {
+	todo := importedTodo{Checks: make(map[string]*time.Time)}
+	rest := strings.TrimSpace(line)
+
+	if strings.HasPrefix(rest, "x ") {
+		todo.Completed = true
+		rest = strings.TrimSpace(rest[2:])
+		if date, after, ok := cutDate(rest); ok {
+			todo.CompletedAt, _ = parseCheckedAt(date, loc)
+			rest = after
+		}
+	} else {
+		todo.Priority, rest = cutPriority(rest)
+	}
+	// 作成日は取り込まない
+	if _, after, ok := cutDate(rest); ok {
+		rest = after
+	}
+
+	parseTodoText(rest, &todo)
+	return todo
+}
+
+// parseTodoText reads the text of a task, as written by todoText: the words
+// that are not +projects, @contexts, due: or pri: make up the title
+func parseTodoText(text string, todo *importedTodo) {
+	var title []string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(word) > 1 && word[0] == '\\' && (word[1] == '\\' || isTodoTxtTag(word[1:])):
+			title = append(title, word[1:])
+		case len(word) > 1 && (word[0] == '+' || word[0] == '@'):
+			todo.Labels = append(todo.Labels, word[1:])
+		case strings.HasPrefix(word, "due:") && len(word) > len("due:"):
+			todo.DueDate = word[len("due:"):]
+		case isTodoTxtTag(word):
+			todo.Priority = todoTxtPriority(word[len("pri:"):])
+		default:
+			title = append(title, word)
+		}
+	}
+	todo.Title = strings.Join(title, " ")
+}
+
+// isTodoTxtTag reports whether a word of a task is a +project, an @context, a
+// due: date or a pri: priority rather than part of the title
+func isTodoTxtTag(word string) bool {
+	switch {
+	case len(word) > 1 && (word[0] == '+' || word[0] == '@'):
+		return true
+	case strings.HasPrefix(word, "due:") && len(word) > len("due:"):
+		return true
+	case strings.HasPrefix(word, "pri:") && len(word) == len("pri:A"):
+		return todoTxtPriority(word[len("pri:"):]) != ""
+	}
+	return false
+}
+
+// cutPriority cuts the priority, such as "(A)", off the start of a task
+func cutPriority(s string) (string, string) {
+	if len(s) >= 4 && s[0] == '(' && s[2] == ')' && s[3] == ' ' {
+		if priority := todoTxtPriority(s[1:2]); priority != "" {
+			return priority, strings.TrimSpace(s[4:])
+		}
+	}
+	return "", s
+}
+
+// todoTxtPriority returns the priority of a todo.txt priority letter, or "" when
+// it is not one
+func todoTxtPriority(letter string) string {
+	switch {
+	case letter == "A":
+		return "high"
+	case letter == "B":
+		return "medium"
+	case len(letter) == 1 && letter[0] >= 'C' && letter[0] <= 'Z':
+		return "low"
+	}
+	return ""
+}
+
+// cutDate cuts a date in the format YYYY-MM-DD off the start of a task
+func cutDate(s string) (string, string, bool) {
+	date, rest, _ := strings.Cut(s, " ")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return "", s, false
+	}
+	return date, strings.TrimSpace(rest), true
+}
+
+// importLines splits an imported text file into its lines
+func importLines(data []byte) []string {
+	text := strings.TrimPrefix(string(data), utf8BOM)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
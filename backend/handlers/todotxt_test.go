@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) postText(path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) textTodos() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	due := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dueTime := time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)
+	completed := time.Date(2024, 4, 30, 3, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 4, 1, 3, 0, 0, 0, time.UTC)
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Buy milk", Priority: "high", Labels: models.Labels{"groceries", "after work"}, DueDate: &due, CreatedAt: created})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Call", Description: "About the\n\nreport", Priority: "low", DueDate: &dueTime, HasDueTime: true, IsCompleted: true, CompletedAt: &completed, CreatedAt: created.Add(time.Minute)})
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Someday", Priority: "medium", CreatedAt: created.Add(2 * time.Minute)})
+}
+
+func (suite *HandlerTestSuite) TestExportTodoTxt() {
+	suite.textTodos()
+
+	w := suite.get("/api/lists/test-list-id/export.txt")
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), "(A) 2024-04-01 Buy milk +groceries +after_work due:2024-05-01\n"+
+		"x 2024-04-30 2024-04-01 Call due:2024-05-02T18:30 pri:C\n"+
+		"(B) 2024-04-01 Someday\n", w.Body.String())
+
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/api/lists/missing/export.txt").Code)
+}
+
+func (suite *HandlerTestSuite) TestImportTodoTxt() {
+	database.DB.Create(&models.List{ID: "test-list-id", TimeZone: "Asia/Tokyo"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	w := suite.postText("/api/lists/test-list-id/import.txt", "\ufeff(A) 2024-04-01 Buy milk +groceries @store due:2024-05-01\r\n"+
+		"\n"+
+		"x 2024-04-30 2024-04-01 Call due:2024-05-02T18:30 pri:C\n"+
+		"(D) Read pri:Z1 at 10:30\n"+
+		"Plain task\n")
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var response struct {
+		TodoIDs []uint `json:"todoIds"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	suite.Require().Len(response.TodoIDs, 4)
+
+	var todos []models.Todo
+	database.DB.Preload("UserStatuses").Order("id").Find(&todos, response.TodoIDs)
+	suite.Require().Len(todos, 4)
+
+	assert.Equal(suite.T(), "Buy milk", todos[0].Title)
+	assert.Equal(suite.T(), "high", todos[0].Priority)
+	assert.Equal(suite.T(), models.Labels{"groceries", "store"}, todos[0].Labels)
+	assert.Equal(suite.T(), "2024-05-01", todos[0].DueDate.UTC().Format("2006-01-02"))
+	assert.False(suite.T(), todos[0].IsCompleted)
+
+	// 完了したタスクは全員のチェック済みとして取り込む
+	assert.Equal(suite.T(), "Call", todos[1].Title)
+	assert.Equal(suite.T(), "low", todos[1].Priority)
+	assert.Equal(suite.T(), time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC), todos[1].DueDate.UTC())
+	assert.True(suite.T(), todos[1].IsCompleted)
+	assert.Equal(suite.T(), "2024-04-30", todos[1].CompletedAt.UTC().Format("2006-01-02"))
+	for _, status := range todos[1].UserStatuses {
+		assert.True(suite.T(), status.IsChecked)
+	}
+
+	// D 以降の優先度は低、pri: でない単語は件名に残る
+	assert.Equal(suite.T(), "Read pri:Z1 at 10:30", todos[2].Title)
+	assert.Equal(suite.T(), "low", todos[2].Priority)
+	assert.Equal(suite.T(), "Plain task", todos[3].Title)
+	assert.Equal(suite.T(), "medium", todos[3].Priority)
+
+	w = suite.postText("/api/lists/test-list-id/import.txt", "Fine\n(A) +label-only\nBad due:tomorrow\n")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	var errorResponse struct {
+		Rows []rowError `json:"rows"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Equal(suite.T(), []rowError{{2, "Title is required"}, {3, errInvalidDueDate.Error()}}, errorResponse.Rows)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.postText("/api/lists/test-list-id/import.txt", "\n\n").Code)
+}
+
+func (suite *HandlerTestSuite) TestTodoTxtRoundTrip() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.List{ID: "other-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "other-list-id"})
+
+	titles := []string{
+		"Email +team about due:friday",
+		"Review pri:A items @home",
+		`Copy C:\temp \+x \\ \`,
+		"(A) Start with a priority",
+		"x marks the spot",
+		"2024-01-01 retro",
+	}
+	created := time.Date(2024, 4, 1, 3, 0, 0, 0, time.UTC)
+	for i, title := range titles {
+		database.DB.Create(&models.Todo{ListID: "test-list-id", Title: title, Priority: "medium", CreatedAt: created.Add(time.Duration(i) * time.Minute)})
+	}
+	completed := time.Date(2024, 4, 30, 3, 0, 0, 0, time.UTC)
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Rate pri:B", Priority: "low", IsCompleted: true, CompletedAt: &completed, CreatedAt: created.Add(time.Hour)})
+	titles = append(titles, "Rate pri:B")
+
+	// 説明は書き出さず、件名の連続した空白は1つになる
+	database.DB.Create(&models.Todo{ListID: "test-list-id", Title: "Two  spaces", Description: "first\nsecond", Priority: "medium", CreatedAt: created.Add(2 * time.Hour)})
+
+	exported := suite.get("/api/lists/test-list-id/export.txt").Body.String()
+	assert.Contains(suite.T(), exported, `Email \+team about \due:friday`)
+	w := suite.postText("/api/lists/other-list-id/import.txt", exported)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var todos []models.Todo
+	database.DB.Where("list_id = ?", "other-list-id").Order("id").Find(&todos)
+	suite.Require().Len(todos, len(titles)+1)
+	for i, title := range titles {
+		assert.Equal(suite.T(), title, todos[i].Title)
+		assert.Empty(suite.T(), todos[i].Labels, title)
+		assert.Nil(suite.T(), todos[i].DueDate, title)
+	}
+	assert.Equal(suite.T(), "low", todos[len(titles)-1].Priority)
+	assert.True(suite.T(), todos[len(titles)-1].IsCompleted)
+	assert.Equal(suite.T(), "Two spaces", todos[len(titles)].Title)
+	assert.Empty(suite.T(), todos[len(titles)].Description)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/ot"
+	"shared-todo-backend/webhook"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultUndoWindowMinutes = 10
+	maxUndoWindowMinutes     = 60
+	maxUndoCount             = 20
+)
+
+// undoableActions are the actions UndoActions can revert
+var undoableActions = []string{
+	models.ActionTodoChecked,
+	models.ActionTodoUnchecked,
+	models.ActionMemoUpdated,
+	models.ActionTodoDeleted,
+}
+
+// undoConflict is returned when an action cannot be undone because the target
+// was changed after it
+type undoConflict struct {
+	activity models.Activity
+	reason   string
+}
+
+func (e *undoConflict) Error() string {
+	return e.reason
+}
+
+// UndoActions reverts the last actions of a user within a time window, newest
+// first: checks and unchecks, memo edits and todo deletions. Nothing is reverted
+// when one of them conflicts with a later change.
+func UndoActions(c *gin.Context) {
+	listID := c.Param("listId")
+	userID := c.Param("userId")
+
+	// Check if user exists in the list
+	var user models.User
+	if err := database.DB.Where("id = ? AND list_id = ?", userID, listID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this list"})
+		return
+	}
+
+	var req struct {
+		Count         int `json:"count"`
+		WindowMinutes int `json:"windowMinutes"`
+	}
+
+	// ボディは省略可能
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 1 || req.Count > maxUndoCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Count must be between 1 and " + strconv.Itoa(maxUndoCount)})
+		return
+	}
+	if req.WindowMinutes == 0 {
+		req.WindowMinutes = defaultUndoWindowMinutes
+	}
+	if req.WindowMinutes < 1 || req.WindowMinutes > maxUndoWindowMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Window must be between 1 and " + strconv.Itoa(maxUndoWindowMinutes) + " minutes"})
+		return
+	}
+
+	// 取り消し済みのエントリと取り消しの記録自体は対象外
+	since := time.Now().Add(-time.Duration(req.WindowMinutes) * time.Minute)
+	undone := database.DB.Model(&models.Activity{}).Select("undo_of").Where("undo_of IS NOT NULL")
+
+	var activities []models.Activity
+	err := database.DB.
+		Where("list_id = ? AND actor_id = ? AND action IN ? AND undo_of IS NULL AND created_at >= ?", listID, userID, undoableActions, since).
+		Where("id NOT IN (?)", undone).
+		Order("id DESC").
+		Limit(req.Count).
+		Find(&activities).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo"})
+		return
+	}
+
+	reverted := []models.Activity{}
+	var operations []models.MemoOperation
+	// 取り消される可能性があるため通知はコミット後に送る
+	db, sendNotifications := deferNotifications(database.DB)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, activity := range activities {
+			inverse, err := undoActivity(tx, activity, &operations)
+			if err != nil {
+				return err
+			}
+			reverted = append(reverted, inverse)
+		}
+		return nil
+	})
+
+	var conflict *undoConflict
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Cannot undo: " + conflict.reason,
+			"activity": conflict.activity,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo"})
+		return
+	}
+
+	sendNotifications()
+	for _, operation := range operations {
+		publishOperation(operation)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"undone": reverted})
+}
+
+// undoActivity reverts a single activity and records the revert. Operations on
+// the memo are appended to operations, to be published once committed.
+func undoActivity(tx *gorm.DB, activity models.Activity, operations *[]models.MemoOperation) (models.Activity, error) {
+	inverse := models.Activity{
+		ListID:     activity.ListID,
+		ActorID:    activity.ActorID,
+		TargetType: activity.TargetType,
+		TargetID:   activity.TargetID,
+		UndoOf:     &activity.ID,
+	}
+
+	var err error
+	switch activity.Action {
+	case models.ActionTodoChecked, models.ActionTodoUnchecked:
+		err = undoCheck(tx, activity, &inverse)
+	case models.ActionMemoUpdated:
+		err = undoMemo(tx, activity, &inverse, operations)
+	case models.ActionTodoDeleted:
+		err = undoDelete(tx, activity, &inverse)
+	default:
+		err = &undoConflict{activity, "action cannot be undone"}
+	}
+	if errors.Is(err, errVersionConflict) {
+		err = &undoConflict{activity, "the target was changed during the undo"}
+	}
+	return inverse, err
+}
+
+// undoCheck restores the check state of the user before the activity,
+// including the original check time
+func undoCheck(tx *gorm.DB, activity models.Activity, inverse *models.Activity) error {
+	var before, after struct {
+		IsChecked bool       `json:"isChecked"`
+		CheckedAt *time.Time `json:"checkedAt"`
+	}
+	if err := json.Unmarshal(activity.Before, &before); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(activity.After, &after); err != nil {
+		return err
+	}
+
+	var todo models.Todo
+	if err := tx.First(&todo, "id = ?", activity.TargetID).Error; err != nil {
+		return &undoConflict{activity, "the todo was deleted"}
+	}
+
+	var status models.TodoUserStatus
+	if err := tx.Where("todo_id = ? AND user_id = ?", todo.ID, activity.ActorID).First(&status).Error; err != nil {
+		return &undoConflict{activity, "the check state no longer exists"}
+	}
+	if status.IsChecked != after.IsChecked {
+		return &undoConflict{activity, "the check state was changed later"}
+	}
+
+	current := statusSnapshot(status)
+	now := time.Now()
+	status.IsChecked = before.IsChecked
+	status.CheckedAt = before.CheckedAt
+	status.ChangedAt = &now
+	if err := tx.Save(&status).Error; err != nil {
+		return err
+	}
+	if err := updateVersioned(tx, &models.Todo{}, todo.ID, 0, nil); err != nil {
+		return err
+	}
+
+	inverse.Action = checkAction(before.IsChecked)
+	if err := createActivity(tx, inverse, current, statusSnapshot(status)); err != nil {
+		return err
+	}
+	emitEvent(tx, todo.ListID, checkEvent(before.IsChecked), gin.H{"todoId": todo.ID, "userId": status.UserID, "checked": before.IsChecked})
+
+	return updateCompletion(tx, todo.ID, activity.ActorID)
+}
+
+// undoMemo restores the memo before the activity unless it was edited again
+func undoMemo(tx *gorm.DB, activity models.Activity, inverse *models.Activity, operations *[]models.MemoOperation) error {
+	var before, after struct {
+		Memo string `json:"memo"`
+	}
+	if err := json.Unmarshal(activity.Before, &before); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(activity.After, &after); err != nil {
+		return err
+	}
+
+	var list models.List
+	if err := tx.First(&list, "id = ?", activity.ListID).Error; err != nil {
+		return err
+	}
+	if list.Memo != after.Memo {
+		return &undoConflict{activity, "the memo was edited later"}
+	}
+
+	if err := updateVersioned(tx, &models.List{}, list.ID, list.Version, map[string]interface{}{"memo": before.Memo}); err != nil {
+		return err
+	}
+	operation, err := createOperation(tx, list, activity.ActorID, "", ot.Diff(list.Memo, before.Memo))
+	if err != nil {
+		return err
+	}
+	*operations = append(*operations, operation)
+	if err := createRevision(tx, list, &models.MemoRevision{ListID: list.ID, AuthorID: activity.ActorID, Memo: before.Memo}); err != nil {
+		return err
+	}
+
+	inverse.Action = models.ActionMemoUpdated
+	if err := createActivity(tx, inverse, gin.H{"memo": after.Memo}, gin.H{"memo": before.Memo}); err != nil {
+		return err
+	}
+	emitEvent(tx, list.ID, webhook.EventMemoUpdated, gin.H{"memo": before.Memo})
+	return nil
+}
+
+// undoDelete restores a deleted todo. Users who joined while it was deleted get
+// a check state for it.
+func undoDelete(tx *gorm.DB, activity models.Activity, inverse *models.Activity) error {
+	var todo models.Todo
+	if err := tx.Unscoped().First(&todo, "id = ?", activity.TargetID).Error; err != nil {
+		return &undoConflict{activity, "the todo no longer exists"}
+	}
+	if !todo.DeletedAt.Valid {
+		return &undoConflict{activity, "the todo was already restored"}
+	}
+
+	if err := updateVersioned(tx.Unscoped(), &models.Todo{}, todo.ID, 0, map[string]interface{}{"deleted_at": nil}); err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := tx.Where("list_id = ?", todo.ListID).Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		status := models.TodoUserStatus{TodoID: todo.ID, UserID: user.ID}
+		if err := tx.Where(status).FirstOrCreate(&status).Error; err != nil {
+			return err
+		}
+	}
+
+	inverse.Action = models.ActionTodoRestored
+	if err := createActivity(tx, inverse, nil, todoSnapshot(todo)); err != nil {
+		return err
+	}
+	emitEvent(tx, todo.ListID, webhook.EventTodoRestored, todo)
+
+	return updateCompletion(tx, todo.ID, activity.ActorID)
+}
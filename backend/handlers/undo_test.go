@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sendAs sends a JSON request on behalf of a user of the list
+func (suite *HandlerTestSuite) sendAs(method, path, userID string, payload interface{}) *httptest.ResponseRecorder {
+	jsonPayload, _ := json.Marshal(payload)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ActorHeader, userID)
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) undo(userID string, payload interface{}) (int, []models.Activity) {
+	w := suite.postJSON("/api/lists/test-list-id/users/"+userID+"/undo", payload)
+
+	var response struct {
+		Undone []models.Activity `json:"undone"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Undone
+}
+
+func (suite *HandlerTestSuite) TestUndoCheck() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	statusPath := "/api/todos/" + strconv.Itoa(int(todo.ID)) + "/status/test-user-id"
+
+	suite.putJSON(statusPath, map[string]bool{"checked": true})
+	var checked models.TodoUserStatus
+	database.DB.Where("todo_id = ?", todo.ID).First(&checked)
+	suite.putJSON(statusPath, map[string]bool{"checked": false})
+
+	// 外したチェックを元に戻すとチェック日時も元に戻る
+	code, undone := suite.undo("test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	suite.Require().Len(undone, 1)
+	assert.Equal(suite.T(), models.ActionTodoChecked, undone[0].Action)
+	suite.Require().NotNil(undone[0].UndoOf)
+
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ?", todo.ID).First(&status)
+	assert.True(suite.T(), status.IsChecked)
+	suite.Require().NotNil(status.CheckedAt)
+	assert.True(suite.T(), checked.CheckedAt.Equal(*status.CheckedAt))
+
+	database.DB.First(&todo, todo.ID)
+	assert.True(suite.T(), todo.IsCompleted)
+
+	// 取り消した操作と取り消しの記録は再び取り消されない
+	code, undone = suite.undo("test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	suite.Require().Len(undone, 1)
+	assert.Equal(suite.T(), models.ActionTodoUnchecked, undone[0].Action)
+
+	var reopened models.Todo
+	database.DB.First(&reopened, todo.ID)
+	assert.False(suite.T(), reopened.IsCompleted)
+	assert.Nil(suite.T(), reopened.CompletedAt)
+
+	code, undone = suite.undo("test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Empty(suite.T(), undone)
+}
+
+func (suite *HandlerTestSuite) TestUndoConflictSendsNoNotifications() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "first"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/other-user-id", map[string]bool{"checked": true})
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/test-user-id", map[string]bool{"checked": true})
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "test-user-id", map[string]string{"memo": "mine"})
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "other-user-id", map[string]string{"memo": "theirs"})
+	suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/test-user-id", map[string]bool{"checked": false})
+
+	// チェックを戻して完了しても、メモの取り消しで巻き戻れば通知しない
+	sent := suite.captureNotifications()
+	code, _ := suite.undo("test-user-id", map[string]int{"count": 2})
+	assert.Equal(suite.T(), http.StatusConflict, code)
+	assert.Empty(suite.T(), *sent)
+
+	database.DB.First(&todo, todo.ID)
+	assert.False(suite.T(), todo.IsCompleted)
+}
+
+func (suite *HandlerTestSuite) TestUndoMemo() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "first"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "test-user-id", map[string]string{"memo": "second"})
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "test-user-id", map[string]string{"memo": "third"})
+
+	code, undone := suite.undo("test-user-id", map[string]int{"count": 2})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Len(suite.T(), undone, 2)
+
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "first", list.Memo)
+
+	// 他のユーザーが後から編集したメモは上書きしない
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "test-user-id", map[string]string{"memo": "mine"})
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "other-user-id", map[string]string{"memo": "theirs"})
+
+	w := suite.postJSON("/api/lists/test-list-id/users/test-user-id/undo", nil)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "the memo was edited later")
+
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "theirs", list.Memo)
+}
+
+func (suite *HandlerTestSuite) TestUndoDelete() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+
+	w = suite.sendAs("DELETE", "/api/lists/test-list-id/users/test-user-id/todos/"+strconv.Itoa(int(todo.ID)), "test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+
+	_, response := suite.getListData("")
+	assert.Empty(suite.T(), response.Todos)
+
+	// 削除中に参加したユーザーにもチェック状態が作られる
+	database.DB.Create(&models.User{ID: "new-user-id", ListID: "test-list-id"})
+
+	code, undone := suite.undo("test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	suite.Require().Len(undone, 1)
+	assert.Equal(suite.T(), models.ActionTodoRestored, undone[0].Action)
+
+	_, response = suite.getListData("")
+	suite.Require().Len(response.Todos, 1)
+
+	var count int64
+	database.DB.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND user_id = ?", todo.ID, "new-user-id").Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *HandlerTestSuite) TestDeleteTodoUnauthorized() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.List{ID: "other-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "other-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	todoID := strconv.Itoa(int(todo.ID))
+
+	// 他のリストのToDoと、リストに属さないユーザーによる削除は拒否する
+	w = suite.sendAs("DELETE", "/api/lists/other-list-id/users/other-user-id/todos/"+todoID, "other-user-id", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+	w = suite.sendAs("DELETE", "/api/lists/test-list-id/users/other-user-id/todos/"+todoID, "other-user-id", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	_, response := suite.getListData("")
+	assert.Len(suite.T(), response.Todos, 1)
+}
+
+func (suite *HandlerTestSuite) TestUndoWindow() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "first"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+
+	suite.sendAs("PUT", "/api/lists/test-list-id/memo", "test-user-id", map[string]string{"memo": "second"})
+	database.DB.Model(&models.Activity{}).Where("1 = 1").Update("created_at", time.Now().Add(-30*time.Minute))
+
+	code, undone := suite.undo("test-user-id", nil)
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Empty(suite.T(), undone)
+
+	code, undone = suite.undo("test-user-id", map[string]int{"windowMinutes": 60})
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Len(suite.T(), undone, 1)
+
+	code, _ = suite.undo("test-user-id", map[string]int{"count": 21})
+	assert.Equal(suite.T(), http.StatusBadRequest, code)
+	code, _ = suite.undo("missing-user-id", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, code)
+}
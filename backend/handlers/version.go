@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errVersionConflict is returned by updateVersioned when the row was changed
+// since it was read
+var errVersionConflict = errors.New("version conflict")
+
+// etag returns the entity tag of a version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header value
+// matches the entity tag current: "*" or a list of entity tags, one of which is
+// current
+func matchesETag(header string, current string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVersion checks the version a client based its change on, given by the
+// If-Match header or by the version field of the request. A stale If-Match
+// fails with 412 and a stale version with 409; both responses carry the
+// current server value returned by current. It returns the version the change
+// must apply to, or 0 when the client gave none and the last write wins, and
+// reports whether the change may proceed.
+func checkVersion(c *gin.Context, version int, requested *int, current func() interface{}) (int, bool) {
+	expected := 0
+	if header := c.GetHeader("If-Match"); header != "" {
+		if !matchesETag(header, etag(version)) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Precondition failed: the resource was changed", "current": current()})
+			return 0, false
+		}
+		if strings.TrimSpace(header) != "*" {
+			expected = version
+		}
+	}
+	if requested != nil {
+		if *requested != version {
+			c.JSON(http.StatusConflict, gin.H{"error": "Version conflict: the resource was changed", "current": current()})
+			return 0, false
+		}
+		expected = version
+	}
+	return expected, true
+}
+
+// respondVersionConflict writes the 409 response for a change that lost the
+// race against another change made after checkVersion
+func respondVersionConflict(c *gin.Context, current func() interface{}) {
+	c.JSON(http.StatusConflict, gin.H{"error": "Version conflict: the resource was changed", "current": current()})
+}
+
+// updateVersioned applies updates to the row of model with the given ID and
+// increments its version. With a version other than 0 the row is only updated
+// while it still has that version, so changes made after the client read it
+// are never overwritten; errVersionConflict is returned otherwise.
+func updateVersioned(db *gorm.DB, model interface{}, id interface{}, version int, updates map[string]interface{}) error {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["version"] = gorm.Expr("version + 1")
+
+	tx := db.Model(model).Where("id = ?", id)
+	if version != 0 {
+		tx = tx.Where("version = ?", version)
+	}
+	result := tx.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strconv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sendIfMatch sends a JSON request with an If-Match header
+func (suite *HandlerTestSuite) sendIfMatch(method, path, ifMatch string, payload interface{}) *httptest.ResponseRecorder {
+	jsonPayload, _ := json.Marshal(payload)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", ifMatch)
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+type conflictResponse struct {
+	Error   string                 `json:"error"`
+	Current map[string]interface{} `json:"current"`
+}
+
+func (suite *HandlerTestSuite) TestMemoIfMatch() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "first"})
+
+	suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "theirs"})
+
+	// 古いバージョンを前提にした更新は拒否され、最新の値が返る
+	w := suite.sendIfMatch("PUT", "/api/lists/test-list-id/memo", `"1"`, map[string]string{"memo": "mine"})
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, w.Code)
+	var conflict conflictResponse
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	assert.Equal(suite.T(), "theirs", conflict.Current["memo"])
+	assert.Equal(suite.T(), float64(2), conflict.Current["version"])
+
+	w = suite.sendIfMatch("PUT", "/api/lists/test-list-id/memo", `W/"0", "2"`, map[string]string{"memo": "merged"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `"3"`, w.Header().Get("ETag"))
+
+	w = suite.sendIfMatch("PUT", "/api/lists/test-list-id/memo", "*", map[string]string{"memo": "any"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var list models.List
+	database.DB.First(&list, "id = ?", "test-list-id")
+	assert.Equal(suite.T(), "any", list.Memo)
+	assert.Equal(suite.T(), 4, list.Version)
+}
+
+func (suite *HandlerTestSuite) TestMemoVersionConflict() {
+	database.DB.Create(&models.List{ID: "test-list-id", Memo: "first"})
+
+	w := suite.putJSON("/api/lists/test-list-id/memo", map[string]interface{}{"memo": "second", "version": 1})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.putJSON("/api/lists/test-list-id/memo", map[string]interface{}{"memo": "stale", "version": 1})
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	var conflict conflictResponse
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	assert.Equal(suite.T(), "second", conflict.Current["memo"])
+	assert.Equal(suite.T(), "<p>second</p>", conflict.Current["memoHtml"])
+
+	// 版の記録にも保存後のバージョンが残る
+	revisions := suite.getRevisions("")
+	suite.Require().Len(revisions, 2)
+	assert.Equal(suite.T(), 2, revisions[0].Version)
+	assert.Equal(suite.T(), 1, revisions[1].Version)
+
+	w = suite.sendIfMatch("POST", "/api/lists/test-list-id/memo/revisions/"+strconv.Itoa(int(revisions[1].ID))+"/restore", `"1"`, nil)
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestTodoVersion() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	assert.Equal(suite.T(), 1, todo.Version)
+	todoPath := "/api/todos/" + strconv.Itoa(int(todo.ID))
+
+	// バージョンを指定しない更新は従来どおり常に成功する
+	w = suite.putJSON(todoPath+"/status/other-user-id", map[string]bool{"checked": true})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), `"2"`, w.Header().Get("ETag"))
+
+	_, data := suite.getListData("")
+	suite.Require().Len(data.Todos, 1)
+	assert.Equal(suite.T(), 2, data.Todos[0].Version)
+
+	w = suite.putJSON(todoPath+"/status/test-user-id", map[string]interface{}{"checked": true, "version": 1})
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	var conflict conflictResponse
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	assert.Equal(suite.T(), "Report", conflict.Current["title"])
+	assert.Len(suite.T(), conflict.Current["userStatuses"], 2)
+
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ? AND user_id = ?", todo.ID, "test-user-id").First(&status)
+	assert.False(suite.T(), status.IsChecked)
+
+	w = suite.sendIfMatch("PUT", "/api/lists/test-list-id/users/test-user-id/todos/"+strconv.Itoa(int(todo.ID))+"/assignees", `"1"`, map[string][]string{"userIds": {"test-user-id"}})
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, w.Code)
+	deletePath := "/api/lists/test-list-id/users/test-user-id/todos/" + strconv.Itoa(int(todo.ID))
+	w = suite.sendIfMatch("DELETE", deletePath, `"1"`, nil)
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, w.Code)
+
+	w = suite.sendIfMatch("DELETE", deletePath, `"2"`, nil)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	_, data = suite.getListData("")
+	assert.Empty(suite.T(), data.Todos)
+}
+
+func (suite *HandlerTestSuite) TestTodoStatusFailureKeepsVersion() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "other-user-id", ListID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Report"})
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+	todoPath := "/api/todos/" + strconv.Itoa(int(todo.ID))
+	suite.putJSON(todoPath+"/status/other-user-id", map[string]bool{"checked": true})
+
+	// 完了を記録できなければチェックもバージョンも変えない
+	suite.Require().NoError(database.DB.Exec("CREATE TRIGGER fail_completion BEFORE INSERT ON activities WHEN NEW.action = '" + models.ActionTodoCompleted + "' BEGIN SELECT RAISE(ABORT, 'unavailable'); END").Error)
+	w = suite.putJSON(todoPath+"/status/test-user-id", map[string]interface{}{"checked": true, "version": 2})
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+
+	database.DB.First(&todo, todo.ID)
+	assert.Equal(suite.T(), 2, todo.Version)
+	assert.False(suite.T(), todo.IsCompleted)
+	var status models.TodoUserStatus
+	database.DB.Where("todo_id = ? AND user_id = ?", todo.ID, "test-user-id").First(&status)
+	assert.False(suite.T(), status.IsChecked)
+}
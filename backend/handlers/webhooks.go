@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/webhook"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// CreateWebhook registers a webhook for the events of a list. The signing
+// secret is only returned in this response.
+func CreateWebhook(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	// Validate URL
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(req.URL) > 2000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "URL must be an absolute http or https URL"})
+		return
+	}
+	if err := webhook.CheckURL(c.Request.Context(), req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate events
+	for _, event := range req.Events {
+		if !webhook.ValidEvent(event) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event: " + event, "events": webhook.Events})
+			return
+		}
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	hook := models.Webhook{
+		ListID: listID,
+		URL:    req.URL,
+		Secret: secret,
+		Events: req.Events,
+	}
+	actorID := requestActor(c, listID)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&hook).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, models.Activity{
+			ListID:     listID,
+			ActorID:    actorID,
+			Action:     models.ActionWebhookCreated,
+			TargetType: models.TargetWebhook,
+			TargetID:   strconv.FormatUint(uint64(hook.ID), 10),
+		}, nil, gin.H{"url": hook.URL, "events": hook.Events})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": hook,
+		"secret":  secret,
+	})
+}
+
+// GetWebhooks lists the webhooks of a list
+func GetWebhooks(c *gin.Context) {
+	listID := c.Param("listId")
+
+	// Check if list exists
+	var list models.List
+	if err := database.DB.First(&list, "id = ?", listID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	hooks := []models.Webhook{}
+	database.DB.Where("list_id = ?", listID).Order("id").Find(&hooks)
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	actorID := requestActor(c, hook.ListID)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&hook).Error; err != nil {
+			return err
+		}
+		return recordActivity(tx, models.Activity{
+			ListID:     hook.ListID,
+			ActorID:    actorID,
+			Action:     models.ActionWebhookDeleted,
+			TargetType: models.TargetWebhook,
+			TargetID:   strconv.FormatUint(uint64(hook.ID), 10),
+		}, gin.H{"url": hook.URL, "events": hook.Events}, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists the deliveries of a webhook, newest first
+func GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxDeliveryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be between 1 and " + strconv.Itoa(maxDeliveryLimit)})
+			return
+		}
+		limit = n
+	}
+
+	tx := database.DB.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := tx.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// findWebhook loads the webhook of the request and writes an error response when
+// it does not exist in the list
+func findWebhook(c *gin.Context) (models.Webhook, bool) {
+	var hook models.Webhook
+
+	webhookID, err := strconv.ParseUint(c.Param("webhookId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID format"})
+		return hook, false
+	}
+
+	if err := database.DB.Where("id = ? AND list_id = ?", webhookID, c.Param("listId")).First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return hook, false
+	}
+	return hook, true
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/webhook"
+	"strconv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *HandlerTestSuite) TestCreateWebhook() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+
+	w := suite.postJSON("/api/lists/test-list-id/webhooks", map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"events": []string{webhook.EventTodoCreated},
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	var response struct {
+		Webhook models.Webhook `json:"webhook"`
+		Secret  string         `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Len(suite.T(), response.Secret, 64)
+	assert.Equal(suite.T(), []string{webhook.EventTodoCreated}, response.Webhook.Events)
+	assert.NotContains(suite.T(), w.Body.String(), `"Secret"`)
+
+	// 一覧にはシークレットを含めない
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/webhooks", nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "https://example.com/hook")
+	assert.NotContains(suite.T(), w.Body.String(), response.Secret)
+
+	cases := []map[string]interface{}{
+		{"url": "ftp://example.com/hook"},
+		{"url": "/relative"},
+		{"url": "https://example.com/hook", "events": []string{"todo.archived"}},
+		{"url": "http://127.0.0.1:8080/hook"},
+		{"url": "http://169.254.169.254/latest/meta-data/"},
+		{"url": "http://192.168.0.10/hook"},
+		{"url": "http://localhost/hook"},
+	}
+	for _, payload := range cases {
+		w := suite.postJSON("/api/lists/test-list-id/webhooks", payload)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, payload)
+	}
+
+	w = suite.postJSON("/api/lists/missing/webhooks", map[string]string{"url": "https://example.com/hook"})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestWebhookEvents() {
+	database.DB.Create(&models.List{ID: "test-list-id"})
+	database.DB.Create(&models.User{ID: "test-user-id", ListID: "test-list-id"})
+	hook := models.Webhook{ListID: "test-list-id", URL: "https://example.com/hook", Secret: "secret"}
+	database.DB.Create(&hook)
+
+	w := suite.postJSON("/api/lists/test-list-id/todos", map[string]string{"title": "Todo"})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var todo models.Todo
+	json.Unmarshal(w.Body.Bytes(), &todo)
+
+	w = suite.putJSON("/api/todos/"+strconv.Itoa(int(todo.ID))+"/status/test-user-id", map[string]bool{"checked": true})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	w = suite.putJSON("/api/lists/test-list-id/memo", map[string]string{"memo": "memo"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/lists/test-list-id/webhooks/"+strconv.Itoa(int(hook.ID))+"/deliveries", nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	var response struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	events := []string{}
+	for _, delivery := range response.Deliveries {
+		events = append(events, delivery.Event)
+		assert.Equal(suite.T(), models.DeliveryPending, delivery.Status)
+	}
+	assert.Equal(suite.T(), []string{
+		webhook.EventMemoUpdated,
+		webhook.EventTodoCompleted,
+		webhook.EventTodoChecked,
+		webhook.EventTodoCreated,
+	}, events)
+
+	// 削除すると配信ログも削除される
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/lists/test-list-id/webhooks/"+strconv.Itoa(int(hook.ID)), nil)
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+
+	var count int64
+	database.DB.Model(&models.WebhookDelivery{}).Count(&count)
+	assert.Equal(suite.T(), int64(0), count)
+}
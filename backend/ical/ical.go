@@ -0,0 +1,200 @@
+// Package ical writes todos with a due date as an iCalendar (RFC 5545)
+// calendar that calendar applications can subscribe to, and reads and writes
+// the single to-dos task applications exchange over CalDAV.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Component types a todo can be written as. Most calendar applications show
+// events, while task applications read to-dos.
+const (
+	Event = "VEVENT"
+	Todo  = "VTODO"
+)
+
+// RefreshInterval is how often subscribers are asked to fetch the calendar again
+const RefreshInterval = time.Hour
+
+// maxLineLength is the length in octets lines are folded at
+const maxLineLength = 75
+
+// Item is a todo of a calendar
+type Item struct {
+	UID         string
+	Summary     string
+	Description string
+	// Due is the due date, or the due time when HasTime is set. A to-do may
+	// have no due date.
+	Due     time.Time
+	HasTime bool
+	// Priority is "high", "medium" or "low"
+	Priority   string
+	Categories []string
+	// Completed is when the todo was completed, or nil. A completed to-do read
+	// from a calendar that does not say when has the zero time.
+	Completed *time.Time
+	Created   time.Time
+	Modified  time.Time
+}
+
+// Calendar is a named set of items
+type Calendar struct {
+	Name     string
+	TimeZone string
+	// Component is Event or Todo
+	Component string
+	Items     []Item
+}
+
+// priorities maps a priority to the PRIORITY property, where 1 is the highest
+var priorities = map[string]int{"high": 1, "medium": 5, "low": 9}
+
+// Write writes the calendar, stamped with now
+func (c Calendar) Write(w io.Writer, now time.Time) error {
+	l := &lineWriter{w: w}
+	l.line("BEGIN:VCALENDAR")
+	l.line("VERSION:2.0")
+	l.line("PRODID:-//shared-todo//calendar//EN")
+	l.line("CALSCALE:GREGORIAN")
+	l.line("METHOD:PUBLISH")
+	l.line("X-WR-CALNAME:" + escape(c.Name))
+	if c.TimeZone != "" {
+		l.line("X-WR-TIMEZONE:" + c.TimeZone)
+	}
+	l.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration(RefreshInterval))
+	l.line("X-PUBLISHED-TTL:" + duration(RefreshInterval))
+
+	for _, item := range c.Items {
+		c.writeItem(l, item, now)
+	}
+
+	l.line("END:VCALENDAR")
+	return l.err
+}
+
+// WriteObject writes item as the calendar object of a CalDAV collection: a
+// calendar of the single to-do without the properties of a feed. It is
+// stamped with the time the item was modified, so that it stays the same
+// until the item changes.
+func WriteObject(w io.Writer, item Item) error {
+	l := &lineWriter{w: w}
+	l.line("BEGIN:VCALENDAR")
+	l.line("VERSION:2.0")
+	l.line("PRODID:-//shared-todo//calendar//EN")
+	Calendar{Component: Todo}.writeItem(l, item, item.Modified)
+	l.line("END:VCALENDAR")
+	return l.err
+}
+
+func (c Calendar) writeItem(l *lineWriter, item Item, now time.Time) {
+	component := c.Component
+	if component != Todo {
+		component = Event
+	}
+
+	l.line("BEGIN:" + component)
+	l.line("UID:" + item.UID)
+	l.line("DTSTAMP:" + utc(now))
+	if !item.Created.IsZero() {
+		l.line("CREATED:" + utc(item.Created))
+	}
+	if !item.Modified.IsZero() {
+		l.line("LAST-MODIFIED:" + utc(item.Modified))
+	}
+
+	summary := item.Summary
+	if component == Event && item.Completed != nil {
+		// 予定には完了の状態がないため件名で示す
+		summary = "✓ " + summary
+	}
+	l.line("SUMMARY:" + escape(summary))
+	if item.Description != "" {
+		l.line("DESCRIPTION:" + escape(item.Description))
+	}
+	if len(item.Categories) > 0 {
+		categories := make([]string, len(item.Categories))
+		for i, category := range item.Categories {
+			categories[i] = escape(category)
+		}
+		l.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	if priority, ok := priorities[item.Priority]; ok {
+		l.line(fmt.Sprintf("PRIORITY:%d", priority))
+	}
+
+	// 時刻のない期限は終日として、時刻のある期限は長さのない予定として書く
+	property := "DTSTART"
+	if component == Todo {
+		property = "DUE"
+	}
+	switch {
+	case item.Due.IsZero():
+	case item.HasTime:
+		l.line(property + ":" + utc(item.Due))
+	default:
+		l.line(property + ";VALUE=DATE:" + item.Due.Format("20060102"))
+	}
+
+	if component == Todo {
+		if item.Completed != nil {
+			l.line("STATUS:COMPLETED")
+			l.line("COMPLETED:" + utc(*item.Completed))
+			l.line("PERCENT-COMPLETE:100")
+		} else {
+			l.line("STATUS:NEEDS-ACTION")
+		}
+	} else {
+		l.line("TRANSP:TRANSPARENT")
+	}
+	l.line("END:" + component)
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func duration(d time.Duration) string {
+	return fmt.Sprintf("PT%dM", int(d.Minutes()))
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a text value
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// lineWriter writes content lines ending in CRLF, folded at maxLineLength
+// octets without splitting a UTF-8 sequence. The first error is kept.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (l *lineWriter) line(s string) {
+	if l.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := maxLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// 継続行は先頭の空白の分だけ短くする
+		limit = maxLineLength - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, l.err = io.WriteString(l.w, b.String())
+}
@@ -0,0 +1,101 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func write(t *testing.T, c Calendar) string {
+	var b strings.Builder
+	require.NoError(t, c.Write(&b, now))
+	return b.String()
+}
+
+func TestWriteEvents(t *testing.T) {
+	completed := time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)
+	out := write(t, Calendar{
+		Name:     "Groceries",
+		TimeZone: "Asia/Tokyo",
+		Items: []Item{
+			{UID: "1@test", Summary: "Milk, eggs; bread", Due: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Priority: "high", Categories: []string{"food", "a,b"}},
+			{UID: "2@test", Summary: "Call", Description: "line 1\nline 2", Due: time.Date(2024, 5, 3, 18, 30, 0, 0, time.FixedZone("JST", 9*60*60)), HasTime: true, Completed: &completed},
+		},
+	})
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "X-WR-CALNAME:Groceries\r\n")
+	assert.Contains(t, out, "X-WR-TIMEZONE:Asia/Tokyo\r\n")
+	assert.Contains(t, out, "REFRESH-INTERVAL;VALUE=DURATION:PT60M\r\n")
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VEVENT\r\n"))
+	assert.Contains(t, out, "SUMMARY:Milk\\, eggs\\; bread\r\n")
+	assert.Contains(t, out, "CATEGORIES:food,a\\,b\r\n")
+	assert.Contains(t, out, "PRIORITY:1\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20240502\r\n")
+	assert.Contains(t, out, "DTSTAMP:20240501T000000Z\r\n")
+
+	// 完了したToDoは件名に印を付け、時刻のある期限はUTCで書く
+	assert.Contains(t, out, "SUMMARY:✓ Call\r\n")
+	assert.Contains(t, out, "DESCRIPTION:line 1\\nline 2\r\n")
+	assert.Contains(t, out, "DTSTART:20240503T093000Z\r\n")
+	assert.NotContains(t, out, "STATUS:")
+}
+
+func TestWriteTodos(t *testing.T) {
+	completed := time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)
+	out := write(t, Calendar{
+		Name:      "Groceries",
+		Component: Todo,
+		Items: []Item{
+			{UID: "1@test", Summary: "Milk", Due: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Priority: "low"},
+			{UID: "2@test", Summary: "Call", Due: time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC), HasTime: true, Completed: &completed},
+		},
+	})
+
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VTODO\r\n"))
+	assert.NotContains(t, out, "VEVENT")
+	assert.Contains(t, out, "DUE;VALUE=DATE:20240502\r\n")
+	assert.Contains(t, out, "PRIORITY:9\r\n")
+	assert.Contains(t, out, "STATUS:NEEDS-ACTION\r\n")
+	assert.Contains(t, out, "SUMMARY:Call\r\n")
+	assert.Contains(t, out, "DUE:20240503T093000Z\r\n")
+	assert.Contains(t, out, "STATUS:COMPLETED\r\nCOMPLETED:20240430T120000Z\r\n")
+}
+
+func TestFolding(t *testing.T) {
+	out := write(t, Calendar{Name: strings.Repeat("あ", 40)})
+
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	var unfolded []string
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), maxLineLength, line)
+		if strings.HasPrefix(line, " ") {
+			unfolded[len(unfolded)-1] += line[1:]
+			continue
+		}
+		unfolded = append(unfolded, line)
+	}
+	assert.Contains(t, unfolded, "X-WR-CALNAME:"+strings.Repeat("あ", 40))
+}
+
+func TestWriteObject(t *testing.T) {
+	modified := time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	require.NoError(t, WriteObject(&b, Item{UID: "1@test", Summary: "Milk", Modified: modified}))
+	out := b.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Contains(t, out, "BEGIN:VTODO\r\n")
+	assert.Contains(t, out, "DTSTAMP:20240430T120000Z\r\n")
+	assert.Contains(t, out, "STATUS:NEEDS-ACTION\r\n")
+	// 期限のないToDoには DUE を書かず、フィードのプロパティも書かない
+	assert.NotContains(t, out, "DUE")
+	assert.NotContains(t, out, "METHOD:")
+	assert.NotContains(t, out, "X-WR-CALNAME")
+}
@@ -0,0 +1,212 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoTodo is returned by ReadTodo for a calendar without a to-do
+var ErrNoTodo = errors.New("calendar has no to-do")
+
+// unfolder joins folded content lines
+var unfolder = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "")
+
+// ReadTodo reads the first to-do of a calendar, as sent by a task
+// application. Date-times without a time zone, or with one that is not known,
+// are read in loc. Priority is empty when the to-do has none, and components
+// within the to-do, such as alarms, are skipped.
+func ReadTodo(r io.Reader, loc *time.Location) (Item, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Item{}, err
+	}
+
+	var item Item
+	found := false
+	// depth は VTODO の中で入れ子になったコンポーネントの深さ。-1 は VTODO の外
+	depth := -1
+	for _, line := range strings.Split(unfolder.Replace(string(data)), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		name, params, value, err := parseLine(line)
+		if err != nil {
+			return Item{}, err
+		}
+
+		switch {
+		case name == "BEGIN" && depth < 0:
+			if strings.EqualFold(value, Todo) && !found {
+				depth = 0
+			}
+			continue
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END" && depth == 0:
+			found = true
+			depth = -1
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case depth != 0:
+			continue
+		}
+
+		switch name {
+		case "UID":
+			item.UID = value
+		case "SUMMARY":
+			item.Summary = unescape(value)
+		case "DESCRIPTION":
+			item.Description = unescape(value)
+		case "CATEGORIES":
+			for _, category := range splitList(value) {
+				if category = strings.TrimSpace(category); category != "" {
+					item.Categories = append(item.Categories, category)
+				}
+			}
+		case "PRIORITY":
+			priority, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || priority < 0 || priority > 9 {
+				return Item{}, fmt.Errorf("invalid PRIORITY %q", value)
+			}
+			item.Priority = priorityName(priority)
+		case "DUE":
+			item.Due, item.HasTime, err = parseTime(value, params, loc)
+			if err != nil {
+				return Item{}, fmt.Errorf("invalid DUE %q", value)
+			}
+		case "STATUS":
+			if strings.EqualFold(value, "COMPLETED") && item.Completed == nil {
+				item.Completed = &time.Time{}
+			}
+		case "COMPLETED":
+			completed, _, err := parseTime(value, params, loc)
+			if err != nil {
+				return Item{}, fmt.Errorf("invalid COMPLETED %q", value)
+			}
+			item.Completed = &completed
+		case "CREATED":
+			item.Created, _, _ = parseTime(value, params, loc)
+		case "LAST-MODIFIED":
+			item.Modified, _, _ = parseTime(value, params, loc)
+		}
+	}
+
+	if !found {
+		return Item{}, ErrNoTodo
+	}
+	return item, nil
+}
+
+// parseLine splits a content line into its upper-cased name, its parameters
+// and its value. Parameter values may be quoted and contain ":" and ";".
+func parseLine(line string) (string, map[string]string, string, error) {
+	params := make(map[string]string)
+	i := strings.IndexAny(line, ";:")
+	if i < 0 {
+		return "", nil, "", fmt.Errorf("invalid content line %q", line)
+	}
+	name := strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return "", nil, "", fmt.Errorf("invalid content line %q", line)
+		}
+		key := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return "", nil, "", fmt.Errorf("invalid content line %q", line)
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end < 0 {
+				return "", nil, "", fmt.Errorf("invalid content line %q", line)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		params[key] = value
+
+		i = len(line) - len(rest)
+		if i >= len(line) {
+			return "", nil, "", fmt.Errorf("invalid content line %q", line)
+		}
+	}
+	return name, params, line[i+1:], nil
+}
+
+// parseTime parses a DATE or DATE-TIME value. Dates are midnight UTC, and
+// date-times are in UTC, in the location of their TZID, or otherwise in loc.
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len("20060102") {
+		t, err := time.Parse("20060102", value)
+		return t, false, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, true, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		// 独自のIDの VTIMEZONE には対応せず loc とみなす
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t.UTC(), true, err
+}
+
+// priorityName maps a PRIORITY value to a priority: 1 to 4 are high, 5 is
+// medium and 6 to 9 are low, while 0 means there is none
+func priorityName(priority int) string {
+	switch {
+	case priority == 0:
+		return ""
+	case priority < 5:
+		return "high"
+	case priority == 5:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// splitList splits a list of text values at the commas that are not escaped,
+// and unescapes each value
+func splitList(s string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, unescape(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, unescape(s[start:]))
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// unescape unescapes a text value
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
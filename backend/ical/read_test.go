@@ -0,0 +1,110 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tokyo = time.FixedZone("JST", 9*60*60)
+
+func read(t *testing.T, lines ...string) Item {
+	item, err := ReadTodo(strings.NewReader(strings.Join(lines, "\r\n")+"\r\n"), tokyo)
+	require.NoError(t, err)
+	return item
+}
+
+func TestReadTodo(t *testing.T) {
+	item := read(t,
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"BEGIN:VTODO",
+		"UID:abc@test",
+		"SUMMARY:Milk\\, eggs\\; bread",
+		"DESCRIPTION:line 1\\nline",
+		"  2",
+		"CATEGORIES:food,a\\,b",
+		"CATEGORIES:home",
+		"PRIORITY:2",
+		"DUE;VALUE=DATE:20240502",
+		"BEGIN:VALARM",
+		"DESCRIPTION:alarm",
+		"END:VALARM",
+		"END:VTODO",
+		"END:VCALENDAR",
+	)
+
+	assert.Equal(t, "abc@test", item.UID)
+	assert.Equal(t, "Milk, eggs; bread", item.Summary)
+	assert.Equal(t, "line 1\nline 2", item.Description)
+	assert.Equal(t, []string{"food", "a,b", "home"}, item.Categories)
+	assert.Equal(t, "high", item.Priority)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), item.Due)
+	assert.False(t, item.HasTime)
+	assert.Nil(t, item.Completed)
+}
+
+func TestReadTodoTimes(t *testing.T) {
+	due := func(line string) time.Time {
+		item := read(t, "BEGIN:VCALENDAR", "BEGIN:VTODO", "SUMMARY:x", line, "END:VTODO", "END:VCALENDAR")
+		assert.True(t, item.HasTime)
+		return item.Due
+	}
+
+	assert.Equal(t, time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC), due("DUE:20240503T093000Z"))
+	assert.Equal(t, time.Date(2024, 5, 3, 16, 30, 0, 0, time.UTC), due(`DUE;TZID="Europe/Berlin":20240503T183000`))
+	// タイムゾーンのない日時や未知のTZIDは loc の時刻
+	assert.Equal(t, time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC), due("DUE:20240503T183000"))
+	assert.Equal(t, time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC), due("DUE;TZID=/custom/Zone:20240503T183000"))
+}
+
+func TestReadTodoCompleted(t *testing.T) {
+	item := read(t, "BEGIN:VCALENDAR", "BEGIN:VTODO", "SUMMARY:x", "STATUS:COMPLETED", "COMPLETED:20240430T120000Z", "END:VTODO", "END:VCALENDAR")
+	require.NotNil(t, item.Completed)
+	assert.Equal(t, time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC), *item.Completed)
+
+	item = read(t, "BEGIN:VCALENDAR", "BEGIN:VTODO", "SUMMARY:x", "STATUS:COMPLETED", "END:VTODO", "END:VCALENDAR")
+	require.NotNil(t, item.Completed)
+	assert.True(t, item.Completed.IsZero())
+
+	item = read(t, "BEGIN:VCALENDAR", "BEGIN:VTODO", "SUMMARY:x", "STATUS:NEEDS-ACTION", "PRIORITY:0", "END:VTODO", "END:VCALENDAR")
+	assert.Nil(t, item.Completed)
+	assert.Equal(t, "", item.Priority)
+}
+
+func TestReadTodoErrors(t *testing.T) {
+	_, err := ReadTodo(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"), tokyo)
+	assert.Equal(t, ErrNoTodo, err)
+
+	_, err = ReadTodo(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nDUE:tomorrow\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"), tokyo)
+	assert.Error(t, err)
+
+	_, err = ReadTodo(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nPRIORITY:10\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"), tokyo)
+	assert.Error(t, err)
+}
+
+func TestReadWrittenObject(t *testing.T) {
+	completed := time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC)
+	written := Item{
+		UID:         "1@test",
+		Summary:     strings.Repeat("長い件名, ", 20),
+		Description: "a;b\\c",
+		Due:         time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC),
+		HasTime:     true,
+		Priority:    "low",
+		Categories:  []string{"x,y", "z"},
+		Completed:   &completed,
+		Modified:    completed,
+	}
+	var b bytes.Buffer
+	require.NoError(t, WriteObject(&b, written))
+
+	item, err := ReadTodo(&b, tokyo)
+	require.NoError(t, err)
+	assert.Equal(t, written, item)
+}
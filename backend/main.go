@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"shared-todo-backend/database"
 	"shared-todo-backend/handlers"
 	"shared-todo-backend/middleware"
+	"shared-todo-backend/notify"
+	"shared-todo-backend/scheduler"
+	"shared-todo-backend/webhook"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +20,52 @@ func main() {
 	// データベース初期化
 	database.InitDatabase()
 
+	// 通知チャネルを構成
+	notifiers := notify.Multi{notify.InApp{DB: database.DB}}
+	if url := os.Getenv("REMINDER_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, notify.Webhook{URL: url})
+	}
+	mailer, err := notify.SMTPFromEnv()
+	if err != nil {
+		log.Fatal("Invalid SMTP configuration: ", err)
+	}
+	if mailer != nil {
+		notifiers = append(notifiers, mailer)
+	}
+	handlers.Notifier = notify.Async{Notifier: notifiers}
+
+	// リマインダーのスケジューラを起動
+	reminders := scheduler.New(database.DB, notifiers)
+	if s := os.Getenv("REMINDER_INTERVAL"); s != "" {
+		interval, err := time.ParseDuration(s)
+		if err != nil || interval <= 0 {
+			log.Fatal("Invalid REMINDER_INTERVAL:", s)
+		}
+		reminders.Interval = interval
+	}
+	reminders.Start(context.Background())
+
+	// Webhookの配信キューを処理
+	if s := os.Getenv("WEBHOOK_ALLOWED_NETWORKS"); s != "" {
+		networks, err := webhook.ParseNetworks(s)
+		if err != nil {
+			log.Fatal("Invalid WEBHOOK_ALLOWED_NETWORKS:", s)
+		}
+		webhook.AllowedNetworks = networks
+	}
+	webhook.NewDispatcher(database.DB).Start(context.Background())
+
+	// 作成系APIの再送を重複させない
+	idempotencyTTL := middleware.DefaultIdempotencyTTL
+	if s := os.Getenv("IDEMPOTENCY_KEY_TTL"); s != "" {
+		ttl, err := time.ParseDuration(s)
+		if err != nil || ttl <= 0 {
+			log.Fatal("Invalid IDEMPOTENCY_KEY_TTL:", s)
+		}
+		idempotencyTTL = ttl
+	}
+	idempotent := middleware.Idempotency(database.DB, idempotencyTTL)
+
 	// Ginエンジン初期化
 	r := gin.Default()
 
@@ -24,17 +76,68 @@ func main() {
 	api := r.Group("/api")
 	{
 		// リスト関連
-		api.POST("/lists", handlers.CreateList)
+		api.POST("/lists", idempotent, handlers.CreateList)
+		api.POST("/lists/import", idempotent, handlers.ImportJSON)
 		api.GET("/lists/:listId/users/:userId", handlers.GetListData)
 		api.PUT("/lists/:listId/memo", handlers.UpdateListMemo)
+		api.GET("/lists/:listId/memo/revisions", handlers.GetMemoRevisions)
+		api.GET("/lists/:listId/memo/revisions/:revisionId", handlers.GetMemoRevision)
+		api.POST("/lists/:listId/memo/revisions/:revisionId/restore", handlers.RestoreMemoRevision)
+		api.GET("/lists/:listId/memo/diff", handlers.GetMemoDiff)
+		api.POST("/lists/:listId/memo/operations", handlers.ApplyMemoOperation)
+		api.GET("/lists/:listId/memo/operations", handlers.GetMemoOperations)
+		api.GET("/lists/:listId/memo/stream", handlers.StreamMemo)
+		api.GET("/lists/:listId/search", handlers.SearchList)
+		api.PUT("/lists/:listId/timezone", handlers.UpdateListTimeZone)
+		api.GET("/lists/:listId/activity", handlers.GetActivity)
+		api.GET("/lists/:listId/calendar", handlers.GetCalendarFeed)
+		api.POST("/lists/:listId/calendar", handlers.CreateCalendarFeed)
+		api.DELETE("/lists/:listId/calendar", handlers.DeleteCalendarFeed)
+		api.GET("/calendar/:token", handlers.GetCalendar)
+		for _, method := range handlers.CalDAVMethods {
+			api.Handle(method, "/caldav/:token/*path", handlers.CalDAV)
+		}
+		api.GET("/lists/:listId/export.csv", handlers.ExportCSV)
+		api.GET("/lists/:listId/export.json", handlers.ExportJSON)
+		api.POST("/lists/:listId/import.csv", handlers.ImportCSV)
+		api.GET("/lists/:listId/export.txt", handlers.ExportTodoTxt)
+		api.POST("/lists/:listId/import.txt", handlers.ImportTodoTxt)
+		api.GET("/lists/:listId/export.md", handlers.ExportChecklist)
+		api.POST("/lists/:listId/import.md", handlers.ImportChecklist)
+		api.GET("/lists/:listId/report", handlers.GetReport)
+		api.GET("/lists/:listId/stats", handlers.GetStats)
+
+		// Webhook関連
+		api.POST("/lists/:listId/webhooks", handlers.CreateWebhook)
+		api.GET("/lists/:listId/webhooks", handlers.GetWebhooks)
+		api.DELETE("/lists/:listId/webhooks/:webhookId", handlers.DeleteWebhook)
+		api.GET("/lists/:listId/webhooks/:webhookId/deliveries", handlers.GetWebhookDeliveries)
 
 		// ユーザー関連
-		api.POST("/lists/:listId/users", handlers.InviteUser)
+		api.POST("/lists/:listId/users", idempotent, handlers.InviteUser)
 		api.PUT("/lists/:listId/users/:userId/name", handlers.UpdateUserName)
+		api.GET("/lists/:listId/users/:userId/preferences", handlers.GetUserPreferences)
+		api.PUT("/lists/:listId/users/:userId/preferences", handlers.UpdateUserPreferences)
+		api.POST("/lists/:listId/users/:userId/undo", handlers.UndoActions)
+		api.POST("/lists/:listId/users/:userId/sync", handlers.SyncList)
+		api.GET("/lists/:listId/users/:userId/calendar", handlers.GetCalendarFeed)
+		api.POST("/lists/:listId/users/:userId/calendar", handlers.CreateCalendarFeed)
+		api.DELETE("/lists/:listId/users/:userId/calendar", handlers.DeleteCalendarFeed)
+		api.GET("/lists/:listId/users/:userId/caldav", handlers.GetCalDAVToken)
+		api.POST("/lists/:listId/users/:userId/caldav", handlers.CreateCalDAVToken)
+		api.DELETE("/lists/:listId/users/:userId/caldav", handlers.DeleteCalDAVToken)
+
+		// 通知関連
+		api.GET("/lists/:listId/users/:userId/digest", handlers.GetDigest)
+		api.GET("/lists/:listId/users/:userId/notifications", handlers.GetNotifications)
+		api.POST("/lists/:listId/users/:userId/notifications/read", handlers.MarkNotificationsRead)
 
 		// ToDo関連
-		api.POST("/lists/:listId/todos", handlers.CreateTodo)
+		api.POST("/lists/:listId/todos", idempotent, handlers.CreateTodo)
+		api.POST("/lists/:listId/todos/batch", idempotent, handlers.BatchTodos)
 		api.PUT("/todos/:todoId/status/:userId", handlers.UpdateTodoUserStatus)
+		api.PUT("/lists/:listId/users/:userId/todos/:todoId/assignees", handlers.UpdateTodoAssignees)
+		api.DELETE("/lists/:listId/users/:userId/todos/:todoId", handlers.DeleteTodo)
 	}
 
 	// ポート設定
@@ -0,0 +1,281 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// allowedSchemes are the URL schemes links may use
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// SanitizeURL returns the escaped URL when it is safe to link to, or "" otherwise.
+// Only http, https and mailto URLs and relative references are allowed.
+func SanitizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	for _, r := range raw {
+		if unicode.IsControl(r) || unicode.IsSpace(r) || strings.ContainsRune("\"'<>`", r) {
+			return ""
+		}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme != "" && !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return ""
+	}
+	// "//evil.example" のようなスキーム相対URLや、スキームに見える相対パスを拒否
+	if u.Scheme == "" && (strings.HasPrefix(raw, "//") || strings.Contains(strings.SplitN(raw, "/", 2)[0], ":")) {
+		return ""
+	}
+	return html.EscapeString(raw)
+}
+
+func link(href, text string) string {
+	return `<a href="` + href + `" rel="nofollow noopener noreferrer">` + text + `</a>`
+}
+
+// renderInline renders inline markup of a paragraph or heading
+func renderInline(src string) string {
+	var b strings.Builder
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == '\\' && i+1 < len(runes) && isEscapable(runes[i+1]):
+			b.WriteString(html.EscapeString(string(runes[i+1])))
+			i += 2
+			continue
+
+		case r == '\n':
+			b.WriteString("<br>\n")
+			i++
+			continue
+
+		case r == '`':
+			if n, out := codeSpan(runes, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+
+		case r == '[':
+			if n, out := inlineLink(runes, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+
+		case r == '<':
+			if n, out := angleLink(runes, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+
+		case r == 'h' && (i == 0 || !isWordRune(runes[i-1])):
+			if n, out := bareLink(runes, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+
+		case r == '*' || r == '_' || r == '~':
+			if n, out := emphasis(runes, i); n > 0 {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+		}
+
+		b.WriteString(html.EscapeString(string(r)))
+		i++
+	}
+	return b.String()
+}
+
+func isEscapable(r rune) bool {
+	return r < unicode.MaxASCII && unicode.IsPunct(r) || strings.ContainsRune("`*_~[]()<>#+-.!|\\", r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// runLength counts the repetitions of runes[i] starting at i
+func runLength(runes []rune, i int) int {
+	n := 0
+	for i+n < len(runes) && runes[i+n] == runes[i] {
+		n++
+	}
+	return n
+}
+
+// codeSpan renders `code` and returns the number of runes consumed
+func codeSpan(runes []rune, i int) (int, string) {
+	ticks := runLength(runes, i)
+	for j := i + ticks; j < len(runes); j++ {
+		if runes[j] != '`' {
+			continue
+		}
+		n := runLength(runes, j)
+		if n == ticks {
+			code := strings.TrimSpace(string(runes[i+ticks : j]))
+			return j + n - i, "<code>" + html.EscapeString(code) + "</code>"
+		}
+		j += n - 1
+	}
+	return 0, ""
+}
+
+// inlineLink renders [text](url)
+func inlineLink(runes []rune, i int) (int, string) {
+	depth := 0
+	closeText := -1
+	for j := i; j < len(runes); j++ {
+		if runes[j] == '\\' {
+			j++
+			continue
+		}
+		if runes[j] == '[' {
+			depth++
+		} else if runes[j] == ']' {
+			depth--
+			if depth == 0 {
+				closeText = j
+				break
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(runes) || runes[closeText+1] != '(' {
+		return 0, ""
+	}
+
+	closeURL := -1
+	parens := 0
+	for j := closeText + 2; j < len(runes) && closeURL < 0; j++ {
+		switch runes[j] {
+		case '(':
+			parens++
+		case ')':
+			if parens == 0 {
+				closeURL = j
+			}
+			parens--
+		case '\n':
+			return 0, ""
+		}
+	}
+	if closeURL < 0 {
+		return 0, ""
+	}
+
+	text := renderInline(string(runes[i+1 : closeText]))
+	href := SanitizeURL(string(runes[closeText+2 : closeURL]))
+	if href == "" {
+		// 危険なURLはリンクにせずテキストのみ表示
+		return closeURL + 1 - i, text
+	}
+	return closeURL + 1 - i, link(href, text)
+}
+
+// angleLink renders <https://example.com>
+func angleLink(runes []rune, i int) (int, string) {
+	for j := i + 1; j < len(runes); j++ {
+		if runes[j] == '>' {
+			raw := string(runes[i+1 : j])
+			if !strings.Contains(raw, ":") {
+				return 0, ""
+			}
+			href := SanitizeURL(raw)
+			if href == "" {
+				return 0, ""
+			}
+			return j + 1 - i, link(href, html.EscapeString(raw))
+		}
+		if runes[j] == '<' || unicode.IsSpace(runes[j]) {
+			break
+		}
+	}
+	return 0, ""
+}
+
+// bareLink renders a plain http(s) URL found in text
+func bareLink(runes []rune, i int) (int, string) {
+	rest := string(runes[i:])
+	if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+		return 0, ""
+	}
+
+	j := i
+	for j < len(runes) && !unicode.IsSpace(runes[j]) && runes[j] != '<' {
+		j++
+	}
+	// 文末の句読点はURLに含めない
+	for j > i && strings.ContainsRune(".,;:!?)'\"", runes[j-1]) {
+		j--
+	}
+
+	raw := string(runes[i:j])
+	href := SanitizeURL(raw)
+	if href == "" || raw == "http://" || raw == "https://" {
+		return 0, ""
+	}
+	return j - i, link(href, html.EscapeString(raw))
+}
+
+// emphasis renders *em*, **strong** and ~~del~~ (also with underscores)
+func emphasis(runes []rune, i int) (int, string) {
+	delim := runes[i]
+	n := runLength(runes, i)
+
+	var tag string
+	var width int
+	switch {
+	case delim == '~' && n >= 2:
+		tag, width = "del", 2
+	case delim == '~':
+		return 0, ""
+	case n >= 2:
+		tag, width = "strong", 2
+	default:
+		tag, width = "em", 1
+	}
+
+	// 単語の途中の "_" は強調として扱わない
+	if delim == '_' && i > 0 && isWordRune(runes[i-1]) {
+		return 0, ""
+	}
+	start := i + width
+	if start >= len(runes) || unicode.IsSpace(runes[start]) {
+		return 0, ""
+	}
+
+	for j := start + 1; j+width <= len(runes); j++ {
+		if runes[j] != delim {
+			continue
+		}
+		if run := runLength(runes, j); unicode.IsSpace(runes[j-1]) || run < width || (width == 1 && run > 1) {
+			j += run - 1
+			continue
+		}
+		if delim == '_' && j+width < len(runes) && isWordRune(runes[j+width]) {
+			continue
+		}
+		inner := renderInline(string(runes[start:j]))
+		return j + width - i, "<" + tag + ">" + inner + "</" + tag + ">"
+	}
+	return 0, ""
+}
@@ -0,0 +1,220 @@
+// Package markdown renders the Markdown subset used in todo descriptions and
+// the list memo to HTML.
+//
+// The renderer is safe by construction: raw HTML in the source is always
+// escaped and links are only emitted for URLs accepted by SanitizeURL, so the
+// output can be inserted into a page without further sanitizing.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	rulePattern    = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fencePattern   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	bulletPattern  = regexp.MustCompile(`^ {0,3}([-*+])[ \t]+`)
+	orderedPattern = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+`)
+	quotePattern   = regexp.MustCompile(`^ {0,3}> ?`)
+)
+
+// Render converts Markdown source to safe HTML
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	if strings.TrimSpace(src) == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// startsBlock reports whether line interrupts a paragraph
+func startsBlock(line string) bool {
+	return headingPattern.MatchString(line) || rulePattern.MatchString(line) ||
+		fencePattern.MatchString(line) || bulletPattern.MatchString(line) ||
+		orderedPattern.MatchString(line) || quotePattern.MatchString(line)
+}
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case isBlank(line):
+			i++
+
+		case fencePattern.MatchString(line):
+			i = renderFence(b, lines, i)
+
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+			i++
+
+		case rulePattern.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case quotePattern.MatchString(line):
+			var quoted []string
+			for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quotePattern.ReplaceAllString(lines[i], ""))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case bulletPattern.MatchString(line):
+			i = renderList(b, lines, i, bulletPattern, "ul")
+
+		case orderedPattern.MatchString(line):
+			i = renderList(b, lines, i, orderedPattern, "ol")
+
+		default:
+			var para []string
+			for ; i < len(lines) && !isBlank(lines[i]); i++ {
+				if len(para) > 0 && startsBlock(lines[i]) {
+					break
+				}
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			b.WriteString("<p>" + renderInline(strings.Join(para, "\n")) + "</p>\n")
+		}
+	}
+}
+
+func renderFence(b *strings.Builder, lines []string, i int) int {
+	m := fencePattern.FindStringSubmatch(lines[i])
+	fence, lang := m[1], m[2]
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) && strings.Trim(strings.TrimSpace(lines[i]), fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	b.WriteString("<pre><code")
+	if lang != "" {
+		b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString(">")
+	if len(code) > 0 {
+		b.WriteString(html.EscapeString(strings.Join(code, "\n")) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderList renders consecutive items of the same list type. Lines indented
+// below an item belong to it and may contain nested blocks.
+func renderList(b *strings.Builder, lines []string, i int, marker *regexp.Regexp, tag string) int {
+	b.WriteString("<" + tag)
+	if tag == "ol" {
+		if start := marker.FindStringSubmatch(lines[i])[1]; strings.TrimLeft(start, "0") != "1" {
+			b.WriteString(` start="` + strings.TrimLeft(start, "0") + `"`)
+		}
+	}
+	b.WriteString(">\n")
+
+	for i < len(lines) && marker.MatchString(lines[i]) {
+		indent := len(marker.FindString(lines[i]))
+		item := []string{lines[i][indent:]}
+		loose := false
+
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				// 空行の後にインデントされた行が続く場合のみ項目に含める
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= indent {
+					item = append(item, "")
+					loose = true
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= indent {
+				item = append(item, dedent(line, indent))
+				continue
+			}
+			if marker.MatchString(line) || startsBlock(line) {
+				break
+			}
+			// 遅延継続行
+			item = append(item, strings.TrimSpace(line))
+		}
+
+		var inner strings.Builder
+		renderBlocks(&inner, item)
+		content := strings.TrimRight(inner.String(), "\n")
+		if !loose && strings.HasPrefix(content, "<p>") {
+			// 詰めて書かれたリストは段落タグを付けない
+			content = strings.Replace(content, "<p>", "", 1)
+			content = strings.Replace(content, "</p>", "", 1)
+		}
+		b.WriteString("<li>" + renderTaskMarker(content) + "</li>\n")
+
+		// 空行を挟んで同じリストが続く場合
+		if i+1 < len(lines) && isBlank(lines[i]) && marker.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderTaskMarker turns a leading "[ ]" or "[x]" of a list item into a disabled checkbox
+func renderTaskMarker(content string) string {
+	switch {
+	case strings.HasPrefix(content, "[ ] "):
+		return `<input type="checkbox" disabled> ` + content[4:]
+	case strings.HasPrefix(content, "[x] "), strings.HasPrefix(content, "[X] "):
+		return `<input type="checkbox" checked disabled> ` + content[4:]
+	}
+	return content
+}
+
+func leadingSpaces(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// dedent removes up to n columns of leading whitespace
+func dedent(line string, n int) string {
+	col := 0
+	for i, r := range line {
+		if col >= n || (r != ' ' && r != '\t') {
+			return line[i:]
+		}
+		if r == '\t' {
+			col += 4
+		} else {
+			col++
+		}
+	}
+	return ""
+}
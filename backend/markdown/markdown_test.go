@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderBlocks(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "  \n", ""},
+		{"paragraph", "line one\nline two\n\nnext", "<p>line one<br>\nline two</p>\n<p>next</p>"},
+		{"heading", "## Title ##", "<h2>Title</h2>"},
+		{"hashtag is not a heading", "#groceries", "<p>#groceries</p>"},
+		{"rule", "a\n\n---", "<p>a</p>\n<hr>"},
+		{"fenced code", "```go\nfmt.Println(\"<b>\")\n```", "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;b&gt;&#34;)\n</code></pre>"},
+		{"unclosed fence", "```\ncode", "<pre><code>code\n</code></pre>"},
+		{"blockquote", "> quoted\n> text", "<blockquote>\n<p>quoted<br>\ntext</p>\n</blockquote>"},
+		{"bullet list", "- one\n- two", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>"},
+		{"ordered list", "3. three\n4. four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>"},
+		{"nested list", "- one\n  - nested\n- two", "<ul>\n<li>one\n<ul>\n<li>nested</li>\n</ul></li>\n<li>two</li>\n</ul>"},
+		{"task list", "- [ ] todo\n- [x] done", "<ul>\n<li><input type=\"checkbox\" disabled> todo</li>\n<li><input type=\"checkbox\" checked disabled> done</li>\n</ul>"},
+		{"list after paragraph", "Items:\n- a", "<p>Items:</p>\n<ul>\n<li>a</li>\n</ul>"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, Render(c.src))
+		})
+	}
+}
+
+func TestRenderInline(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"emphasis", "*em* **strong** ~~gone~~", "<em>em</em> <strong>strong</strong> <del>gone</del>"},
+		{"nested emphasis", "*a **b** c*", "<em>a <strong>b</strong> c</em>"},
+		{"intraword underscore", "snake_case_name", "snake_case_name"},
+		{"code span", "use `a < b` here", "use <code>a &lt; b</code> here"},
+		{"escape", `\*not em\*`, "*not em*"},
+		{"link", "[docs](https://example.com/a?b=1&c=2)", `<a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">docs</a>`},
+		{"bare url", "see https://example.com/x.", `see <a href="https://example.com/x" rel="nofollow noopener noreferrer">https://example.com/x</a>.`},
+		{"angle link", "<https://example.com>", `<a href="https://example.com" rel="nofollow noopener noreferrer">https://example.com</a>`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, "<p>"+c.want+"</p>", Render(c.src))
+		})
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	assert.Equal(t, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>", Render("<script>alert(1)</script>"))
+	assert.Equal(t, "<p>&lt;img src=x onerror=alert(1)&gt;</p>", Render("<img src=x onerror=alert(1)>"))
+	assert.Equal(t, "<p>click</p>", Render("[click](javascript:alert(1))"))
+	assert.Equal(t, "<p>x</p>", Render(`[x]("onmouseover=alert(1))`))
+}
+
+func TestSanitizeURL(t *testing.T) {
+	assert.Equal(t, "https://example.com", SanitizeURL("https://example.com"))
+	assert.Equal(t, "mailto:a@example.com", SanitizeURL("mailto:a@example.com"))
+	assert.Equal(t, "/relative/path", SanitizeURL("/relative/path"))
+	assert.Equal(t, "", SanitizeURL("javascript:alert(1)"))
+	assert.Equal(t, "", SanitizeURL("JaVaScRiPt:alert(1)"))
+	assert.Equal(t, "", SanitizeURL("data:text/html,<b>"))
+	assert.Equal(t, "", SanitizeURL("//evil.example"))
+	assert.Equal(t, "", SanitizeURL("java\tscript:alert(1)"))
+}
@@ -15,7 +15,8 @@ func SetupCORS() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{corsOrigin}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-User-ID", "If-Match", "If-None-Match", "If-Modified-Since", "Idempotency-Key"}
+	config.ExposeHeaders = []string{"ETag", "Last-Modified", "Idempotent-Replayed"}
 
 	return cors.New(config)
 }
\ No newline at end of file
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying an idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on a response replayed for a retry
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL is how long a response is kept for retries by default
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize is the largest request body read to hash it: the
+	// largest file an import accepts (5 MB) and the multipart form around it
+	maxIdempotentBodySize = 5<<20 + 1<<20
+	// idempotencyLockTimeout is how long a request in progress holds its key.
+	// A key held longer belongs to a request that never finished, e.g. because
+	// the server stopped, and may be taken by a retry.
+	idempotencyLockTimeout = time.Minute
+)
+
+// Idempotency returns a middleware deduplicating requests sent with an
+// Idempotency-Key header. The response to the first request is stored for ttl,
+// and a retry with the same key, method and path replays it without running
+// the handler again. A retry with a different body fails with 422, and one
+// sent while the first request is still running fails with 409. Server errors
+// are not stored, so the request can be retried.
+func Idempotency(db *gorm.DB, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency key must be 255 characters or less"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+
+		now := time.Now().UTC()
+		record := models.IdempotencyKey{
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: hex.EncodeToString(sum[:]),
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		// 期限切れのキーと終わらなかったリクエストのキーを削除してから予約する
+		db.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+		db.Where("key = ? AND method = ? AND path = ? AND status_code = 0 AND created_at < ?",
+			record.Key, record.Method, record.Path, now.Add(-idempotencyLockTimeout)).Delete(&models.IdempotencyKey{})
+
+		if err := db.Create(&record).Error; err != nil {
+			var stored models.IdempotencyKey
+			if err := db.Where("key = ? AND method = ? AND path = ?", record.Key, record.Method, record.Path).First(&stored).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+				return
+			}
+			replay(c, stored, record.RequestHash)
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			db.Delete(&record)
+			return
+		}
+		err = db.Model(&record).Updates(map[string]interface{}{
+			"status_code":  status,
+			"content_type": writer.Header().Get("Content-Type"),
+			"body":         writer.body.String(),
+		}).Error
+		if err != nil {
+			log.Printf("Failed to store response for idempotency key %s: %v", key, err)
+			db.Delete(&record)
+		}
+	}
+}
+
+// replay responds to a retry with the stored response of the first request
+func replay(c *gin.Context, stored models.IdempotencyKey, requestHash string) {
+	if stored.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency key was already used for a different request"})
+		return
+	}
+	if stored.StatusCode == 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this idempotency key is in progress"})
+		return
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(stored.StatusCode, stored.ContentType, []byte(stored.Body))
+	c.Abort()
+}
+
+// recordingWriter keeps a copy of the response body written by a handler
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	router *gin.Engine
+	calls  int
+	status int
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	db, err := database.SetupTestDatabase()
+	suite.Require().NoError(err)
+	suite.db = db
+	suite.calls = 0
+	suite.status = http.StatusCreated
+
+	suite.router = gin.New()
+	suite.router.POST("/items", Idempotency(db, time.Hour), func(c *gin.Context) {
+		suite.calls++
+		c.JSON(suite.status, gin.H{"call": suite.calls})
+	})
+}
+
+func (suite *IdempotencyTestSuite) post(key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *IdempotencyTestSuite) TestReplay() {
+	first := suite.post("key-1", `{"title": "Milk"}`)
+	assert.Equal(suite.T(), http.StatusCreated, first.Code)
+	assert.Empty(suite.T(), first.Header().Get(IdempotentReplayedHeader))
+
+	retry := suite.post("key-1", `{"title": "Milk"}`)
+	assert.Equal(suite.T(), http.StatusCreated, retry.Code)
+	assert.Equal(suite.T(), first.Body.String(), retry.Body.String())
+	assert.Equal(suite.T(), "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(suite.T(), "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), 1, suite.calls)
+
+	// キーが違う、またはキーがないリクエストは毎回実行する
+	suite.post("key-2", `{"title": "Milk"}`)
+	suite.post("", `{"title": "Milk"}`)
+	suite.post("", `{"title": "Milk"}`)
+	assert.Equal(suite.T(), 4, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestDifferentRequest() {
+	suite.post("key-1", `{"title": "Milk"}`)
+
+	w := suite.post("key-1", `{"title": "Eggs"}`)
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestInProgress() {
+	suite.db.Create(&models.IdempotencyKey{
+		Key:         "key-1",
+		Method:      "POST",
+		Path:        "/items",
+		RequestHash: "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+
+	w := suite.post("key-1", `{}`)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.Equal(suite.T(), 0, suite.calls)
+
+	// 終わらなかったリクエストのキーは時間が経てば再送で使える
+	suite.db.Model(&models.IdempotencyKey{}).Where("key = ?", "key-1").Update("created_at", time.Now().Add(-2*idempotencyLockTimeout))
+	w = suite.post("key-1", `{}`)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Equal(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestServerErrorNotStored() {
+	suite.status = http.StatusInternalServerError
+	w := suite.post("key-1", `{}`)
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+
+	suite.status = http.StatusCreated
+	w = suite.post("key-1", `{}`)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Equal(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestExpired() {
+	suite.post("key-1", `{}`)
+	suite.db.Model(&models.IdempotencyKey{}).Where("key = ?", "key-1").Update("expires_at", time.Now().Add(-time.Minute))
+
+	w := suite.post("key-1", `{}`)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	assert.Empty(suite.T(), w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(suite.T(), 2, suite.calls)
+
+	var count int64
+	suite.db.Model(&models.IdempotencyKey{}).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+}
+
+func (suite *IdempotencyTestSuite) TestKeyTooLong() {
+	w := suite.post(string(bytes.Repeat([]byte("k"), 256)), `{}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Equal(suite.T(), 0, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestBodyTooLarge() {
+	w := suite.post("key-1", `{"title": "`+strings.Repeat("a", maxIdempotentBodySize)+`"}`)
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(suite.T(), 0, suite.calls)
+
+	// 大きすぎたリクエストはキーを予約しない
+	w = suite.post("key-1", `{"title": "Milk"}`)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Activity actions
+const (
+	ActionListCreated        = "list.created"
+	ActionListImported       = "list.imported"
+	ActionMemoUpdated        = "memo.updated"
+	ActionMemoEdited         = "memo.edited"
+	ActionTimeZoneUpdated    = "list.timezone_updated"
+	ActionUserJoined         = "user.joined"
+	ActionUserRenamed        = "user.renamed"
+	ActionPreferencesUpdated = "user.preferences_updated"
+	ActionTodoCreated        = "todo.created"
+	ActionTodoUpdated        = "todo.updated"
+	ActionTodoChecked        = "todo.checked"
+	ActionTodoUnchecked      = "todo.unchecked"
+	ActionTodoCompleted      = "todo.completed"
+	ActionTodoReopened       = "todo.reopened"
+	ActionTodoDeleted        = "todo.deleted"
+	ActionTodoRestored       = "todo.restored"
+	ActionAssigneesUpdated   = "todo.assignees_updated"
+	ActionPriorityUpdated    = "todo.priority_updated"
+	ActionWebhookCreated     = "webhook.created"
+	ActionWebhookDeleted     = "webhook.deleted"
+)
+
+// Activity target types
+const (
+	TargetList    = "list"
+	TargetUser    = "user"
+	TargetTodo    = "todo"
+	TargetWebhook = "webhook"
+)
+
+// Activity is an entry of the append-only activity log of a list. Before and
+// After hold the changed values as JSON and are null when there is no value,
+// e.g. Before of a created todo. An entry recording an undo points to the
+// undone entry with UndoOf.
+type Activity struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	ListID     string          `json:"listId" gorm:"not null;index"`
+	ActorID    string          `json:"actorId" gorm:"default:''"`
+	Action     string          `json:"action" gorm:"not null"`
+	TargetType string          `json:"targetType" gorm:"not null"`
+	TargetID   string          `json:"targetId" gorm:"not null"`
+	Before     json.RawMessage `json:"before" gorm:"type:text"`
+	After      json.RawMessage `json:"after" gorm:"type:text"`
+	UndoOf     *uint           `json:"undoOf,omitempty" gorm:"index"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed is the secret token of the iCalendar feed of a list, or of a
+// user of the list when UserID is set. Anyone with the token can read the
+// feed, so a new token replaces the old one.
+type CalendarFeed struct {
+	Token     string    `json:"token" gorm:"primaryKey"`
+	ListID    string    `json:"listId" gorm:"not null;uniqueIndex:idx_calendar_feed"`
+	UserID    string    `json:"userId" gorm:"not null;default:'';uniqueIndex:idx_calendar_feed"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CalDAVToken is the secret token with which the CalDAV clients of a user
+// change todos as the user. Calendar feed tokens only read, as they are handed
+// to calendar applications and may be published, so writing takes this
+// separate token. A new token replaces the old one.
+type CalDAVToken struct {
+	Token     string    `json:"token" gorm:"primaryKey"`
+	ListID    string    `json:"listId" gorm:"not null;uniqueIndex:idx_caldav_token"`
+	UserID    string    `json:"userId" gorm:"not null;uniqueIndex:idx_caldav_token"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CalDAVObject is the name and UID a CalDAV client gave a todo it created.
+// Other todos are served as "<id>.ics" with a UID made from their ID.
+type CalDAVObject struct {
+	TodoID uint   `json:"todoId" gorm:"primaryKey;autoIncrement:false"`
+	ListID string `json:"listId" gorm:"not null;uniqueIndex:idx_caldav_object"`
+	Name   string `json:"name" gorm:"not null;uniqueIndex:idx_caldav_object"`
+	UID    string `json:"uid" gorm:"not null"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// ListDocumentFormat identifies a list exported as JSON
+const ListDocumentFormat = "shared-todo/list"
+
+// ListDocumentVersion is the version of the documents written by exports.
+// Imports read this version and the ones before it.
+const ListDocumentVersion = 1
+
+// ListDocument is a whole list exported as JSON, to back it up or move it to
+// another instance. The IDs in a document only relate its parts to each other;
+// an import gives the list, its users and its todos new IDs.
+type ListDocument struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	List       DocumentList   `json:"list"`
+	Users      []DocumentUser `json:"users"`
+	Todos      []DocumentTodo `json:"todos"`
+}
+
+type DocumentList struct {
+	ID        string    `json:"id"`
+	Memo      string    `json:"memo"`
+	TimeZone  string    `json:"timeZone"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type DocumentUser struct {
+	ID                  string `json:"id"`
+	DisplayName         string `json:"displayName"`
+	TimeZone            string `json:"timeZone"`
+	ReminderLeadMinutes int    `json:"reminderLeadMinutes"`
+	// The email address of a user is private to the user and not exported.
+	// The email settings are nil when a document leaves them out, and then
+	// take the defaults of a new user
+	EmailOnComplete *bool     `json:"emailOnComplete"`
+	EmailOnAssign   *bool     `json:"emailOnAssign"`
+	EmailDigest     *bool     `json:"emailDigest"`
+	DigestFrequency string    `json:"digestFrequency"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type DocumentTodo struct {
+	ID          uint             `json:"id"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Priority    string           `json:"priority"`
+	Labels      Labels           `json:"labels"`
+	DueDate     *time.Time       `json:"dueDate"`
+	HasDueTime  bool             `json:"hasDueTime"`
+	IsCompleted bool             `json:"isCompleted"`
+	CompletedAt *time.Time       `json:"completedAt"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	Statuses    []DocumentStatus `json:"statuses"`
+}
+
+// DocumentStatus is a TodoUserStatus of a DocumentTodo
+type DocumentStatus struct {
+	UserID     string     `json:"userId"`
+	IsChecked  bool       `json:"isChecked"`
+	CheckedAt  *time.Time `json:"checkedAt"`
+	IsAssigned bool       `json:"isAssigned"`
+}
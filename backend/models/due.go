@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+// DefaultTimeZone is used when neither the list nor the user sets a time zone
+const DefaultTimeZone = "UTC"
+
+// LoadLocation returns the location for a time zone name, falling back to UTC
+// for empty or unknown names.
+func LoadLocation(name string) *time.Location {
+	if name == "" || name == "Local" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidTimeZone reports whether name is an IANA time zone name
+func ValidTimeZone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// EffectiveTimeZone returns the user's time zone, or the list's when the user has none
+func EffectiveTimeZone(list List, user User) string {
+	if user.TimeZone != "" {
+		return user.TimeZone
+	}
+	if list.TimeZone != "" {
+		return list.TimeZone
+	}
+	return DefaultTimeZone
+}
+
+// DueDay returns the calendar day of the due date in loc. A date-only due date is
+// stored as midnight UTC and keeps its calendar day in every time zone.
+func (t *Todo) DueDay(loc *time.Location) (int, time.Month, int) {
+	if !t.HasDueTime {
+		return t.DueDate.UTC().Date()
+	}
+	return t.DueDate.In(loc).Date()
+}
+
+// DueAt returns the instant the todo becomes overdue, or nil without a due date.
+// A date-only due date lasts until the end of that day in loc.
+func (t *Todo) DueAt(loc *time.Location) *time.Time {
+	if t.DueDate == nil {
+		return nil
+	}
+	if t.HasDueTime {
+		due := *t.DueDate
+		return &due
+	}
+	y, m, d := t.DueDay(loc)
+	due := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	return &due
+}
+
+// UpdateDueState sets IsOverdue and IsDueToday as seen at now in loc.
+// Completed todos are never overdue.
+func (t *Todo) UpdateDueState(now time.Time, loc *time.Location) {
+	t.IsOverdue = false
+	t.IsDueToday = false
+	if t.DueDate == nil {
+		return
+	}
+
+	y, m, d := t.DueDay(loc)
+	ny, nm, nd := now.In(loc).Date()
+	t.IsDueToday = y == ny && m == nm && d == nd
+	t.IsOverdue = !t.IsCompleted && !now.Before(*t.DueAt(loc))
+}
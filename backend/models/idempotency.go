@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey stores the response to a request sent with an Idempotency-Key
+// header, so that a retry of the request replays it until ExpiresAt instead of
+// running again. StatusCode is 0 while the first request is in progress.
+type IdempotencyKey struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Key         string    `json:"key" gorm:"not null;uniqueIndex:idx_idempotency_key"`
+	Method      string    `json:"method" gorm:"not null;uniqueIndex:idx_idempotency_key"`
+	Path        string    `json:"path" gorm:"not null;uniqueIndex:idx_idempotency_key"`
+	RequestHash string    `json:"requestHash" gorm:"not null"`
+	StatusCode  int       `json:"statusCode" gorm:"default:0"`
+	ContentType string    `json:"contentType" gorm:"default:''"`
+	Body        string    `json:"body" gorm:"type:text;default:''"`
+	ExpiresAt   time.Time `json:"expiresAt" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MemoRevision is a saved version of the memo of a list. Every save of the memo
+// adds a revision, so the latest revision matches List.Memo. Version is the
+// List.Version the save produced.
+type MemoRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ListID    string    `json:"listId" gorm:"not null;index"`
+	AuthorID  string    `json:"authorId"`
+	Memo      string    `json:"memo" gorm:"type:text"`
+	Version   int       `json:"version"`
+	RestoreOf *uint     `json:"restoreOf,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MemoOperation is the edit of the memo of a list that produced Version, as an
+// ot.Operation in JSON. Every change of the memo is logged, so editors based on
+// an older version can be brought up to date.
+type MemoOperation struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	ListID    string          `json:"listId" gorm:"not null;uniqueIndex:idx_memo_operation_version"`
+	Version   int             `json:"version" gorm:"not null;uniqueIndex:idx_memo_operation_version"`
+	AuthorID  string          `json:"authorId"`
+	ClientID  string          `json:"clientId,omitempty"`
+	Operation json.RawMessage `json:"operation" gorm:"type:text"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
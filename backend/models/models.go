@@ -2,11 +2,15 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type List struct {
 	ID        string    `json:"id" gorm:"primaryKey"`
 	Memo      string    `json:"memo" gorm:"default:''"`
+	TimeZone  string    `json:"timeZone" gorm:"default:'UTC'"`
+	Version   int       `json:"version" gorm:"not null;default:1"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	Users     []User    `json:"users,omitempty" gorm:"foreignKey:ListID"`
@@ -14,31 +18,50 @@ type List struct {
 }
 
 type User struct {
-	ID          string    `json:"id" gorm:"primaryKey"`
-	ListID      string    `json:"listId" gorm:"not null"`
-	DisplayName string    `json:"displayName" gorm:"default:''"`
-	CreatedAt   time.Time `json:"createdAt"`
-	List        List      `json:"-" gorm:"foreignKey:ListID"`
+	ID                  string     `json:"id" gorm:"primaryKey"`
+	ListID              string     `json:"listId" gorm:"not null"`
+	DisplayName         string     `json:"displayName" gorm:"default:''"`
+	TimeZone            string     `json:"timeZone" gorm:"default:''"`
+	ReminderLeadMinutes int        `json:"reminderLeadMinutes" gorm:"default:60"`
+	Email               string     `json:"-" gorm:"default:''"`
+	EmailOnComplete     bool       `json:"-" gorm:"default:true"`
+	EmailOnAssign       bool       `json:"-" gorm:"default:true"`
+	EmailDigest         bool       `json:"-" gorm:"default:true"`
+	DigestFrequency     string     `json:"-" gorm:"default:'off'"`
+	LastDigestAt        *time.Time `json:"-"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	List                List       `json:"-" gorm:"foreignKey:ListID"`
 }
 
 type Todo struct {
-	ID           uint              `json:"id" gorm:"primaryKey"`
-	ListID       string            `json:"listId" gorm:"not null"`
-	Title        string            `json:"title" gorm:"not null"`
-	Priority     string            `json:"priority" gorm:"default:'medium';check:priority IN ('high', 'medium', 'low')"`
-	DueDate      *time.Time        `json:"dueDate"`
-	IsCompleted  bool              `json:"isCompleted" gorm:"default:false"`
-	CreatedAt    time.Time         `json:"createdAt"`
-	UpdatedAt    time.Time         `json:"updatedAt"`
-	List         List              `json:"-" gorm:"foreignKey:ListID"`
-	UserStatuses []TodoUserStatus  `json:"userStatuses,omitempty" gorm:"foreignKey:TodoID"`
+	ID              uint             `json:"id" gorm:"primaryKey"`
+	ListID          string           `json:"listId" gorm:"not null"`
+	Title           string           `json:"title" gorm:"not null"`
+	Description     string           `json:"description" gorm:"type:text;default:''"`
+	DescriptionHTML string           `json:"descriptionHtml" gorm:"-"`
+	Priority        string           `json:"priority" gorm:"default:'medium';check:priority IN ('high', 'medium', 'low')"`
+	Labels          Labels           `json:"labels" gorm:"type:text;default:'[]'"`
+	DueDate         *time.Time       `json:"dueDate"`
+	HasDueTime      bool             `json:"hasDueTime" gorm:"default:false"`
+	IsOverdue       bool             `json:"isOverdue" gorm:"-"`
+	IsDueToday      bool             `json:"isDueToday" gorm:"-"`
+	IsCompleted     bool             `json:"isCompleted" gorm:"default:false"`
+	CompletedAt     *time.Time       `json:"completedAt"`
+	Version         int              `json:"version" gorm:"not null;default:1"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt   `json:"-" gorm:"index"`
+	List            List             `json:"-" gorm:"foreignKey:ListID"`
+	UserStatuses    []TodoUserStatus `json:"userStatuses,omitempty" gorm:"foreignKey:TodoID"`
 }
 
 type TodoUserStatus struct {
-	TodoID    uint       `json:"todoId" gorm:"primaryKey"`
-	UserID    string     `json:"userId" gorm:"primaryKey"`
-	IsChecked bool       `json:"isChecked" gorm:"default:false"`
-	CheckedAt *time.Time `json:"checkedAt"`
-	Todo      Todo       `json:"-" gorm:"foreignKey:TodoID"`
-	User      User       `json:"-" gorm:"foreignKey:UserID"`
-}
\ No newline at end of file
+	TodoID     uint       `json:"todoId" gorm:"primaryKey"`
+	UserID     string     `json:"userId" gorm:"primaryKey"`
+	IsChecked  bool       `json:"isChecked" gorm:"default:false"`
+	CheckedAt  *time.Time `json:"checkedAt"`
+	IsAssigned bool       `json:"isAssigned" gorm:"default:false"`
+	ChangedAt  *time.Time `json:"-"`
+	Todo       Todo       `json:"-" gorm:"foreignKey:TodoID"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+}
@@ -198,6 +198,67 @@ func (suite *ModelsTestSuite) TestToDoModelWithNullDueDate() {
 	assert.Nil(suite.T(), retrievedTodo.DueDate)
 }
 
+func (suite *ModelsTestSuite) TestTodoDueState() {
+	tokyo := LoadLocation("Asia/Tokyo")
+	berlin := LoadLocation("Europe/Berlin")
+
+	// 日付のみの期限はどのタイムゾーンでも同じ日付
+	dueDate := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	todo := Todo{DueDate: &dueDate}
+
+	todo.UpdateDueState(time.Date(2025, 6, 10, 23, 30, 0, 0, tokyo), tokyo)
+	assert.True(suite.T(), todo.IsDueToday)
+	assert.False(suite.T(), todo.IsOverdue)
+
+	todo.UpdateDueState(time.Date(2025, 6, 11, 0, 0, 0, 0, tokyo), tokyo)
+	assert.False(suite.T(), todo.IsDueToday)
+	assert.True(suite.T(), todo.IsOverdue)
+
+	// 同じ瞬間でもベルリンではまだ期限の日
+	todo.UpdateDueState(time.Date(2025, 6, 11, 0, 0, 0, 0, tokyo), berlin)
+	assert.True(suite.T(), todo.IsDueToday)
+	assert.False(suite.T(), todo.IsOverdue)
+
+	// 時刻付きの期限は瞬間で判定し、日付はタイムゾーンごとに異なる
+	dueTime := time.Date(2025, 6, 10, 20, 0, 0, 0, time.UTC)
+	timed := Todo{DueDate: &dueTime, HasDueTime: true}
+
+	timed.UpdateDueState(time.Date(2025, 6, 10, 19, 0, 0, 0, time.UTC), berlin)
+	assert.True(suite.T(), timed.IsDueToday)
+	assert.False(suite.T(), timed.IsOverdue)
+
+	// 東京では期限は6月11日05:00なので、6月10日21:00の時点では「今日」ではない
+	timed.UpdateDueState(time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC), tokyo)
+	assert.False(suite.T(), timed.IsDueToday)
+
+	timed.UpdateDueState(time.Date(2025, 6, 10, 20, 0, 0, 0, time.UTC), berlin)
+	assert.True(suite.T(), timed.IsOverdue)
+
+	// 完了済みのToDoは期限切れにならない
+	timed.IsCompleted = true
+	timed.UpdateDueState(time.Date(2025, 6, 12, 0, 0, 0, 0, time.UTC), berlin)
+	assert.False(suite.T(), timed.IsOverdue)
+
+	// 期限なし
+	none := Todo{}
+	none.UpdateDueState(time.Now(), tokyo)
+	assert.False(suite.T(), none.IsDueToday)
+	assert.False(suite.T(), none.IsOverdue)
+	assert.Nil(suite.T(), none.DueAt(tokyo))
+}
+
+func (suite *ModelsTestSuite) TestEffectiveTimeZone() {
+	list := List{TimeZone: "Asia/Tokyo"}
+	assert.Equal(suite.T(), "Asia/Tokyo", EffectiveTimeZone(list, User{}))
+	assert.Equal(suite.T(), "Europe/Berlin", EffectiveTimeZone(list, User{TimeZone: "Europe/Berlin"}))
+	assert.Equal(suite.T(), DefaultTimeZone, EffectiveTimeZone(List{}, User{}))
+
+	assert.True(suite.T(), ValidTimeZone("Asia/Tokyo"))
+	assert.False(suite.T(), ValidTimeZone("Mars/Olympus"))
+	assert.False(suite.T(), ValidTimeZone("Local"))
+	assert.Equal(suite.T(), time.UTC, LoadLocation("Mars/Olympus"))
+}
+
 func TestModelsTestSuite(t *testing.T) {
 	suite.Run(t, new(ModelsTestSuite))
 }
\ No newline at end of file
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification is an in-app notification shown to a user
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	ListID    string     `json:"listId" gorm:"not null;index"`
+	UserID    string     `json:"userId" gorm:"not null;index"`
+	TodoID    *uint      `json:"todoId"`
+	Type      string     `json:"type" gorm:"not null"`
+	Subject   string     `json:"subject" gorm:"not null"`
+	Body      string     `json:"body" gorm:"type:text;default:''"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ReminderLog records a reminder claimed by the scheduler. The unique index makes
+// sure each reminder for a due date is sent only once, even across restarts.
+// ClaimedAt is cleared when a delivery failed so that the next scan retries it,
+// and a claim older than the scheduler's lease is taken over by the next scan.
+type ReminderLog struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TodoID    uint       `json:"todoId" gorm:"not null;uniqueIndex:idx_reminder_once"`
+	UserID    string     `json:"userId" gorm:"not null;uniqueIndex:idx_reminder_once"`
+	Kind      string     `json:"kind" gorm:"not null;uniqueIndex:idx_reminder_once"`
+	DueAt     time.Time  `json:"dueAt" gorm:"not null;uniqueIndex:idx_reminder_once"`
+	ClaimedAt *time.Time `json:"claimedAt"`
+	SentAt    *time.Time `json:"sentAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ReminderDelivery records a reminder delivered through one channel, so that
+// retrying a reminder only sends it through the channels that failed
+type ReminderDelivery struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ReminderLogID uint      `json:"reminderLogId" gorm:"not null;uniqueIndex:idx_reminder_delivery"`
+	Channel       string    `json:"channel" gorm:"not null;uniqueIndex:idx_reminder_delivery"`
+	SentAt        time.Time `json:"sentAt"`
+}
+
+// DigestDelivery records a digest delivered through one channel, so that
+// retrying a digest only sends it through the channels that failed
+type DigestDelivery struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      string    `json:"userId" gorm:"not null;uniqueIndex:idx_digest_delivery"`
+	ScheduledAt time.Time `json:"scheduledAt" gorm:"not null;uniqueIndex:idx_digest_delivery"`
+	Channel     string    `json:"channel" gorm:"not null;uniqueIndex:idx_digest_delivery"`
+	SentAt      time.Time `json:"sentAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Client mutation types accepted by the sync endpoint
+const (
+	MutationTodoCreate  = "todo.create"
+	MutationTodoCheck   = "todo.check"
+	MutationTodoUncheck = "todo.uncheck"
+	MutationMemoEdit    = "memo.edit"
+)
+
+// ClientMutation records a mutation queued by an offline client and applied
+// by the sync endpoint, with the result sent back. The unique index makes sure
+// a mutation sent again by a retried sync is not applied twice.
+type ClientMutation struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	ListID     string          `json:"listId" gorm:"not null;uniqueIndex:idx_client_mutation"`
+	UserID     string          `json:"userId" gorm:"not null;uniqueIndex:idx_client_mutation"`
+	MutationID string          `json:"mutationId" gorm:"not null;uniqueIndex:idx_client_mutation"`
+	Type       string          `json:"type" gorm:"not null"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Result     json.RawMessage `json:"result" gorm:"type:text"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a URL that receives the events of a list
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ListID    string    `json:"listId" gorm:"not null;index"`
+	URL       string    `json:"url" gorm:"not null"`
+	Secret    string    `json:"-" gorm:"not null"`
+	Events    []string  `json:"events" gorm:"serializer:json"`
+	CreatedAt time.Time `json:"createdAt"`
+	List      List      `json:"-" gorm:"foreignKey:ListID"`
+}
+
+// WebhookDelivery is a queued event for a webhook and the log of its delivery attempts
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	WebhookID      uint       `json:"webhookId" gorm:"not null;index"`
+	EventID        string     `json:"eventId" gorm:"not null"`
+	Event          string     `json:"event" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:text;not null"`
+	Status         string     `json:"status" gorm:"not null;default:'pending';index"`
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt" gorm:"index"`
+	ResponseStatus int        `json:"responseStatus" gorm:"default:0"`
+	LastError      string     `json:"lastError" gorm:"default:''"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"shared-todo-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// InApp stores messages as notifications shown in the app
+type InApp struct {
+	DB *gorm.DB
+}
+
+// Notify stores msg for its user
+func (n InApp) Notify(ctx context.Context, msg Message) error {
+	notification := models.Notification{
+		ListID:  msg.List.ID,
+		UserID:  msg.User.ID,
+		Type:    msg.Type,
+		Subject: msg.Subject,
+		Body:    msg.Body,
+	}
+	if msg.Todo != nil {
+		id := msg.Todo.ID
+		notification.TodoID = &id
+	}
+	return n.DB.WithContext(ctx).Create(&notification).Error
+}
@@ -0,0 +1,75 @@
+// Package notify delivers notifications to users through pluggable channels.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"shared-todo-backend/models"
+)
+
+// Notification types
+const (
+	TypeReminder  = "reminder"
+	TypeOverdue   = "overdue"
+	TypeCompleted = "completed"
+	TypeAssigned  = "assigned"
+	TypeDigest    = "digest"
+)
+
+// Message is a notification for a single user
+type Message struct {
+	Type    string
+	List    models.List
+	User    models.User
+	Todo    *models.Todo
+	Subject string
+	Body    string
+	// HTML is an optional HTML version of Body, e.g. a rendered digest. It must
+	// already be escaped; HTML emails use it instead of Body.
+	HTML string
+}
+
+// Notifier delivers messages through one channel
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Func adapts a function to the Notifier interface
+type Func func(ctx context.Context, msg Message) error
+
+// Notify calls f
+func (f Func) Notify(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Multi delivers a message through every notifier. All notifiers are tried even
+// when one of them fails, and the errors are joined.
+type Multi []Notifier
+
+// Notify delivers msg through every notifier
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Async delivers messages in the background so that callers such as request
+// handlers do not wait for slow channels. Errors are logged.
+type Async struct {
+	Notifier Notifier
+}
+
+// Notify starts delivering msg and returns immediately
+func (a Async) Notify(ctx context.Context, msg Message) error {
+	go func() {
+		if err := a.Notifier.Notify(context.Background(), msg); err != nil {
+			log.Printf("Failed to send %s notification to user %s: %v", msg.Type, msg.User.ID, err)
+		}
+	}()
+	return nil
+}
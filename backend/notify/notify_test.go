@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMessage() Message {
+	return Message{
+		Type:    TypeReminder,
+		List:    models.List{ID: "list"},
+		User:    models.User{ID: "alice", ListID: "list"},
+		Todo:    &models.Todo{ID: 7, ListID: "list", Title: "Report"},
+		Subject: "Reminder: Report",
+		Body:    "\"Report\" is due 2025-06-10.",
+	}
+}
+
+func TestMulti(t *testing.T) {
+	var calls []string
+	ok := Func(func(ctx context.Context, msg Message) error {
+		calls = append(calls, "ok")
+		return nil
+	})
+	failing := Func(func(ctx context.Context, msg Message) error {
+		calls = append(calls, "failing")
+		return errors.New("boom")
+	})
+
+	err := Multi{failing, ok}.Notify(context.Background(), testMessage())
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, []string{"failing", "ok"}, calls)
+}
+
+func TestInApp(t *testing.T) {
+	db, err := database.SetupTestDatabase()
+	assert.NoError(t, err)
+
+	assert.NoError(t, InApp{DB: db}.Notify(context.Background(), testMessage()))
+
+	var notification models.Notification
+	assert.NoError(t, db.First(&notification).Error)
+	assert.Equal(t, "alice", notification.UserID)
+	assert.Equal(t, TypeReminder, notification.Type)
+	assert.Equal(t, uint(7), *notification.TodoID)
+	assert.Nil(t, notification.ReadAt)
+}
+
+func TestWebhook(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, Webhook{URL: server.URL}.Notify(context.Background(), testMessage()))
+	assert.Equal(t, "reminder", received["type"])
+	assert.Equal(t, "alice", received["userId"])
+	assert.Equal(t, float64(7), received["todoId"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	assert.EqualError(t, Webhook{URL: failing.URL}.Notify(context.Background(), testMessage()), "webhook returned status 500")
+}
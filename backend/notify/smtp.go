@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"shared-todo-backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSMTPPort is the submission port used when SMTP_PORT is not set
+const DefaultSMTPPort = 587
+
+// smtpTimeout limits a whole SMTP session when the context has no deadline
+const smtpTimeout = 30 * time.Second
+
+// SMTP sends messages by email to users who set an email address and did not
+// turn off email for the message type. STARTTLS is used when the server offers it.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the sender, e.g. "Shared Todo <todo@example.com>"
+	From string
+	// AppURL is the base URL of the app used for links in emails; links are
+	// left out when it is empty
+	AppURL string
+}
+
+// SMTPFromEnv returns the SMTP notifier configured by SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM and APP_URL. It returns nil when
+// SMTP_HOST is not set.
+func SMTPFromEnv() (*SMTP, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, nil
+	}
+
+	n := &SMTP{
+		Host:     host,
+		Port:     DefaultSMTPPort,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		AppURL:   strings.TrimRight(os.Getenv("APP_URL"), "/"),
+	}
+	if s := os.Getenv("SMTP_PORT"); s != "" {
+		port, err := strconv.Atoi(s)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid SMTP_PORT %q", s)
+		}
+		n.Port = port
+	}
+	if _, err := mail.ParseAddress(n.From); err != nil {
+		return nil, fmt.Errorf("invalid SMTP_FROM %q: %w", n.From, err)
+	}
+	return n, nil
+}
+
+// WantsEmail reports whether user receives messages of the given type by email
+func WantsEmail(user models.User, kind string) bool {
+	if user.Email == "" {
+		return false
+	}
+	switch kind {
+	case TypeCompleted:
+		return user.EmailOnComplete
+	case TypeAssigned:
+		return user.EmailOnAssign
+	case TypeDigest:
+		return user.EmailDigest
+	}
+	return false
+}
+
+// Notify emails msg to its user
+func (n *SMTP) Notify(ctx context.Context, msg Message) error {
+	if !WantsEmail(msg.User, msg.Type) {
+		return nil
+	}
+
+	from, err := mail.ParseAddress(n.From)
+	if err != nil {
+		return err
+	}
+	data, err := n.compose(msg, from, time.Now())
+	if err != nil {
+		return err
+	}
+	return n.send(ctx, from.Address, msg.User.Email, data)
+}
+
+// compose builds a multipart/alternative email with a plain text and an HTML body
+func (n *SMTP) compose(msg Message, from *mail.Address, now time.Time) ([]byte, error) {
+	content := newEmailContent(msg, n.AppURL)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		render      func(*bytes.Buffer) error
+	}{
+		{"text/plain; charset=UTF-8", func(buf *bytes.Buffer) error { return textTemplate.Execute(buf, content) }},
+		{"text/html; charset=UTF-8", func(buf *bytes.Buffer) error { return htmlTemplate.Execute(buf, content) }},
+	}
+	for _, part := range parts {
+		var rendered bytes.Buffer
+		if err := part.render(&rendered); err != nil {
+			return nil, err
+		}
+
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(rendered.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	to := mail.Address{Name: msg.User.DisplayName, Address: msg.User.Email}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var out bytes.Buffer
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("UTF-8", msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + uuid.New().String() + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&out, "%s: %s\r\n", h[0], h[1])
+	}
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// send delivers data in a single SMTP session
+func (n *SMTP) send(ctx context.Context, from, to string, data []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.Host, strconv.Itoa(n.Port)))
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
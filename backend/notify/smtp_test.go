@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify/smtptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func emailMessage() Message {
+	return Message{
+		Type:    TypeCompleted,
+		List:    models.List{ID: "list"},
+		User:    models.User{ID: "alice", ListID: "list", DisplayName: "Alice", Email: "alice@example.com", EmailOnComplete: true},
+		Todo:    &models.Todo{ID: 7, ListID: "list", Title: "Report <draft>", Description: "Send **before** noon"},
+		Subject: "Completed: 報告書",
+		Body:    "\"Report <draft>\" was checked by everyone and is now complete.",
+	}
+}
+
+// readParts parses a received email and returns its bodies by content type
+func readParts(t *testing.T, data []byte) (*mail.Message, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+	return msg, parts
+}
+
+func TestSMTP(t *testing.T) {
+	server, err := smtptest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	server.RequireAuth("user", "secret")
+
+	n := &SMTP{
+		Host:     server.Host(),
+		Port:     server.Port(),
+		Username: "user",
+		Password: "secret",
+		From:     "Shared Todo <todo@example.com>",
+		AppURL:   "https://todo.example.com",
+	}
+	require.NoError(t, n.Notify(context.Background(), emailMessage()))
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "todo@example.com", messages[0].From)
+	assert.Equal(t, []string{"alice@example.com"}, messages[0].To)
+
+	msg, parts := readParts(t, messages[0].Data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.Equal(t, "Completed: 報告書", subject)
+	assert.Equal(t, `"Alice" <alice@example.com>`, msg.Header.Get("To"))
+
+	assert.Contains(t, parts["text/plain"], "Hi Alice,")
+	assert.Contains(t, parts["text/plain"], "Send **before** noon")
+	assert.Contains(t, parts["text/plain"], "Open the list: https://todo.example.com/list/alice")
+
+	// HTMLではメッセージをエスケープし、説明はMarkdownをレンダリングする
+	assert.Contains(t, parts["text/html"], "&#34;Report &lt;draft&gt;&#34; was checked")
+	assert.Contains(t, parts["text/html"], "<strong>before</strong>")
+	assert.Contains(t, parts["text/html"], `<a href="https://todo.example.com/list/alice">`)
+}
+
+func TestSMTPAuthFailure(t *testing.T) {
+	server, err := smtptest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	server.RequireAuth("user", "secret")
+
+	n := &SMTP{Host: server.Host(), Port: server.Port(), Username: "user", Password: "wrong", From: "todo@example.com"}
+	assert.Error(t, n.Notify(context.Background(), emailMessage()))
+	assert.Empty(t, server.Messages())
+}
+
+func TestSMTPSkipsUnwantedMessages(t *testing.T) {
+	server, err := smtptest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	n := &SMTP{Host: server.Host(), Port: server.Port(), From: "todo@example.com"}
+
+	noEmail := emailMessage()
+	noEmail.User.Email = ""
+	optedOut := emailMessage()
+	optedOut.User.EmailOnComplete = false
+	reminder := emailMessage()
+	reminder.Type = TypeReminder
+
+	for _, msg := range []Message{noEmail, optedOut, reminder} {
+		assert.NoError(t, n.Notify(context.Background(), msg))
+	}
+	assert.Empty(t, server.Messages())
+}
+
+func TestSMTPFromEnv(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	n, err := SMTPFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, n)
+
+	t.Setenv("SMTP_HOST", "mail.example.com")
+	t.Setenv("SMTP_FROM", "Shared Todo <todo@example.com>")
+	t.Setenv("APP_URL", "https://todo.example.com/")
+	n, err = SMTPFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSMTPPort, n.Port)
+	assert.Equal(t, "https://todo.example.com", n.AppURL)
+
+	t.Setenv("SMTP_PORT", "abc")
+	_, err = SMTPFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("SMTP_PORT", "25")
+	t.Setenv("SMTP_FROM", "")
+	_, err = SMTPFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+// Package smtptest provides a minimal in-memory SMTP server for tests. It
+// accepts every message, optionally requires AUTH PLAIN, and records what it
+// received.
+package smtptest
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is a message received by the server
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Server is a running SMTP server listening on the loopback interface
+type Server struct {
+	// Addr is the host:port of the server
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	username string
+	password string
+	messages []Message
+}
+
+// NewServer starts a server on a random loopback port. Call Close when done.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{Addr: listener.Addr().String(), listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Host returns the host of the server
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+// Port returns the port of the server
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr)
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// RequireAuth makes the server reject mail from clients that did not
+// authenticate with the given credentials
+func (s *Server) RequireAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// Messages returns the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the server and waits for open sessions to end
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle runs one SMTP session
+func (s *Server) handle(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	s.mu.Lock()
+	username, password := s.username, s.password
+	s.mu.Unlock()
+
+	authenticated := username == ""
+	var current *Message
+
+	tp.PrintfLine("220 smtptest ESMTP ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if username != "" {
+				tp.PrintfLine("250-smtptest")
+				tp.PrintfLine("250-8BITMIME")
+				tp.PrintfLine("250 AUTH PLAIN")
+			} else {
+				tp.PrintfLine("250-smtptest")
+				tp.PrintfLine("250 8BITMIME")
+			}
+		case "HELO":
+			tp.PrintfLine("250 smtptest")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				tp.PrintfLine("504 unsupported mechanism")
+				continue
+			}
+			if initial == "" {
+				tp.PrintfLine("334 ")
+				if initial, err = tp.ReadLine(); err != nil {
+					return
+				}
+			}
+			if checkPlain(initial, username, password) {
+				authenticated = true
+				tp.PrintfLine("235 authenticated")
+			} else {
+				tp.PrintfLine("535 invalid credentials")
+			}
+		case "MAIL":
+			if !authenticated {
+				tp.PrintfLine("530 authentication required")
+				continue
+			}
+			current = &Message{From: address(arg)}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			if current == nil {
+				tp.PrintfLine("503 need MAIL first")
+				continue
+			}
+			current.To = append(current.To, address(arg))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			if current == nil || len(current.To) == 0 {
+				tp.PrintfLine("503 need RCPT first")
+				continue
+			}
+			tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			current.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, *current)
+			s.mu.Unlock()
+			current = nil
+			tp.PrintfLine("250 ok")
+		case "RSET":
+			current = nil
+			tp.PrintfLine("250 ok")
+		case "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// address extracts the address from a "FROM:<addr>" or "TO:<addr>" argument
+func address(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.LastIndex(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// checkPlain validates an AUTH PLAIN response
+func checkPlain(response, username, password string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	return len(parts) == 3 && parts[1] == username && parts[2] == password
+}
@@ -0,0 +1,74 @@
+package notify
+
+import (
+	htmltemplate "html/template"
+	"shared-todo-backend/markdown"
+	"strings"
+	texttemplate "text/template"
+)
+
+// emailContent is the data passed to the email templates
+type emailContent struct {
+	Name    string
+	Subject string
+	Body    string
+	// BodyHTML is the already escaped HTML version of Body, if any
+	BodyHTML htmltemplate.HTML
+	// Title and Description are those of the todo of the message;
+	// DescriptionHTML is the rendered Markdown description
+	Title           string
+	Description     string
+	DescriptionHTML htmltemplate.HTML
+	URL             string
+}
+
+func newEmailContent(msg Message, appURL string) emailContent {
+	content := emailContent{
+		Name:     msg.User.DisplayName,
+		Subject:  msg.Subject,
+		Body:     msg.Body,
+		BodyHTML: htmltemplate.HTML(msg.HTML),
+	}
+	if msg.Todo != nil {
+		content.Title = msg.Todo.Title
+		content.Description = strings.TrimSpace(msg.Todo.Description)
+		// markdown.Render はエスケープ済みの安全なHTMLを返す
+		content.DescriptionHTML = htmltemplate.HTML(markdown.Render(msg.Todo.Description))
+	}
+	if appURL != "" && msg.List.ID != "" && msg.User.ID != "" {
+		content.URL = appURL + "/" + msg.List.ID + "/" + msg.User.ID
+	}
+	return content
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{if .Name}}Hi {{.Name}},{{else}}Hi,{{end}}
+
+{{.Body}}
+{{- if .Description}}
+
+{{.Description}}
+{{- end}}
+{{- if .URL}}
+
+Open the list: {{.URL}}
+{{- end}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Subject}}</title>
+</head>
+<body style="font-family: sans-serif; line-height: 1.5; color: #222;">
+<p>{{if .Name}}Hi {{.Name}},{{else}}Hi,{{end}}</p>
+{{if .BodyHTML}}{{.BodyHTML}}{{else}}<p>{{.Body}}</p>{{end}}
+{{- if .DescriptionHTML}}
+<div style="border-left: 3px solid #ddd; padding-left: 12px; color: #555;">{{.DescriptionHTML}}</div>
+{{- end}}
+{{- if .URL}}
+<p><a href="{{.URL}}">Open the list</a></p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook posts messages as JSON to a fixed URL
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the JSON body posted by Webhook
+type webhookPayload struct {
+	Type    string      `json:"type"`
+	ListID  string      `json:"listId"`
+	UserID  string      `json:"userId"`
+	TodoID  *uint       `json:"todoId,omitempty"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	SentAt  time.Time   `json:"sentAt"`
+	Todo    interface{} `json:"todo,omitempty"`
+}
+
+// Notify posts msg to the webhook URL
+func (n Webhook) Notify(ctx context.Context, msg Message) error {
+	payload := webhookPayload{
+		Type:    msg.Type,
+		ListID:  msg.List.ID,
+		UserID:  msg.User.ID,
+		Subject: msg.Subject,
+		Body:    msg.Body,
+		SentAt:  time.Now().UTC(),
+	}
+	if msg.Todo != nil {
+		id := msg.Todo.ID
+		payload.TodoID = &id
+		payload.Todo = msg.Todo
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
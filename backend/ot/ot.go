@@ -0,0 +1,310 @@
+// Package ot implements operational transformation for plain text, used to
+// merge concurrent edits of the list memo.
+//
+// An operation walks over a whole text and is a sequence of components:
+// retain n characters, insert a string, or delete n characters. Lengths count
+// Unicode code points. In JSON an operation is an array in which a positive
+// integer retains, a negative integer deletes and a string inserts, the format
+// used by ot.js:
+//
+//	[5, "inserted", -3, 10]
+//
+// The server applies operations in the order it receives them. An operation
+// based on an older version of the text is transformed against the operations
+// applied since, so that it keeps its intent.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLength is the length of the longest text an operation may span or
+// produce, the limit of the memo. Decoding rejects longer operations, so that
+// lengths cannot overflow.
+const MaxLength = 5000
+
+// ErrBaseLength is returned when an operation does not span the text it is
+// applied or transformed against
+var ErrBaseLength = errors.New("operation length does not match the text")
+
+// Component is a single step of an operation. Exactly one of its fields is set.
+type Component struct {
+	Retain int
+	Insert string
+	Delete int
+}
+
+// Operation is an edit of a whole text. Build it with Retain, Insert and Delete,
+// which keep it in canonical form: adjacent components of the same kind are
+// merged and an insert always comes before an adjacent delete.
+type Operation struct {
+	Components []Component
+	// BaseLen is the length of the text the operation applies to
+	BaseLen int
+	// TargetLen is the length of the text after applying the operation
+	TargetLen int
+}
+
+// Retain appends a component keeping the next n characters
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLen += n
+	o.TargetLen += n
+	if last := o.last(); last != nil && last.Retain > 0 {
+		last.Retain += n
+	} else {
+		o.Components = append(o.Components, Component{Retain: n})
+	}
+	return o
+}
+
+// Insert appends a component inserting s
+func (o *Operation) Insert(s string) *Operation {
+	if s == "" {
+		return o
+	}
+	o.TargetLen += utf8.RuneCountInString(s)
+
+	last := o.last()
+	switch {
+	case last != nil && last.Insert != "":
+		last.Insert += s
+	case last != nil && last.Delete > 0:
+		// 挿入と削除が隣接する場合は挿入を先に置く
+		n := len(o.Components)
+		if n > 1 && o.Components[n-2].Insert != "" {
+			o.Components[n-2].Insert += s
+		} else {
+			deleted := *last
+			o.Components = append(o.Components[:n-1], Component{Insert: s}, deleted)
+		}
+	default:
+		o.Components = append(o.Components, Component{Insert: s})
+	}
+	return o
+}
+
+// Delete appends a component deleting the next n characters
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLen += n
+	if last := o.last(); last != nil && last.Delete > 0 {
+		last.Delete += n
+	} else {
+		o.Components = append(o.Components, Component{Delete: n})
+	}
+	return o
+}
+
+func (o *Operation) last() *Component {
+	if len(o.Components) == 0 {
+		return nil
+	}
+	return &o.Components[len(o.Components)-1]
+}
+
+// IsNoop reports whether the operation leaves the text unchanged
+func (o Operation) IsNoop() bool {
+	return len(o.Components) == 0 || (len(o.Components) == 1 && o.Components[0].Retain > 0)
+}
+
+// Apply returns the text after applying the operation to s
+func (o Operation) Apply(s string) (string, error) {
+	runes := []rune(s)
+	if len(runes) != o.BaseLen {
+		return "", ErrBaseLength
+	}
+
+	var sb strings.Builder
+	i := 0
+	for _, c := range o.Components {
+		switch {
+		case c.Retain > 0:
+			if c.Retain > len(runes)-i {
+				return "", ErrBaseLength
+			}
+			sb.WriteString(string(runes[i : i+c.Retain]))
+			i += c.Retain
+		case c.Insert != "":
+			sb.WriteString(c.Insert)
+		default:
+			if c.Delete > len(runes)-i {
+				return "", ErrBaseLength
+			}
+			i += c.Delete
+		}
+	}
+	return sb.String(), nil
+}
+
+// Diff returns an operation turning a into b. It keeps their common prefix and
+// suffix and replaces the rest.
+func Diff(a, b string) Operation {
+	ra, rb := []rune(a), []rune(b)
+	prefix := 0
+	for prefix < len(ra) && prefix < len(rb) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(ra)-prefix && suffix < len(rb)-prefix && ra[len(ra)-1-suffix] == rb[len(rb)-1-suffix] {
+		suffix++
+	}
+
+	var o Operation
+	o.Retain(prefix)
+	o.Insert(string(rb[prefix : len(rb)-suffix]))
+	o.Delete(len(ra) - prefix - suffix)
+	o.Retain(suffix)
+	return o
+}
+
+// Transform takes two operations a and b made concurrently on the same text
+// and returns a' and b' such that applying a then b' gives the same text as
+// applying b then a'. When both insert at the same position, the insert of a
+// comes first.
+func Transform(a, b Operation) (Operation, Operation, error) {
+	var aPrime, bPrime Operation
+	if a.BaseLen != b.BaseLen {
+		return aPrime, bPrime, ErrBaseLength
+	}
+
+	ia, ib := newIterator(a), newIterator(b)
+	for ia.cur != nil || ib.cur != nil {
+		// 挿入は相手の操作に関係なくそのまま残し、相手側では保持する
+		if ia.cur != nil && ia.cur.Insert != "" {
+			aPrime.Insert(ia.cur.Insert)
+			bPrime.Retain(utf8.RuneCountInString(ia.cur.Insert))
+			ia.next()
+			continue
+		}
+		if ib.cur != nil && ib.cur.Insert != "" {
+			aPrime.Retain(utf8.RuneCountInString(ib.cur.Insert))
+			bPrime.Insert(ib.cur.Insert)
+			ib.next()
+			continue
+		}
+		if ia.cur == nil || ib.cur == nil {
+			return aPrime, bPrime, ErrBaseLength
+		}
+
+		n := min(ia.length(), ib.length())
+		switch {
+		case ia.cur.Retain > 0 && ib.cur.Retain > 0:
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+		case ia.cur.Delete > 0 && ib.cur.Retain > 0:
+			aPrime.Delete(n)
+		case ia.cur.Retain > 0 && ib.cur.Delete > 0:
+			bPrime.Delete(n)
+		}
+		// 両方が削除した部分はどちらの変換後の操作にも残らない
+		ia.consume(n)
+		ib.consume(n)
+	}
+	return aPrime, bPrime, nil
+}
+
+// iterator walks over the components of an operation, splitting retains and
+// deletes as they are consumed
+type iterator struct {
+	rest []Component
+	cur  *Component
+}
+
+func newIterator(o Operation) *iterator {
+	it := &iterator{rest: o.Components}
+	it.next()
+	return it
+}
+
+func (it *iterator) next() {
+	if len(it.rest) == 0 {
+		it.cur = nil
+		return
+	}
+	c := it.rest[0]
+	it.rest = it.rest[1:]
+	it.cur = &c
+}
+
+// length returns the number of base characters the current retain or delete spans
+func (it *iterator) length() int {
+	if it.cur.Retain > 0 {
+		return it.cur.Retain
+	}
+	return it.cur.Delete
+}
+
+// consume removes n base characters from the current retain or delete
+func (it *iterator) consume(n int) {
+	if it.length() > n {
+		if it.cur.Retain > 0 {
+			it.cur.Retain -= n
+		} else {
+			it.cur.Delete -= n
+		}
+		return
+	}
+	it.next()
+}
+
+// MarshalJSON encodes the operation in the ot.js array format
+func (o Operation) MarshalJSON() ([]byte, error) {
+	values := make([]interface{}, 0, len(o.Components))
+	for _, c := range o.Components {
+		switch {
+		case c.Retain > 0:
+			values = append(values, c.Retain)
+		case c.Insert != "":
+			values = append(values, c.Insert)
+		default:
+			values = append(values, -c.Delete)
+		}
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON decodes an operation in the ot.js array format
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	*o = Operation{}
+	for _, value := range values {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			if s == "" {
+				return errors.New("insert must not be empty")
+			}
+			o.Insert(s)
+			if o.TargetLen > MaxLength {
+				return fmt.Errorf("operation must span at most %d characters", MaxLength)
+			}
+			continue
+		}
+
+		var n int
+		if err := json.Unmarshal(value, &n); err != nil || n == 0 || n > MaxLength || n < -MaxLength {
+			return fmt.Errorf("invalid operation component %s", value)
+		}
+		if n > 0 {
+			o.Retain(n)
+		} else {
+			o.Delete(-n)
+		}
+		if o.BaseLen > MaxLength || o.TargetLen > MaxLength {
+			return fmt.Errorf("operation must span at most %d characters", MaxLength)
+		}
+	}
+	return nil
+}
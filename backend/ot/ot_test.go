@@ -0,0 +1,159 @@
+package ot
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func op(t *testing.T, s string) Operation {
+	var o Operation
+	require.NoError(t, json.Unmarshal([]byte(s), &o))
+	return o
+}
+
+func TestApply(t *testing.T) {
+	o := op(t, `[2, "X", -1, 2]`)
+	assert.Equal(t, 5, o.BaseLen)
+	assert.Equal(t, 5, o.TargetLen)
+
+	result, err := o.Apply("abcde")
+	assert.NoError(t, err)
+	assert.Equal(t, "abXde", result)
+
+	// 長さはコードポイント単位で数える
+	result, err = op(t, `[1, -1, "り", 1]`).Apply("牛乳パ")
+	assert.NoError(t, err)
+	assert.Equal(t, "牛りパ", result)
+
+	_, err = o.Apply("abc")
+	assert.ErrorIs(t, err, ErrBaseLength)
+}
+
+func TestCanonicalForm(t *testing.T) {
+	var o Operation
+	o.Retain(1).Retain(2).Delete(1).Insert("a").Delete(2).Insert("b")
+	data, err := json.Marshal(o)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[3, "ab", -3]`, string(data))
+
+	assert.True(t, op(t, `[4]`).IsNoop())
+	assert.False(t, op(t, `[4, "a"]`).IsNoop())
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	for _, s := range []string{`[0]`, `[""]`, `[1.5]`, `[true]`, `{"retain": 1}`} {
+		var o Operation
+		assert.Error(t, json.Unmarshal([]byte(s), &o), s)
+	}
+
+	// 長さが溢れる操作は受け付けない
+	long := `"` + strings.Repeat("a", MaxLength+1) + `"`
+	for _, s := range []string{`[9223372036854775807, "x", 9223372036854775807, 4]`, `[-9223372036854775808]`, `[5001]`, `[3000, 3000]`, `[-3000, -3000]`, `[` + long + `]`} {
+		var o Operation
+		assert.Error(t, json.Unmarshal([]byte(s), &o), s)
+	}
+}
+
+func TestApplyOutOfRange(t *testing.T) {
+	o := Operation{Components: []Component{{Retain: 5}, {Delete: 3}}, BaseLen: 2}
+	_, err := o.Apply("ab")
+	assert.ErrorIs(t, err, ErrBaseLength)
+
+	o = Operation{Components: []Component{{Delete: 5}}, BaseLen: 2}
+	_, err = o.Apply("ab")
+	assert.ErrorIs(t, err, ErrBaseLength)
+}
+
+func TestDiff(t *testing.T) {
+	cases := [][2]string{
+		{"", ""},
+		{"", "new"},
+		{"old", ""},
+		{"milk\neggs\nrice", "milk\nbread\nrice"},
+		{"aaa", "aaaa"},
+		{"買い物", "買い物リスト"},
+	}
+	for _, c := range cases {
+		o := Diff(c[0], c[1])
+		result, err := o.Apply(c[0])
+		assert.NoError(t, err)
+		assert.Equal(t, c[1], result)
+	}
+
+	data, _ := json.Marshal(Diff("milk\neggs\nrice", "milk\nbread\nrice"))
+	assert.JSONEq(t, `[5, "bread", -4, 5]`, string(data))
+}
+
+func TestTransform(t *testing.T) {
+	doc := "milk eggs"
+	a := op(t, `[4, " tea", 5]`)   // "milk tea eggs"
+	b := op(t, `[5, -4, "bread"]`) // "milk bread"
+
+	aPrime, bPrime, err := Transform(a, b)
+	assert.NoError(t, err)
+
+	ab, _ := a.Apply(doc)
+	ab, _ = bPrime.Apply(ab)
+	ba, _ := b.Apply(doc)
+	ba, _ = aPrime.Apply(ba)
+	assert.Equal(t, "milk tea bread", ab)
+	assert.Equal(t, ab, ba)
+
+	// 同じ位置への挿入は a が先になる
+	aPrime, _, err = Transform(op(t, `["A", 1]`), op(t, `["B", 1]`))
+	assert.NoError(t, err)
+	result, _ := aPrime.Apply("Bx")
+	assert.Equal(t, "ABx", result)
+
+	_, _, err = Transform(op(t, `[1]`), op(t, `[2]`))
+	assert.ErrorIs(t, err, ErrBaseLength)
+}
+
+// randomOperation returns a random operation applicable to doc
+func randomOperation(r *rand.Rand, doc string) Operation {
+	var o Operation
+	remaining := len([]rune(doc))
+	for remaining > 0 {
+		n := r.Intn(remaining) + 1
+		switch r.Intn(3) {
+		case 0:
+			o.Retain(n)
+			remaining -= n
+		case 1:
+			o.Delete(n)
+			remaining -= n
+		default:
+			o.Insert(string([]rune("abcあい\n")[r.Intn(6)]))
+		}
+	}
+	if r.Intn(2) == 0 {
+		o.Insert("z")
+	}
+	return o
+}
+
+func TestTransformConverges(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		doc := string([]rune("買い物 milk eggs\nrice")[:r.Intn(18)])
+		a, b := randomOperation(r, doc), randomOperation(r, doc)
+
+		aPrime, bPrime, err := Transform(a, b)
+		require.NoError(t, err)
+
+		ab, err := a.Apply(doc)
+		require.NoError(t, err)
+		ab, err = bPrime.Apply(ab)
+		require.NoError(t, err)
+		ba, err := b.Apply(doc)
+		require.NoError(t, err)
+		ba, err = aPrime.Apply(ba)
+		require.NoError(t, err)
+		require.Equal(t, ab, ba, "doc %q a %v b %v", doc, a.Components, b.Components)
+	}
+}
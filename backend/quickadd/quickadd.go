@@ -0,0 +1,280 @@
+// Package quickadd parses a todo written as a single line of free text, such
+// as "Buy milk tomorrow !high #groceries @alice", into its title, due date,
+// priority, labels and assignees. Due dates may be written in English or
+// Japanese and are relative to the time the todo is added.
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is a parsed todo. Fields that were not given are empty.
+type Result struct {
+	Title string
+	// DueDate is "2006-01-02", or "2006-01-02T15:04" when a time of day was given
+	DueDate  string
+	Priority string
+	Labels   []string
+	// Mentions are the names given with @, which name the assignees
+	Mentions []string
+}
+
+// normalizer turns full-width digits and symbols, as typed with a Japanese
+// input method, into their ASCII forms
+var normalizer = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"！", "!", "＃", "#", "＠", "@", "：", ":", "／", "/", "　", " ",
+)
+
+var priorities = map[string]string{
+	"high": "high", "h": "high", "1": "high", "高": "high",
+	"medium": "medium", "med": "medium", "m": "medium", "2": "medium", "中": "medium",
+	"low": "low", "l": "low", "3": "low", "低": "low",
+}
+
+// Parse parses text, resolving relative dates against now in its location.
+// The words left after taking out the date, the !priority, the #labels and the
+// @mentions make up the title.
+func Parse(text string, now time.Time) Result {
+	var result Result
+	var words []string
+	for _, word := range strings.Fields(normalizer.Replace(text)) {
+		switch {
+		case len(word) > 1 && word[0] == '!' && priorities[strings.ToLower(word[1:])] != "":
+			if result.Priority == "" {
+				result.Priority = priorities[strings.ToLower(word[1:])]
+			}
+		case len(word) > 1 && word[0] == '#':
+			result.Labels = appendUnique(result.Labels, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			result.Mentions = appendUnique(result.Mentions, word[1:])
+		default:
+			words = append(words, word)
+		}
+	}
+
+	rest := strings.Join(words, " ")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	date, rest, hasDate := match(dateRules, rest, today)
+	at, rest, hasTime := match(timeRules, rest, today)
+	switch {
+	case hasDate && hasTime:
+		result.DueDate = time.Date(date.Year(), date.Month(), date.Day(), at.Hour(), at.Minute(), 0, 0, now.Location()).Format("2006-01-02T15:04")
+	case hasDate:
+		result.DueDate = date.Format("2006-01-02")
+	case hasTime:
+		// 時刻だけなら、その時刻がまだ来ていなければ今日、過ぎていれば明日
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		result.DueDate = at.Format("2006-01-02T15:04")
+	}
+
+	result.Title = strings.Join(strings.Fields(rest), " ")
+	return result
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// rule resolves an expression matched by re to a time. For a date rule only
+// the date of the result is used, and for a time rule only the time of day.
+type rule struct {
+	re      *regexp.Regexp
+	resolve func(m []string, today time.Time) (time.Time, bool)
+}
+
+// match applies the first of rules that matches s and resolves, and returns
+// its result and s without the matched expression
+func match(rules []rule, s string, today time.Time) (time.Time, string, bool) {
+	for _, r := range rules {
+		for _, loc := range r.re.FindAllStringSubmatchIndex(s, -1) {
+			m := make([]string, len(loc)/2)
+			for i := range m {
+				if loc[2*i] >= 0 {
+					m[i] = s[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			if t, ok := r.resolve(m, today); ok {
+				return t, s[:loc[0]] + s[loc[1]:], true
+			}
+		}
+	}
+	return time.Time{}, s, false
+}
+
+const (
+	// enPrefix and jaSuffix are the words around a due date that are taken out with it
+	enPrefix = `(?:(?:on|by|due)\s+)?`
+	jaSuffix = `(?:までに|まで)?`
+
+	enWeekday      = `(monday|tuesday|wednesday|thursday|friday|saturday|sunday)`
+	enShortWeekday = `(monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thu|friday|fri|saturday|sat|sunday|sun)`
+	jaWeekday      = `([月火水木金土日])曜日?`
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday, "日": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday, "月": time.Monday,
+	"tuesday": time.Tuesday, "tues": time.Tuesday, "tue": time.Tuesday, "火": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "水": time.Wednesday,
+	"thursday": time.Thursday, "thurs": time.Thursday, "thu": time.Thursday, "木": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "金": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "土": time.Saturday,
+}
+
+// dateRules are tried in order, so that a longer expression is matched before
+// the shorter one it contains, e.g. 再来週 before 来週.
+var dateRules = []rule{
+	{regexp.MustCompile(`(?i)\b` + enPrefix + `(\d{4})-(\d{1,2})-(\d{1,2})\b`), absoluteDate},
+	{regexp.MustCompile(`(\d{4})年(\d{1,2})月(\d{1,2})日` + jaSuffix), absoluteDate},
+	{regexp.MustCompile(`()(\d{1,2})月(\d{1,2})日` + jaSuffix), absoluteDate},
+	{regexp.MustCompile(`(?i)\b` + enPrefix + `()(\d{1,2})/(\d{1,2})\b` + jaSuffix), absoluteDate},
+
+	{regexp.MustCompile(`(?i)\b` + enPrefix + `(the\s+)?day\s+after\s+tomorrow\b|(明後日|あさって)` + jaSuffix), days(2)},
+	{regexp.MustCompile(`(?i)\b` + enPrefix + `(today|tonight)\b|(今日|きょう|今夜)` + jaSuffix), days(0)},
+	{regexp.MustCompile(`(?i)\b` + enPrefix + `(tomorrow|tmrw)\b|(明日|あした)` + jaSuffix), days(1)},
+	{regexp.MustCompile(`(?i)\bin\s+(\d+)\s+(day|week|month)s?\b`), inPeriod},
+	{regexp.MustCompile(`(\d+)(日|週間|か月|ヶ月|カ月)後` + jaSuffix), inPeriod},
+
+	{regexp.MustCompile(`(?i)\b` + enPrefix + `(next|this)\s+` + enShortWeekday + `\b`), weekdayOfWeek},
+	{regexp.MustCompile(`(再来週|来週|今週)の?` + jaWeekday + jaSuffix), weekdayOfWeek},
+	{regexp.MustCompile(`(?i)\b(next)\s+(week)\b|(再来週|来週)` + jaSuffix), startOfWeek},
+	{regexp.MustCompile(`(?i)\b` + enPrefix + enWeekday + `\b`), nextWeekday},
+	{regexp.MustCompile(`(?i)\b(?:on|by|due)\s+` + enShortWeekday + `\b`), nextWeekday},
+	{regexp.MustCompile(jaWeekday + jaSuffix), nextWeekday},
+}
+
+// timeRules are tried in order after the date was taken out
+var timeRules = []rule{
+	{regexp.MustCompile(`(?i)\b(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b`), clockTime},
+	{regexp.MustCompile(`(?i)\b(?:at\s+)?(\d{1,2}):(\d{2})()\b`), clockTime},
+	{regexp.MustCompile(`(午前|午後)?(\d{1,2})時(?:(\d{1,2})分|(半))?` + jaSuffix), japaneseTime},
+	{regexp.MustCompile(`(?i)\b(?:at\s+)?(noon)\b|(正午)` + jaSuffix), func(m []string, today time.Time) (time.Time, bool) {
+		return at(today, 12, 0)
+	}},
+}
+
+func absoluteDate(m []string, today time.Time) (time.Time, bool) {
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	year := today.Year()
+	if m[1] != "" {
+		year, _ = strconv.Atoi(m[1])
+	}
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	if d.Month() != time.Month(month) || d.Day() != day {
+		return time.Time{}, false
+	}
+	// 年がなければ次に来るその日
+	if m[1] == "" && d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d, true
+}
+
+func days(n int) func(m []string, today time.Time) (time.Time, bool) {
+	return func(m []string, today time.Time) (time.Time, bool) {
+		return today.AddDate(0, 0, n), true
+	}
+}
+
+func inPeriod(m []string, today time.Time) (time.Time, bool) {
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n > 3650 {
+		return time.Time{}, false
+	}
+	switch strings.ToLower(m[2]) {
+	case "day", "日":
+		return today.AddDate(0, 0, n), true
+	case "week", "週間":
+		return today.AddDate(0, 0, 7*n), true
+	default:
+		return today.AddDate(0, n, 0), true
+	}
+}
+
+// monday returns the Monday of the week of today, weeks weeks later
+func monday(today time.Time, weeks int) time.Time {
+	return today.AddDate(0, 0, 7*weeks-(int(today.Weekday())+6)%7)
+}
+
+// weekdayOfWeek resolves a weekday of this week, the next week or the week
+// after. A day of this week that has passed is the one of the next week.
+func weekdayOfWeek(m []string, today time.Time) (time.Time, bool) {
+	weeks := 0
+	switch strings.ToLower(m[1]) {
+	case "next", "来週":
+		weeks = 1
+	case "再来週":
+		weeks = 2
+	}
+	d := monday(today, weeks).AddDate(0, 0, (int(weekdays[strings.ToLower(m[2])])+6)%7)
+	if d.Before(today) {
+		d = d.AddDate(0, 0, 7)
+	}
+	return d, true
+}
+
+// startOfWeek resolves "next week", 来週 and 再来週 to the Monday of that week
+func startOfWeek(m []string, today time.Time) (time.Time, bool) {
+	if m[3] == "再来週" {
+		return monday(today, 2), true
+	}
+	return monday(today, 1), true
+}
+
+// nextWeekday resolves a weekday to the next one after today
+func nextWeekday(m []string, today time.Time) (time.Time, bool) {
+	n := (int(weekdays[strings.ToLower(m[1])]) - int(today.Weekday()) + 7) % 7
+	if n == 0 {
+		n = 7
+	}
+	return today.AddDate(0, 0, n), true
+}
+
+func clockTime(m []string, today time.Time) (time.Time, bool) {
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	switch strings.ToLower(m[3]) {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return time.Time{}, false
+		}
+		hour %= 12
+		if strings.ToLower(m[3]) == "pm" {
+			hour += 12
+		}
+	}
+	return at(today, hour, minute)
+}
+
+func japaneseTime(m []string, today time.Time) (time.Time, bool) {
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	if m[4] != "" {
+		minute = 30
+	}
+	if m[1] == "午後" && hour < 12 {
+		hour += 12
+	}
+	return at(today, hour, minute)
+}
+
+func at(today time.Time, hour, minute int) (time.Time, bool) {
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+	return time.Date(today.Year(), today.Month(), today.Day(), hour, minute, 0, 0, today.Location()), true
+}
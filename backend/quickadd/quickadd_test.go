@@ -0,0 +1,103 @@
+package quickadd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 2024-05-15 は水曜日
+var now = time.Date(2024, 5, 15, 10, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+
+func TestParse(t *testing.T) {
+	result := Parse("Buy milk tomorrow !high #groceries @alice", now)
+	assert.Equal(t, Result{
+		Title:    "Buy milk",
+		DueDate:  "2024-05-16",
+		Priority: "high",
+		Labels:   []string{"groceries"},
+		Mentions: []string{"alice"},
+	}, result)
+
+	// 全角の記号や数字も使える
+	result = Parse("！高　＃買い物 ＃買い物 ＠太郎 牛乳 !low", now)
+	assert.Equal(t, Result{
+		Title:    "牛乳",
+		Priority: "high",
+		Labels:   []string{"買い物"},
+		Mentions: []string{"太郎"},
+	}, result)
+
+	// 知らない優先度はタイトルに残る
+	assert.Equal(t, Result{Title: "Fix it !urgent"}, Parse("Fix it !urgent", now))
+	assert.Equal(t, Result{Labels: []string{"a"}, Mentions: []string{"b"}}, Parse("#a @b", now))
+}
+
+func TestParseDates(t *testing.T) {
+	tests := []struct {
+		text    string
+		title   string
+		dueDate string
+	}{
+		{"Call mom today", "Call mom", "2024-05-15"},
+		{"Pay rent due tomorrow", "Pay rent", "2024-05-16"},
+		{"Pay rent the day after tomorrow", "Pay rent", "2024-05-17"},
+		{"Report in 3 days", "Report", "2024-05-18"},
+		{"Report in 2 weeks", "Report", "2024-05-29"},
+		{"Report in 1 month", "Report", "2024-06-15"},
+		{"Dentist Friday", "Dentist", "2024-05-17"},
+		{"Dentist on wednesday", "Dentist", "2024-05-22"},
+		{"Dentist on fri", "Dentist", "2024-05-17"},
+		{"Dentist this friday", "Dentist", "2024-05-17"},
+		{"Dentist this monday", "Dentist", "2024-05-20"},
+		{"Dentist next friday", "Dentist", "2024-05-24"},
+		{"Plan trip next week", "Plan trip", "2024-05-20"},
+		{"Submit 2024-06-01", "Submit", "2024-06-01"},
+		{"Submit by 6/1", "Submit", "2024-06-01"},
+		{"Submit 5/1", "Submit", "2025-05-01"},
+		{"Buy sun cream", "Buy sun cream", ""},
+		{"Bake 2/30 cake", "Bake 2/30 cake", ""},
+		{"明日までに牛乳を買う", "牛乳を買う", "2024-05-16"},
+		{"今日 掃除", "掃除", "2024-05-15"},
+		{"明後日 掃除", "掃除", "2024-05-17"},
+		{"3日後 返却", "返却", "2024-05-18"},
+		{"2週間後 返却", "返却", "2024-05-29"},
+		{"来週金曜 歯医者", "歯医者", "2024-05-24"},
+		{"今週の月曜日 歯医者", "歯医者", "2024-05-20"},
+		{"再来週水曜 歯医者", "歯医者", "2024-05-29"},
+		{"金曜 飲み会", "飲み会", "2024-05-17"},
+		{"来週 旅行の計画", "旅行の計画", "2024-05-20"},
+		{"12月25日 プレゼント", "プレゼント", "2024-12-25"},
+		{"2025年1月5日 初詣", "初詣", "2025-01-05"},
+	}
+	for _, tt := range tests {
+		result := Parse(tt.text, now)
+		assert.Equal(t, tt.title, result.Title, tt.text)
+		assert.Equal(t, tt.dueDate, result.DueDate, tt.text)
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	tests := []struct {
+		text    string
+		title   string
+		dueDate string
+	}{
+		{"Call tomorrow at 2:30pm", "Call", "2024-05-16T14:30"},
+		{"Call tomorrow 14:30", "Call", "2024-05-16T14:30"},
+		{"Lunch at noon", "Lunch", "2024-05-15T12:00"},
+		{"Meeting at 3pm", "Meeting", "2024-05-15T15:00"},
+		// 過ぎた時刻は明日
+		{"Meeting 9am", "Meeting", "2024-05-16T09:00"},
+		{"Meeting 13pm", "Meeting 13pm", ""},
+		{"明日15時半 会議", "会議", "2024-05-16T15:30"},
+		{"会議 午後3時", "会議", "2024-05-15T15:00"},
+		{"来週金曜 9時10分までに提出", "提出", "2024-05-24T09:10"},
+	}
+	for _, tt := range tests {
+		result := Parse(tt.text, now)
+		assert.Equal(t, tt.title, result.Title, tt.text)
+		assert.Equal(t, tt.dueDate, result.DueDate, tt.text)
+	}
+}
@@ -0,0 +1,93 @@
+// Package realtime fans out events of a list to the clients connected to it,
+// e.g. over server-sent events.
+package realtime
+
+import (
+	"sync"
+)
+
+// subscriptionBuffer is the number of events a subscriber may fall behind
+// before it is dropped
+const subscriptionBuffer = 64
+
+// Event is a named event with a JSON-encodable payload
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// Hub keeps the subscribers of each list. The zero value is not usable; create
+// one with NewHub.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+// Subscription receives the events of a list on C until it is closed
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	hub    *Hub
+	listID string
+}
+
+// NewHub returns an empty hub
+func NewHub() *Hub {
+	return &Hub{subs: map[string]map[*Subscription]struct{}{}}
+}
+
+// Subscribe starts receiving the events of a list
+func (h *Hub) Subscribe(listID string) *Subscription {
+	c := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: c, c: c, hub: h, listID: listID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[listID] == nil {
+		h.subs[listID] = map[*Subscription]struct{}{}
+	}
+	h.subs[listID][sub] = struct{}{}
+	return sub
+}
+
+// Close stops the subscription and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Publish sends an event to every subscriber of a list without blocking. A
+// subscriber whose buffer is full is dropped and its channel closed, so that
+// the client reconnects and catches up instead of missing events silently.
+func (h *Hub) Publish(listID string, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[listID] {
+		select {
+		case sub.c <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers of a list
+func (h *Hub) Subscribers(listID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[listID])
+}
+
+// remove unregisters a subscription; the caller holds h.mu
+func (h *Hub) remove(s *Subscription) {
+	subs := h.subs[s.listID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subs, s.listID)
+	}
+	close(s.c)
+}
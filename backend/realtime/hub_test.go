@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub()
+	a := hub.Subscribe("list")
+	b := hub.Subscribe("list")
+	other := hub.Subscribe("other")
+	assert.Equal(t, 2, hub.Subscribers("list"))
+
+	hub.Publish("list", Event{Name: "operation", Data: 1})
+	assert.Equal(t, Event{Name: "operation", Data: 1}, <-a.C)
+	assert.Equal(t, Event{Name: "operation", Data: 1}, <-b.C)
+	assert.Len(t, other.C, 0)
+
+	// 閉じた購読には送られない
+	a.Close()
+	a.Close()
+	_, ok := <-a.C
+	assert.False(t, ok)
+	hub.Publish("list", Event{Name: "operation", Data: 2})
+	assert.Equal(t, 2, (<-b.C).Data)
+	assert.Equal(t, 1, hub.Subscribers("list"))
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe("list")
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish("list", Event{Name: "operation", Data: i})
+	}
+	assert.Equal(t, 0, hub.Subscribers("list"))
+
+	// バッファ済みのイベントを受け取った後にチャネルが閉じられる
+	count := 0
+	for range sub.C {
+		count++
+	}
+	assert.Equal(t, subscriptionBuffer, count)
+	sub.Close()
+}
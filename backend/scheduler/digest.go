@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"shared-todo-backend/digest"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify"
+	"time"
+)
+
+// sendDigests sends the scheduled digest of every user whose digest is due
+func (s *Scheduler) sendDigests(ctx context.Context, now time.Time) error {
+	var users []models.User
+	err := s.DB.WithContext(ctx).
+		Where("digest_frequency IN ?", []string{digest.FrequencyDaily, digest.FrequencyWeekly}).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	lists := map[string]*models.List{}
+	for _, user := range users {
+		list, ok := lists[user.ListID]
+		if !ok {
+			list = &models.List{}
+			if err := s.DB.WithContext(ctx).First(list, "id = ?", user.ListID).Error; err != nil {
+				return err
+			}
+			lists[user.ListID] = list
+		}
+
+		loc := models.LoadLocation(models.EffectiveTimeZone(*list, user))
+		scheduled := digest.Scheduled(user.DigestFrequency, now, loc)
+		if user.LastDigestAt != nil && !user.LastDigestAt.Before(scheduled) {
+			continue
+		}
+		if err := s.sendDigest(ctx, now, scheduled, *list, user); err != nil {
+			log.Printf("Failed to send digest to user %s: %v", user.ID, err)
+		}
+	}
+	return nil
+}
+
+// sendDigest sends the digest due at scheduled to user. Digests without
+// anything to report are skipped.
+func (s *Scheduler) sendDigest(ctx context.Context, now, scheduled time.Time, list models.List, user models.User) error {
+	since := scheduled.Add(-digest.Period(user.DigestFrequency))
+	if user.LastDigestAt != nil {
+		since = *user.LastDigestAt
+	}
+
+	// 他のインスタンスと重複して送らないよう、送信前に送信日時を記録する
+	result := s.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND (last_digest_at IS NULL OR last_digest_at < ?)", user.ID, scheduled.UTC()).
+		Update("last_digest_at", now.UTC())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	release := func() {
+		s.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("last_digest_at", user.LastDigestAt)
+	}
+
+	d, err := digest.Build(s.DB.WithContext(ctx), list, user, since, now)
+	if err != nil {
+		release()
+		return err
+	}
+	if d.Empty() {
+		return nil
+	}
+
+	var delivered []string
+	err = s.DB.WithContext(ctx).Model(&models.DigestDelivery{}).
+		Where("user_id = ? AND scheduled_at = ?", user.ID, scheduled.UTC()).
+		Pluck("channel", &delivered).Error
+	if err != nil {
+		release()
+		return err
+	}
+	done := make(map[string]bool)
+	for _, channel := range delivered {
+		done[channel] = true
+	}
+
+	// 送信済みのチャネルには再送しない
+	msg := notify.Message{
+		Type:    notify.TypeDigest,
+		List:    list,
+		User:    user,
+		Subject: d.Subject(),
+		Body:    d.Text(),
+		HTML:    d.HTML(),
+	}
+	var errs []error
+	for _, ch := range channels(s.Notifier) {
+		if done[ch.name] {
+			continue
+		}
+		if err := ch.notifier.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivery := models.DigestDelivery{UserID: user.ID, ScheduledAt: scheduled.UTC(), Channel: ch.name, SentAt: s.Now().UTC()}
+		if err := s.DB.WithContext(ctx).Create(&delivery).Error; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		// 失敗したチャネルは次回のスキャンで再送する
+		release()
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *SchedulerTestSuite) TestDailyDigest() {
+	suite.db.Model(&models.User{}).Where("id = ?", "alice").Update("digest_frequency", "daily")
+	todo := models.Todo{ListID: "list", Title: "Report", Priority: "medium"}
+	suite.db.Create(&todo)
+	suite.db.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "alice"})
+	ctx := context.Background()
+
+	// 2025-06-10 17:00 JST: 当日08:00の分を送る
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	suite.Require().Len(suite.sent, 1)
+	assert.Equal(suite.T(), notify.TypeDigest, suite.sent[0].Type)
+	assert.Equal(suite.T(), "alice", suite.sent[0].User.ID)
+	assert.Contains(suite.T(), suite.sent[0].Body, "- Report")
+	assert.Contains(suite.T(), suite.sent[0].HTML, "<li>Report <small>waiting on bob</small></li>")
+
+	// 翌日08:00 JSTまでは再送しない
+	suite.now = time.Date(2025, 6, 10, 22, 59, 0, 0, time.UTC)
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Len(suite.T(), suite.sent, 1)
+
+	suite.now = time.Date(2025, 6, 10, 23, 0, 0, 0, time.UTC)
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Len(suite.T(), suite.sent, 2)
+}
+
+func (suite *SchedulerTestSuite) TestDigestRetriedAfterFailure() {
+	suite.db.Model(&models.User{}).Where("id = ?", "alice").Update("digest_frequency", "weekly")
+	todo := models.Todo{ListID: "list", Title: "Report", Priority: "medium"}
+	suite.db.Create(&todo)
+	ctx := context.Background()
+
+	suite.fail = true
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+
+	var user models.User
+	suite.db.First(&user, "id = ?", "alice")
+	assert.Nil(suite.T(), user.LastDigestAt)
+
+	suite.fail = false
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Len(suite.T(), suite.sent, 1)
+}
+
+func (suite *SchedulerTestSuite) TestFailedDigestChannelIsRetriedAlone() {
+	suite.db.Model(&models.User{}).Where("id = ?", "alice").Update("digest_frequency", "daily")
+	suite.db.Create(&models.Todo{ListID: "list", Title: "Report", Priority: "medium"})
+	ctx := context.Background()
+
+	var inApp, email []notify.Message
+	failing := true
+	s := New(suite.db, notify.Multi{
+		notify.Func(func(ctx context.Context, msg notify.Message) error {
+			inApp = append(inApp, msg)
+			return nil
+		}),
+		notify.Func(func(ctx context.Context, msg notify.Message) error {
+			if failing {
+				return errors.New("delivery failed")
+			}
+			email = append(email, msg)
+			return nil
+		}),
+	})
+	s.Now = func() time.Time { return suite.now }
+
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.Len(suite.T(), inApp, 1)
+	assert.Empty(suite.T(), email)
+
+	// 成功したチャネルには再送せず、失敗したチャネルだけ再送する
+	suite.now = suite.now.Add(time.Minute)
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.Len(suite.T(), inApp, 1)
+	assert.Empty(suite.T(), email)
+
+	failing = false
+	suite.now = suite.now.Add(time.Minute)
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.Len(suite.T(), inApp, 1)
+	assert.Len(suite.T(), email, 1)
+
+	var user models.User
+	suite.db.First(&user, "id = ?", "alice")
+	assert.NotNil(suite.T(), user.LastDigestAt)
+}
+
+func (suite *SchedulerTestSuite) TestEmptyDigestIsSkipped() {
+	suite.db.Model(&models.User{}).Where("id = ?", "alice").Update("digest_frequency", "daily")
+
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(context.Background()))
+	assert.Empty(suite.T(), suite.sent)
+
+	var user models.User
+	suite.db.First(&user, "id = ?", "alice")
+	assert.NotNil(suite.T(), user.LastDigestAt)
+}
@@ -0,0 +1,269 @@
+// Package scheduler periodically scans todos with a due date and sends
+// reminders before the due time and when a todo becomes overdue. It also sends
+// the daily and weekly digests users subscribed to.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxLeadMinutes is the longest reminder lead time a user can choose (one week)
+const MaxLeadMinutes = 7 * 24 * 60
+
+// DefaultInterval is the time between two scans
+const DefaultInterval = time.Minute
+
+// ClaimLease is how long a claimed reminder is left to the scan that claimed
+// it. A claim still unsent after that, e.g. because the process stopped while
+// sending, is taken over by the next scan.
+const ClaimLease = 10 * time.Minute
+
+// OverdueWindow limits overdue notices to todos that became overdue recently, so
+// that old todos do not all fire at once when reminders are first enabled
+const OverdueWindow = 24 * time.Hour
+
+// Scheduler sends due date reminders through a notifier
+type Scheduler struct {
+	DB       *gorm.DB
+	Notifier notify.Notifier
+	Interval time.Duration
+	// Now returns the current time; replaced in tests
+	Now func() time.Time
+}
+
+// New returns a scheduler scanning every DefaultInterval
+func New(db *gorm.DB, notifier notify.Notifier) *Scheduler {
+	return &Scheduler{
+		DB:       db,
+		Notifier: notifier,
+		Interval: DefaultInterval,
+		Now:      time.Now,
+	}
+}
+
+// Start runs the scheduler in the background until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RunOnce(ctx); err != nil {
+				log.Println("Scheduler run failed:", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce sends every reminder and digest that is due now and has not been sent yet
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	now := s.Now()
+
+	// 日付のみの期限はタイムゾーンによって最大1日ずれるため余裕を持って取得する
+	from := now.Add(-OverdueWindow - 48*time.Hour)
+	to := now.Add(MaxLeadMinutes*time.Minute + 24*time.Hour)
+
+	var todos []models.Todo
+	err := s.DB.WithContext(ctx).
+		Where("due_date IS NOT NULL AND due_date >= ? AND due_date <= ? AND is_completed = ?", from.UTC(), to.UTC(), false).
+		Preload("UserStatuses").
+		Find(&todos).Error
+	if err != nil {
+		return err
+	}
+
+	lists := map[string]*listMembers{}
+	for i := range todos {
+		todo := &todos[i]
+
+		members, ok := lists[todo.ListID]
+		if !ok {
+			members, err = s.loadMembers(ctx, todo.ListID)
+			if err != nil {
+				return err
+			}
+			lists[todo.ListID] = members
+		}
+
+		checked := map[string]bool{}
+		for _, status := range todo.UserStatuses {
+			checked[status.UserID] = status.IsChecked
+		}
+
+		for _, user := range members.users {
+			if checked[user.ID] {
+				continue
+			}
+			if err := s.remind(ctx, now, members.list, user, todo); err != nil {
+				log.Printf("Failed to send reminder for todo %d to user %s: %v", todo.ID, user.ID, err)
+			}
+		}
+	}
+	return s.sendDigests(ctx, now)
+}
+
+type listMembers struct {
+	list  models.List
+	users []models.User
+}
+
+func (s *Scheduler) loadMembers(ctx context.Context, listID string) (*listMembers, error) {
+	members := &listMembers{}
+	if err := s.DB.WithContext(ctx).First(&members.list, "id = ?", listID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.WithContext(ctx).Where("list_id = ?", listID).Find(&members.users).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// remind sends the reminder or overdue notice of todo to user if one is due
+func (s *Scheduler) remind(ctx context.Context, now time.Time, list models.List, user models.User, todo *models.Todo) error {
+	loc := models.LoadLocation(models.EffectiveTimeZone(list, user))
+	dueAt := todo.DueAt(loc)
+
+	var kind string
+	switch {
+	case now.Sub(*dueAt) >= OverdueWindow:
+		return nil
+	case !now.Before(*dueAt):
+		kind = notify.TypeOverdue
+	case user.ReminderLeadMinutes > 0 && !now.Before(dueAt.Add(-time.Duration(user.ReminderLeadMinutes)*time.Minute)):
+		kind = notify.TypeReminder
+	default:
+		return nil
+	}
+
+	claimed, entry, err := s.claim(ctx, todo.ID, user.ID, kind, *dueAt)
+	if err != nil || !claimed {
+		return err
+	}
+
+	var delivered []string
+	if err := s.DB.WithContext(ctx).Model(&models.ReminderDelivery{}).Where("reminder_log_id = ?", entry.ID).Pluck("channel", &delivered).Error; err != nil {
+		return err
+	}
+	done := make(map[string]bool)
+	for _, channel := range delivered {
+		done[channel] = true
+	}
+
+	// 送信済みのチャネルには再送しない
+	msg := message(kind, list, user, todo, dueAt.In(loc))
+	var errs []error
+	for _, ch := range channels(s.Notifier) {
+		if done[ch.name] {
+			continue
+		}
+		if err := ch.notifier.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivery := models.ReminderDelivery{ReminderLogID: entry.ID, Channel: ch.name, SentAt: s.Now().UTC()}
+		if err := s.DB.WithContext(ctx).Create(&delivery).Error; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		// 失敗したチャネルは次回のスキャンで再送する
+		s.DB.WithContext(ctx).Model(entry).Update("claimed_at", nil)
+		return errors.Join(errs...)
+	}
+
+	sentAt := s.Now()
+	return s.DB.WithContext(ctx).Model(entry).Update("sent_at", sentAt).Error
+}
+
+// claim records the reminder before sending it. It reports false when the
+// reminder was already sent, or claimed by another scan within ClaimLease.
+func (s *Scheduler) claim(ctx context.Context, todoID uint, userID, kind string, dueAt time.Time) (bool, *models.ReminderLog, error) {
+	now := s.Now().UTC()
+	entry := &models.ReminderLog{
+		TodoID:    todoID,
+		UserID:    userID,
+		Kind:      kind,
+		DueAt:     dueAt.UTC(),
+		ClaimedAt: &now,
+	}
+	result := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return false, nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, entry, nil
+	}
+
+	// 送信に失敗した、または送信中に止まった予約を引き継ぐ
+	result = s.DB.WithContext(ctx).Model(&models.ReminderLog{}).
+		Where("todo_id = ? AND user_id = ? AND kind = ? AND due_at = ?", todoID, userID, kind, dueAt.UTC()).
+		Where("sent_at IS NULL AND (claimed_at IS NULL OR claimed_at < ?)", now.Add(-ClaimLease)).
+		Update("claimed_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, nil, result.Error
+	}
+	err := s.DB.WithContext(ctx).Where("todo_id = ? AND user_id = ? AND kind = ? AND due_at = ?", todoID, userID, kind, dueAt.UTC()).First(entry).Error
+	return err == nil, entry, err
+}
+
+// channel is a notifier with the name its deliveries are recorded under
+type channel struct {
+	name     string
+	notifier notify.Notifier
+}
+
+// channels splits a notify.Multi into its notifiers, named by their type. A
+// type used more than once is numbered in order.
+func channels(n notify.Notifier) []channel {
+	multi, ok := n.(notify.Multi)
+	if !ok {
+		multi = notify.Multi{n}
+	}
+	var result []channel
+	seen := make(map[string]int)
+	for _, notifier := range multi {
+		name := fmt.Sprintf("%T", notifier)
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+		result = append(result, channel{name, notifier})
+	}
+	return result
+}
+
+func message(kind string, list models.List, user models.User, todo *models.Todo, dueAt time.Time) notify.Message {
+	due := dueAt.Format("2006-01-02 15:04 MST")
+	if !todo.HasDueTime {
+		due = todo.DueDate.UTC().Format("2006-01-02")
+	}
+
+	msg := notify.Message{
+		Type: kind,
+		List: list,
+		User: user,
+		Todo: todo,
+	}
+	if kind == notify.TypeOverdue {
+		msg.Subject = fmt.Sprintf("Overdue: %s", todo.Title)
+		msg.Body = fmt.Sprintf("\"%s\" was due %s and is not checked yet.", todo.Title, due)
+	} else {
+		msg.Subject = fmt.Sprintf("Reminder: %s", todo.Title)
+		msg.Body = fmt.Sprintf("\"%s\" is due %s.", todo.Title, due)
+	}
+	return msg
+}
@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"shared-todo-backend/notify"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type SchedulerTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	sent []notify.Message
+	fail bool
+	now  time.Time
+}
+
+func (suite *SchedulerTestSuite) SetupTest() {
+	db, err := database.SetupTestDatabase()
+	suite.Require().NoError(err)
+	suite.db = db
+	suite.sent = nil
+	suite.fail = false
+	suite.now = time.Date(2025, 6, 10, 8, 0, 0, 0, time.UTC)
+
+	db.Create(&models.List{ID: "list", TimeZone: "Asia/Tokyo"})
+	db.Create(&models.User{ID: "alice", ListID: "list"})
+	db.Create(&models.User{ID: "bob", ListID: "list", ReminderLeadMinutes: 30})
+}
+
+func (suite *SchedulerTestSuite) newScheduler() *Scheduler {
+	s := New(suite.db, notify.Func(func(ctx context.Context, msg notify.Message) error {
+		if suite.fail {
+			return errors.New("delivery failed")
+		}
+		suite.sent = append(suite.sent, msg)
+		return nil
+	}))
+	s.Now = func() time.Time { return suite.now }
+	return s
+}
+
+func (suite *SchedulerTestSuite) createTodo(title string, due time.Time, hasTime bool) models.Todo {
+	todo := models.Todo{ListID: "list", Title: title, Priority: "medium", DueDate: &due, HasDueTime: hasTime}
+	suite.db.Create(&todo)
+	suite.db.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "alice"})
+	suite.db.Create(&models.TodoUserStatus{TodoID: todo.ID, UserID: "bob"})
+	return todo
+}
+
+func (suite *SchedulerTestSuite) sentTo() map[string]string {
+	result := map[string]string{}
+	for _, msg := range suite.sent {
+		result[msg.User.ID] = msg.Type
+	}
+	return result
+}
+
+func (suite *SchedulerTestSuite) TestReminderLeadTime() {
+	suite.createTodo("Report", suite.now.Add(45*time.Minute), true)
+	ctx := context.Background()
+
+	// aliceはデフォルトの60分前、bobは30分前
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Equal(suite.T(), map[string]string{"alice": notify.TypeReminder}, suite.sentTo())
+	assert.Equal(suite.T(), "Reminder: Report", suite.sent[0].Subject)
+	assert.Contains(suite.T(), suite.sent[0].Body, "2025-06-10 17:45 JST")
+
+	suite.now = suite.now.Add(20 * time.Minute)
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Len(suite.T(), suite.sent, 2)
+	assert.Equal(suite.T(), notify.TypeReminder, suite.sentTo()["bob"])
+}
+
+func (suite *SchedulerTestSuite) TestOverdueSentOnceAcrossRestarts() {
+	todo := suite.createTodo("Report", suite.now.Add(-time.Minute), true)
+	suite.db.Model(&models.TodoUserStatus{}).Where("todo_id = ? AND user_id = ?", todo.ID, "bob").Update("is_checked", true)
+	ctx := context.Background()
+
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Equal(suite.T(), map[string]string{"alice": notify.TypeOverdue}, suite.sentTo())
+
+	// 再起動後の新しいスケジューラでも再送しない
+	suite.now = suite.now.Add(time.Minute)
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Len(suite.T(), suite.sent, 1)
+
+	var entry models.ReminderLog
+	suite.db.First(&entry)
+	assert.NotNil(suite.T(), entry.SentAt)
+}
+
+func (suite *SchedulerTestSuite) TestDateOnlyDueInUserTimeZone() {
+	suite.db.Model(&models.User{}).Where("id = ?", "bob").Update("time_zone", "Europe/Berlin")
+	// 6月10日の期限は東京では6月10日15:00 UTC、ベルリンでは6月10日22:00 UTCに終わる
+	suite.createTodo("Report", time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), false)
+	suite.db.Model(&models.User{}).Where("id = ?", "alice").Update("reminder_lead_minutes", 0)
+	suite.now = time.Date(2025, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(context.Background()))
+	assert.Equal(suite.T(), map[string]string{"alice": notify.TypeOverdue}, suite.sentTo())
+	assert.Contains(suite.T(), suite.sent[0].Body, "was due 2025-06-10")
+}
+
+func (suite *SchedulerTestSuite) TestFailedDeliveryIsRetried() {
+	suite.createTodo("Report", suite.now.Add(-time.Minute), true)
+	ctx := context.Background()
+
+	suite.fail = true
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Empty(suite.T(), suite.sent)
+
+	// 予約は解放され、次回のスキャンで再送する
+	var entries []models.ReminderLog
+	suite.db.Find(&entries)
+	suite.Require().Len(entries, 2)
+	assert.Nil(suite.T(), entries[0].ClaimedAt)
+	assert.Nil(suite.T(), entries[0].SentAt)
+
+	suite.fail = false
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Len(suite.T(), suite.sent, 2)
+}
+
+func (suite *SchedulerTestSuite) TestFailedChannelIsRetriedAlone() {
+	suite.createTodo("Report", suite.now.Add(-time.Minute), true)
+	suite.db.Model(&models.User{}).Where("id = ?", "bob").Update("list_id", "other")
+	ctx := context.Background()
+
+	var inApp, webhook []notify.Message
+	failing := true
+	s := New(suite.db, notify.Multi{
+		notify.Func(func(ctx context.Context, msg notify.Message) error {
+			inApp = append(inApp, msg)
+			return nil
+		}),
+		notify.Func(func(ctx context.Context, msg notify.Message) error {
+			if failing {
+				return errors.New("delivery failed")
+			}
+			webhook = append(webhook, msg)
+			return nil
+		}),
+	})
+	s.Now = func() time.Time { return suite.now }
+
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.Len(suite.T(), inApp, 1)
+	assert.Empty(suite.T(), webhook)
+
+	// 成功したチャネルには再送せず、失敗したチャネルだけ再送する
+	suite.now = suite.now.Add(time.Minute)
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.Len(suite.T(), inApp, 1)
+	assert.Empty(suite.T(), webhook)
+
+	failing = false
+	suite.now = suite.now.Add(time.Minute)
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.NoError(suite.T(), s.RunOnce(ctx))
+	assert.Len(suite.T(), inApp, 1)
+	assert.Len(suite.T(), webhook, 1)
+
+	var entry models.ReminderLog
+	suite.db.First(&entry)
+	assert.NotNil(suite.T(), entry.SentAt)
+}
+
+func (suite *SchedulerTestSuite) TestStaleClaimIsTakenOver() {
+	todo := suite.createTodo("Report", suite.now.Add(-time.Minute), true)
+	suite.db.Model(&models.User{}).Where("id = ?", "bob").Update("list_id", "other")
+
+	// 送信中に止まったスキャンの予約
+	claimedAt := suite.now
+	suite.db.Create(&models.ReminderLog{TodoID: todo.ID, UserID: "alice", Kind: notify.TypeOverdue, DueAt: todo.DueDate.UTC(), ClaimedAt: &claimedAt})
+	ctx := context.Background()
+
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Empty(suite.T(), suite.sent)
+
+	suite.now = suite.now.Add(ClaimLease + time.Minute)
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(ctx))
+	assert.Equal(suite.T(), map[string]string{"alice": notify.TypeOverdue}, suite.sentTo())
+}
+
+func (suite *SchedulerTestSuite) TestSkipsCompletedAndOldTodos() {
+	completed := suite.createTodo("Done", suite.now.Add(-time.Minute), true)
+	suite.db.Model(&completed).Update("is_completed", true)
+	suite.createTodo("Old", suite.now.Add(-OverdueWindow-time.Minute), true)
+	suite.createTodo("Later", suite.now.Add(3*time.Hour), true)
+
+	assert.NoError(suite.T(), suite.newScheduler().RunOnce(context.Background()))
+	assert.Empty(suite.T(), suite.sent)
+}
+
+func TestSchedulerTestSuite(t *testing.T) {
+	suite.Run(t, new(SchedulerTestSuite))
+}
@@ -0,0 +1,252 @@
+package search
+
+import (
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// IndexTable is the FTS5 table holding todo and memo text
+const IndexTable = "search_index"
+
+// highlight markers used in SQL snippets; replaced with <mark> after escaping
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// minTermLength is the shortest term the trigram tokenizer can match
+const minTermLength = 3
+
+// Result is a single search hit
+type Result struct {
+	Type    string  `json:"type"`
+	TodoID  *uint   `json:"todoId,omitempty"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// indexTriggers keep the FTS index in sync with the todos and lists tables.
+// Soft-deleted todos are removed from the index.
+var indexTriggers = map[string]string{
+	"search_todos_ai": `AFTER INSERT ON todos BEGIN
+		INSERT INTO search_index (kind, ref_id, list_id, title, body) SELECT 'todo', new.id, new.list_id, new.title, new.description WHERE new.deleted_at IS NULL;
+	END`,
+	"search_todos_au": `AFTER UPDATE ON todos BEGIN
+		DELETE FROM search_index WHERE kind = 'todo' AND ref_id = old.id;
+		INSERT INTO search_index (kind, ref_id, list_id, title, body) SELECT 'todo', new.id, new.list_id, new.title, new.description WHERE new.deleted_at IS NULL;
+	END`,
+	"search_todos_ad": `AFTER DELETE ON todos BEGIN
+		DELETE FROM search_index WHERE kind = 'todo' AND ref_id = old.id;
+	END`,
+	"search_lists_ai": `AFTER INSERT ON lists BEGIN
+		INSERT INTO search_index (kind, ref_id, list_id, title, body) VALUES ('memo', new.id, new.id, '', new.memo);
+	END`,
+	"search_lists_au": `AFTER UPDATE OF memo ON lists BEGIN
+		DELETE FROM search_index WHERE kind = 'memo' AND ref_id = old.id;
+		INSERT INTO search_index (kind, ref_id, list_id, title, body) VALUES ('memo', new.id, new.id, '', new.memo);
+	END`,
+	"search_lists_ad": `AFTER DELETE ON lists BEGIN
+		DELETE FROM search_index WHERE kind = 'memo' AND ref_id = old.id;
+	END`,
+}
+
+// IndexVersionTable records the version of the index it was built with
+const IndexVersionTable = "search_index_version"
+
+// indexVersion is the version of the index table and its triggers. Bump it when
+// either changes so that Setup rebuilds the index once.
+const indexVersion = 1
+
+// rebuildStatements refill the index from the source tables
+var rebuildStatements = []string{
+	"DELETE FROM " + IndexTable,
+	"INSERT INTO " + IndexTable + " (kind, ref_id, list_id, title, body) SELECT 'todo', id, list_id, title, description FROM todos WHERE deleted_at IS NULL",
+	"INSERT INTO " + IndexTable + " (kind, ref_id, list_id, title, body) SELECT 'memo', id, id, '', memo FROM lists",
+}
+
+// Setup creates the FTS5 index and its triggers. The index is filled from the
+// todos and lists tables only when it is first created or indexVersion changed;
+// otherwise the triggers have kept it current. When the driver is not SQLite or
+// FTS5 is unavailable the index is not created and Search falls back to LIKE queries.
+func Setup(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS " + IndexVersionTable + " (version INTEGER NOT NULL)").Error; err != nil {
+		return err
+	}
+	var version int
+	if err := db.Raw("SELECT COALESCE(MAX(version), 0) FROM " + IndexVersionTable).Scan(&version).Error; err != nil {
+		return err
+	}
+	if version == indexVersion && db.Migrator().HasTable(IndexTable) {
+		return nil
+	}
+
+	// 版が変わったら作り直す
+	if err := db.Exec("DROP TABLE IF EXISTS " + IndexTable).Error; err != nil {
+		return err
+	}
+	err := db.Exec("CREATE VIRTUAL TABLE " + IndexTable + " USING fts5(kind UNINDEXED, ref_id UNINDEXED, list_id UNINDEXED, title, body, tokenize='trigram')").Error
+	if err != nil {
+		log.Println("FTS5 is not available, falling back to LIKE search:", err)
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, body := range indexTriggers {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("CREATE TRIGGER " + name + " " + body).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, statement := range rebuildStatements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM " + IndexVersionTable).Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO "+IndexVersionTable+" (version) VALUES (?)", indexVersion).Error
+	})
+}
+
+// Search finds todos and the memo of a list matching every term of query,
+// best match first.
+func Search(db *gorm.DB, listID, query string, limit int) ([]Result, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return []Result{}, nil
+	}
+
+	if useIndex(db, terms) {
+		return searchIndex(db, listID, terms, limit)
+	}
+	return searchLike(db, listID, terms, limit)
+}
+
+func useIndex(db *gorm.DB, terms []string) bool {
+	if db.Dialector.Name() != "sqlite" || !db.Migrator().HasTable(IndexTable) {
+		return false
+	}
+	// trigramトークナイザは3文字未満の語にマッチしない
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < minTermLength {
+			return false
+		}
+	}
+	return true
+}
+
+func searchIndex(db *gorm.DB, listID string, terms []string, limit int) ([]Result, error) {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	var rows []struct {
+		Kind    string
+		RefID   string
+		Title   string
+		Snippet string
+		Rank    float64
+	}
+	err := db.Raw(`SELECT kind, ref_id,
+			highlight(`+IndexTable+`, 3, char(2), char(3)) AS title,
+			snippet(`+IndexTable+`, 4, char(2), char(3), '…', 64) AS snippet,
+			bm25(`+IndexTable+`, 0, 0, 0, 10.0, 1.0) AS rank
+		FROM `+IndexTable+`
+		WHERE `+IndexTable+` MATCH ? AND list_id = ?
+		ORDER BY rank
+		LIMIT ?`, strings.Join(quoted, " "), listID, limit).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(rows))
+	for _, row := range rows {
+		result := Result{
+			Type:    row.Kind,
+			Title:   markup(row.Title),
+			Snippet: markup(row.Snippet),
+			Score:   -row.Rank,
+		}
+		if row.Kind == "todo" {
+			result.TodoID = parseID(row.RefID)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func searchLike(db *gorm.DB, listID string, terms []string, limit int) ([]Result, error) {
+	var todos []struct {
+		ID          uint
+		Title       string
+		Description string
+	}
+	tx := db.Table("todos").Select("id, title, description").Where("list_id = ? AND deleted_at IS NULL", listID)
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		tx = tx.Where("(title LIKE ? ESCAPE ? OR description LIKE ? ESCAPE ?)", pattern, `\`, pattern, `\`)
+	}
+	if err := tx.Scan(&todos).Error; err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, todo := range todos {
+		id := todo.ID
+		snippet := ""
+		if todo.Description != "" {
+			snippet = snippetFor(todo.Description, terms)
+		}
+		results = append(results, Result{
+			Type:    "todo",
+			TodoID:  &id,
+			Title:   highlightTerms(todo.Title, terms),
+			Snippet: snippet,
+			Score:   10*float64(countTerms(todo.Title, terms)) + float64(countTerms(todo.Description, terms)),
+		})
+	}
+
+	var memo string
+	if err := db.Table("lists").Select("memo").Where("id = ?", listID).Scan(&memo).Error; err != nil {
+		return nil, err
+	}
+	if matchesAll(memo, terms) {
+		results = append(results, Result{
+			Type:    "memo",
+			Title:   "",
+			Snippet: snippetFor(memo, terms),
+			Score:   float64(countTerms(memo, terms)),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// markup escapes text and turns the highlight markers into <mark> tags
+func markup(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, markStart, "<mark>")
+	return strings.ReplaceAll(s, markEnd, "</mark>")
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"shared-todo-backend/models"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type SearchTestSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (suite *SearchTestSuite) SetupTest() {
+	// インメモリSQLiteデータベースを使用
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	err = db.AutoMigrate(&models.List{}, &models.User{}, &models.Todo{}, &models.TodoUserStatus{})
+	suite.Require().NoError(err)
+
+	// インデックス作成前のデータも検索できること
+	db.Create(&models.List{ID: "list", Memo: "買い物リストの共有メモ"})
+	db.Create(&models.Todo{ListID: "list", Title: "Buy 100% juice", Description: "Orange or apple", Priority: "medium"})
+
+	suite.Require().NoError(Setup(db))
+	suite.db = db
+}
+
+func (suite *SearchTestSuite) TestSetupCreatesIndex() {
+	assert.True(suite.T(), suite.db.Migrator().HasTable(IndexTable))
+
+	// 再実行してもインデックスが重複しない
+	assert.NoError(suite.T(), Setup(suite.db))
+	var count int64
+	suite.db.Table(IndexTable).Count(&count)
+	assert.Equal(suite.T(), int64(2), count)
+}
+
+func (suite *SearchTestSuite) TestSetupRebuildsOnlyOnVersionChange() {
+	// 同じ版なら再構築せず、トリガーで保たれた内容をそのまま使う
+	suite.db.Exec("DELETE FROM " + IndexTable + " WHERE kind = 'memo'")
+	suite.Require().NoError(Setup(suite.db))
+	var count int64
+	suite.db.Table(IndexTable).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// 版が変わると作り直す
+	suite.db.Exec("UPDATE "+IndexVersionTable+" SET version = ?", indexVersion-1)
+	suite.Require().NoError(Setup(suite.db))
+	suite.db.Table(IndexTable).Count(&count)
+	assert.Equal(suite.T(), int64(2), count)
+
+	var version int
+	suite.db.Raw("SELECT version FROM " + IndexVersionTable).Scan(&version)
+	assert.Equal(suite.T(), indexVersion, version)
+}
+
+func (suite *SearchTestSuite) TestSearchIndex() {
+	results, err := Search(suite.db, "list", "共有メモ", 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), results, 1)
+	assert.Equal(suite.T(), "memo", results[0].Type)
+	assert.Equal(suite.T(), "買い物リストの<mark>共有メモ</mark>", results[0].Snippet)
+
+	// 更新がインデックスに反映されること
+	suite.db.Model(&models.List{}).Where("id = ?", "list").Update("memo", "nothing here")
+	results, err = Search(suite.db, "list", "共有メモ", 10)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), results)
+
+	results, err = Search(suite.db, "list", "juice", 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), results, 1)
+	assert.Equal(suite.T(), uint(1), *results[0].TodoID)
+
+	// 説明文も検索対象
+	results, err = Search(suite.db, "list", "apple", 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), results, 1)
+	assert.Equal(suite.T(), "Buy 100% juice", results[0].Title)
+	assert.Equal(suite.T(), "Orange or <mark>apple</mark>", results[0].Snippet)
+}
+
+func (suite *SearchTestSuite) TestSearchExcludesDeletedTodos() {
+	suite.db.Delete(&models.Todo{}, 1)
+	results, err := Search(suite.db, "list", "juice", 10)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), results)
+
+	// 復元すると再び検索できること
+	suite.db.Unscoped().Model(&models.Todo{}).Where("id = ?", 1).Update("deleted_at", nil)
+	results, err = Search(suite.db, "list", "juice", 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), results, 1)
+}
+
+func (suite *SearchTestSuite) TestSearchLike() {
+	// LIKEのワイルドカードはエスケープされる
+	results, err := searchLike(suite.db, "list", []string{"0%"}, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), results, 1)
+	assert.Equal(suite.T(), "Buy 10<mark>0%</mark> juice", results[0].Title)
+
+	results, err = searchLike(suite.db, "list", []string{"or"}, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), results, 1)
+	assert.Equal(suite.T(), "<mark>Or</mark>ange <mark>or</mark> apple", results[0].Snippet)
+
+	results, err = searchLike(suite.db, "list", []string{"_"}, 10)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), results)
+}
+
+func (suite *SearchTestSuite) TestSnippet() {
+	text := "The quick brown fox jumps over the lazy dog and keeps running far away"
+	assert.Equal(suite.T(), "…rown fox jumps over the <mark>lazy</mark> dog and keeps runni…", snippetFor(text, []string{"LAZY"}))
+	assert.Equal(suite.T(), "&lt;b&gt; <mark>x</mark>", highlightTerms("<b> x", []string{"x"}))
+}
+
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchTestSuite))
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// snippetRadius is the number of characters shown around the first match
+const snippetRadius = 24
+
+func parseID(s string) *uint {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return nil
+	}
+	value := uint(id)
+	return &value
+}
+
+// escapeLike escapes the LIKE wildcards of s using '\'
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return strings.ReplaceAll(s, "_", `\_`)
+}
+
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// indexOf returns the index of sub in runes starting at from, or -1
+func indexOf(runes, sub []rune, from int) int {
+	for i := from; i+len(sub) <= len(runes); i++ {
+		match := true
+		for j := range sub {
+			if runes[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchRanges returns a flag per rune of text telling whether it is part of a match
+func matchRanges(text string, terms []string) ([]rune, []bool) {
+	runes := []rune(text)
+	lower := lowerRunes(text)
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		sub := lowerRunes(term)
+		if len(sub) == 0 {
+			continue
+		}
+		for i := indexOf(lower, sub, 0); i >= 0; i = indexOf(lower, sub, i+len(sub)) {
+			for j := i; j < i+len(sub); j++ {
+				marked[j] = true
+			}
+		}
+	}
+	return runes, marked
+}
+
+func matchesAll(text string, terms []string) bool {
+	lower := lowerRunes(text)
+	for _, term := range terms {
+		if indexOf(lower, lowerRunes(term), 0) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func countTerms(text string, terms []string) int {
+	lower := lowerRunes(text)
+	count := 0
+	for _, term := range terms {
+		sub := lowerRunes(term)
+		for i := indexOf(lower, sub, 0); i >= 0; i = indexOf(lower, sub, i+len(sub)) {
+			count++
+		}
+	}
+	return count
+}
+
+// highlightTerms wraps every occurrence of the terms in text with the highlight markers
+func highlightTerms(text string, terms []string) string {
+	runes, marked := matchRanges(text, terms)
+	return markup(wrapMarked(runes, marked, 0, len(runes)))
+}
+
+// snippetFor cuts the text around the first match and highlights the terms
+func snippetFor(text string, terms []string) string {
+	runes, marked := matchRanges(text, terms)
+
+	first := 0
+	for i, m := range marked {
+		if m {
+			first = i
+			break
+		}
+	}
+
+	start := first - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := first + snippetRadius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(wrapMarked(runes, marked, start, end))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return markup(b.String())
+}
+
+func wrapMarked(runes []rune, marked []bool, start, end int) string {
+	var b strings.Builder
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			b.WriteString(markStart)
+			inMark = true
+		} else if !marked[i] && inMark {
+			b.WriteString(markEnd)
+			inMark = false
+		}
+		b.WriteRune(runes[i])
+	}
+	if inMark {
+		b.WriteString(markEnd)
+	}
+	return b.String()
+}
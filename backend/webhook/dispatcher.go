@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"shared-todo-backend/models"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultInterval is the time between two polls of the delivery queue
+	DefaultInterval = 5 * time.Second
+	// DefaultMaxAttempts is the number of attempts before a delivery is given up
+	DefaultMaxAttempts = 8
+	// DefaultBaseBackoff is the delay before the first retry; it doubles on every attempt
+	DefaultBaseBackoff = 30 * time.Second
+	// maxBackoff caps the delay between two attempts
+	maxBackoff = 6 * time.Hour
+	// batchSize is the maximum number of deliveries sent per poll
+	batchSize = 50
+)
+
+// Dispatcher sends queued deliveries
+type Dispatcher struct {
+	DB          *gorm.DB
+	Client      *http.Client
+	Interval    time.Duration
+	MaxAttempts int
+	BaseBackoff time.Duration
+	// Now returns the current time; replaced in tests
+	Now func() time.Time
+}
+
+// NewDispatcher returns a dispatcher with the default settings
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		DB:          db,
+		Client:      NewClient(10 * time.Second),
+		Interval:    DefaultInterval,
+		MaxAttempts: DefaultMaxAttempts,
+		BaseBackoff: DefaultBaseBackoff,
+		Now:         time.Now,
+	}
+}
+
+// Start polls the queue in the background until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.RunOnce(ctx); err != nil {
+				log.Println("Webhook dispatch failed:", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce attempts every pending delivery whose next attempt is due
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	var deliveries []models.WebhookDelivery
+	err := d.DB.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, d.Now().UTC()).
+		Order("id").
+		Limit(batchSize).
+		Find(&deliveries).Error
+	if err != nil {
+		return err
+	}
+
+	hooks := map[uint]*models.Webhook{}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		hook, ok := hooks[delivery.WebhookID]
+		if !ok {
+			hook = &models.Webhook{}
+			if err := d.DB.WithContext(ctx).First(hook, delivery.WebhookID).Error; err != nil {
+				// Webhookが削除された場合は配信を中止する
+				hook = nil
+			}
+			hooks[delivery.WebhookID] = hook
+		}
+
+		if hook == nil {
+			delivery.Status = models.DeliveryFailed
+			delivery.LastError = "webhook was deleted"
+		} else {
+			d.attempt(ctx, hook, delivery)
+		}
+
+		if err := d.DB.WithContext(ctx).Save(delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends the delivery once and updates its status and next attempt
+func (d *Dispatcher) attempt(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+
+	status, err := d.send(ctx, hook, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		now := d.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.MaxAttempts {
+		delivery.Status = models.DeliveryFailed
+		return
+	}
+	delivery.NextAttemptAt = d.Now().UTC().Add(d.backoff(delivery.Attempts))
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(d.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "shared-todo-webhook/1")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.EventID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
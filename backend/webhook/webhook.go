@@ -0,0 +1,144 @@
+// Package webhook queues list events for registered webhooks and delivers them
+// as signed JSON payloads, retrying failed deliveries with exponential backoff.
+//
+// Every request carries these headers:
+//
+//	X-Webhook-Event:     the event name, e.g. "todo.created"
+//	X-Webhook-Delivery:  the event ID, identical across retries
+//	X-Webhook-Timestamp: Unix time of the attempt
+//	X-Webhook-Signature: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"shared-todo-backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Events sent to webhooks
+const (
+	EventTodoCreated   = "todo.created"
+	EventTodoChecked   = "todo.checked"
+	EventTodoUnchecked = "todo.unchecked"
+	EventTodoCompleted = "todo.completed"
+	EventTodoDeleted   = "todo.deleted"
+	EventTodoRestored  = "todo.restored"
+	EventMemoUpdated   = "memo.updated"
+	EventUserJoined    = "user.joined"
+)
+
+// Events lists every event a webhook can subscribe to
+var Events = []string{
+	EventTodoCreated,
+	EventTodoChecked,
+	EventTodoUnchecked,
+	EventTodoCompleted,
+	EventTodoDeleted,
+	EventTodoRestored,
+	EventMemoUpdated,
+	EventUserJoined,
+}
+
+// ValidEvent reports whether name is a known event
+func ValidEvent(name string) bool {
+	for _, event := range Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body posted to webhooks
+type Payload struct {
+	ID         string      `json:"id"`
+	Event      string      `json:"event"`
+	ListID     string      `json:"listId"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// NewSecret returns a random secret for signing payloads
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for a payload sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the payload
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// subscribed reports whether the webhook receives event. A webhook without
+// events receives all of them.
+func subscribed(hook models.Webhook, event string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, e := range hook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Enqueue queues event for every webhook of the list subscribed to it. It should
+// be called with the transaction of the change so the event is queued atomically.
+func Enqueue(db *gorm.DB, listID, event string, data interface{}) error {
+	var hooks []models.Webhook
+	if err := db.Where("list_id = ?", listID).Find(&hooks).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, hook := range hooks {
+		if !subscribed(hook, event) {
+			continue
+		}
+
+		payload := Payload{
+			ID:         uuid.New().String(),
+			Event:      event,
+			ListID:     listID,
+			OccurredAt: now,
+			Data:       data,
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		delivery := models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventID:       payload.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"shared-todo-backend/database"
+	"shared-todo-backend/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+type WebhookTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	server *httptest.Server
+	now    time.Time
+
+	mu       sync.Mutex
+	requests []received
+	status   int
+}
+
+func (suite *WebhookTestSuite) SetupTest() {
+	db, err := database.SetupTestDatabase()
+	suite.Require().NoError(err)
+	suite.db = db
+	// 配信は現在時刻でキューに入るため少し先の時刻から始める
+	suite.now = time.Now().UTC().Add(time.Second)
+	suite.requests = nil
+	suite.status = http.StatusOK
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+		suite.requests = append(suite.requests, received{header: r.Header.Clone(), body: body})
+		w.WriteHeader(suite.status)
+	}))
+
+	db.Create(&models.List{ID: "list"})
+
+	// テスト用サーバーはループバックで動くため許可する
+	AllowedNetworks, err = ParseNetworks("127.0.0.1, ::1")
+	suite.Require().NoError(err)
+}
+
+func (suite *WebhookTestSuite) TearDownTest() {
+	suite.server.Close()
+	AllowedNetworks = nil
+}
+
+func (suite *WebhookTestSuite) newDispatcher() *Dispatcher {
+	d := NewDispatcher(suite.db)
+	d.Now = func() time.Time { return suite.now }
+	return d
+}
+
+func (suite *WebhookTestSuite) createWebhook(events ...string) models.Webhook {
+	hook := models.Webhook{ListID: "list", URL: suite.server.URL, Secret: "secret", Events: events}
+	suite.db.Create(&hook)
+	return hook
+}
+
+func (suite *WebhookTestSuite) delivery() models.WebhookDelivery {
+	var delivery models.WebhookDelivery
+	suite.db.Order("id DESC").First(&delivery)
+	return delivery
+}
+
+func (suite *WebhookTestSuite) TestSignedDelivery() {
+	suite.createWebhook()
+	assert.NoError(suite.T(), Enqueue(suite.db, "list", EventMemoUpdated, map[string]string{"memo": "hello"}))
+	assert.NoError(suite.T(), suite.newDispatcher().RunOnce(context.Background()))
+
+	suite.Require().Len(suite.requests, 1)
+	req := suite.requests[0]
+	assert.Equal(suite.T(), EventMemoUpdated, req.header.Get("X-Webhook-Event"))
+	assert.True(suite.T(), Verify("secret", req.header.Get("X-Webhook-Timestamp"), req.body, req.header.Get("X-Webhook-Signature")))
+	assert.False(suite.T(), Verify("other", req.header.Get("X-Webhook-Timestamp"), req.body, req.header.Get("X-Webhook-Signature")))
+
+	var payload Payload
+	json.Unmarshal(req.body, &payload)
+	assert.Equal(suite.T(), req.header.Get("X-Webhook-Delivery"), payload.ID)
+	assert.Equal(suite.T(), "list", payload.ListID)
+	assert.Equal(suite.T(), map[string]interface{}{"memo": "hello"}, payload.Data)
+
+	delivery := suite.delivery()
+	assert.Equal(suite.T(), models.DeliverySucceeded, delivery.Status)
+	assert.Equal(suite.T(), 1, delivery.Attempts)
+	assert.Equal(suite.T(), http.StatusOK, delivery.ResponseStatus)
+	assert.NotNil(suite.T(), delivery.DeliveredAt)
+}
+
+func (suite *WebhookTestSuite) TestSubscribedEventsOnly() {
+	suite.createWebhook(EventTodoCreated)
+	assert.NoError(suite.T(), Enqueue(suite.db, "list", EventMemoUpdated, nil))
+	assert.NoError(suite.T(), Enqueue(suite.db, "list", EventTodoCreated, nil))
+
+	var deliveries []models.WebhookDelivery
+	suite.db.Find(&deliveries)
+	suite.Require().Len(deliveries, 1)
+	assert.Equal(suite.T(), EventTodoCreated, deliveries[0].Event)
+}
+
+func (suite *WebhookTestSuite) TestRetryWithBackoff() {
+	suite.createWebhook()
+	suite.status = http.StatusInternalServerError
+	assert.NoError(suite.T(), Enqueue(suite.db, "list", EventUserJoined, nil))
+
+	d := suite.newDispatcher()
+	ctx := context.Background()
+	assert.NoError(suite.T(), d.RunOnce(ctx))
+
+	delivery := suite.delivery()
+	assert.Equal(suite.T(), models.DeliveryPending, delivery.Status)
+	assert.Equal(suite.T(), http.StatusInternalServerError, delivery.ResponseStatus)
+	assert.True(suite.T(), suite.now.Add(DefaultBaseBackoff).Equal(delivery.NextAttemptAt))
+
+	// バックオフ期間中は再送しない
+	suite.now = suite.now.Add(DefaultBaseBackoff - time.Second)
+	assert.NoError(suite.T(), d.RunOnce(ctx))
+	assert.Len(suite.T(), suite.requests, 1)
+
+	suite.now = suite.now.Add(time.Second)
+	assert.NoError(suite.T(), d.RunOnce(ctx))
+	assert.Len(suite.T(), suite.requests, 2)
+	delivery = suite.delivery()
+	assert.True(suite.T(), suite.now.Add(2*DefaultBaseBackoff).Equal(delivery.NextAttemptAt))
+
+	// 同じイベントIDで再送される
+	assert.Equal(suite.T(), suite.requests[0].header.Get("X-Webhook-Delivery"), suite.requests[1].header.Get("X-Webhook-Delivery"))
+
+	suite.status = http.StatusNoContent
+	suite.now = delivery.NextAttemptAt
+	assert.NoError(suite.T(), d.RunOnce(ctx))
+	delivery = suite.delivery()
+	assert.Equal(suite.T(), models.DeliverySucceeded, delivery.Status)
+	assert.Equal(suite.T(), 3, delivery.Attempts)
+	assert.Empty(suite.T(), delivery.LastError)
+}
+
+func (suite *WebhookTestSuite) TestGiveUpAfterMaxAttempts() {
+	suite.createWebhook()
+	suite.status = http.StatusBadGateway
+	assert.NoError(suite.T(), Enqueue(suite.db, "list", EventUserJoined, nil))
+
+	d := suite.newDispatcher()
+	d.MaxAttempts = 3
+	for i := 0; i < 5; i++ {
+		assert.NoError(suite.T(), d.RunOnce(context.Background()))
+		suite.now = suite.now.Add(maxBackoff)
+	}
+
+	assert.Len(suite.T(), suite.requests, 3)
+	delivery := suite.delivery()
+	assert.Equal(suite.T(), models.DeliveryFailed, delivery.Status)
+	assert.Equal(suite.T(), "unexpected status 502", delivery.LastError)
+}
+
+func (suite *WebhookTestSuite) TestBackoffIsCapped() {
+	d := suite.newDispatcher()
+	assert.Equal(suite.T(), DefaultBaseBackoff, d.backoff(1))
+	assert.Equal(suite.T(), 4*DefaultBaseBackoff, d.backoff(3))
+	assert.Equal(suite.T(), maxBackoff, d.backoff(30))
+}
+
+func (suite *WebhookTestSuite) TestForbiddenTargetIsNotContacted() {
+	AllowedNetworks = nil
+	suite.createWebhook()
+	assert.NoError(suite.T(), Enqueue(suite.db, "list", EventMemoUpdated, map[string]string{"memo": "hello"}))
+	assert.NoError(suite.T(), suite.newDispatcher().RunOnce(context.Background()))
+
+	assert.Empty(suite.T(), suite.requests)
+	delivery := suite.delivery()
+	assert.Equal(suite.T(), models.DeliveryPending, delivery.Status)
+	assert.Contains(suite.T(), delivery.LastError, ErrForbiddenTarget.Error())
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	for _, u := range []string{
+		"http://127.0.0.1/hook",
+		"http://[::1]:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hook",
+		"https://192.168.1.1/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://localhost:8080/hook",
+	} {
+		assert.ErrorIs(t, CheckURL(ctx, u), ErrForbiddenTarget, u)
+	}
+	assert.NoError(t, CheckURL(ctx, "https://93.184.215.14/hook"))
+
+	networks, err := ParseNetworks("10.0.0.0/8, 127.0.0.1")
+	assert.NoError(t, err)
+	AllowedNetworks = networks
+	defer func() { AllowedNetworks = nil }()
+	assert.NoError(t, CheckURL(ctx, "http://10.0.0.5/hook"))
+	assert.NoError(t, CheckURL(ctx, "http://127.0.0.1/hook"))
+	assert.ErrorIs(t, CheckURL(ctx, "http://127.0.0.2/hook"), ErrForbiddenTarget)
+
+	_, err = ParseNetworks("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = ParseNetworks("internal")
+	assert.Error(t, err)
+}
+
+func TestWebhookTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookTestSuite))
+}
@@ -22,7 +22,9 @@ vi.mock('axios', () => ({
 }))
 
 // apiモジュールのインポート（axiosモック後）
-const { createList, getListData, createTodo, updateTodoUserStatus, updateListMemo, inviteUser, updateUserName } = await import('./api')
+const { ApiError, createList, getListData, createTodo, updateTodoUserStatus, updateListMemo, inviteUser, updateUserName } = await import('./api')
+
+const idempotencyHeaders = { headers: { 'Idempotency-Key': expect.any(String) } }
 
 describe('API Functions', () => {
   beforeEach(() => {
@@ -46,8 +48,32 @@ describe('API Functions', () => {
 
       const result = await createList()
 
-      expect(mockAxiosInstance.post).toHaveBeenCalledWith('/lists')
+      expect(mockAxiosInstance.post).toHaveBeenCalledWith('/lists', undefined, idempotencyHeaders)
+      expect(result).toEqual(mockResponse.data)
+    })
+
+    it('should retry with the same idempotency key when there is no response', async () => {
+      const mockResponse = { data: { listId: 'test-list-id', userId: 'test-user-id' } }
+      ;(mockAxiosInstance.post as MockedFunction<any>)
+        .mockRejectedValueOnce(new ApiError('ネットワークエラー: サーバーに接続できません'))
+        .mockRejectedValueOnce(new ApiError('409: A request with this idempotency key is in progress', 409))
+        .mockResolvedValue(mockResponse)
+
+      const result = await createList()
+
       expect(result).toEqual(mockResponse.data)
+      const calls = (mockAxiosInstance.post as MockedFunction<any>).mock.calls
+      expect(calls).toHaveLength(3)
+      const key = calls[0][2].headers['Idempotency-Key']
+      expect(calls[1][2].headers['Idempotency-Key']).toBe(key)
+      expect(calls[2][2].headers['Idempotency-Key']).toBe(key)
+    })
+
+    it('should not retry an error response', async () => {
+      ;(mockAxiosInstance.post as MockedFunction<any>).mockRejectedValue(new ApiError('400: Invalid request', 400))
+
+      await expect(createList()).rejects.toThrow('400: Invalid request')
+      expect(mockAxiosInstance.post).toHaveBeenCalledTimes(1)
     })
 
     it('should handle API errors', async () => {
@@ -77,6 +103,17 @@ describe('API Functions', () => {
   })
 
   describe('createTodo', () => {
+    it('should use a new idempotency key for each submit', async () => {
+      const todoData = { title: 'New Todo', priority: 'high' as const, dueDate: null }
+      ;(mockAxiosInstance.post as MockedFunction<any>).mockResolvedValue({ data: { id: 1, ...todoData } })
+
+      await createTodo('list-id', todoData)
+      await createTodo('list-id', todoData)
+
+      const calls = (mockAxiosInstance.post as MockedFunction<any>).mock.calls
+      expect(calls[0][2].headers['Idempotency-Key']).not.toBe(calls[1][2].headers['Idempotency-Key'])
+    })
+
     it('should create a todo successfully', async () => {
       const todoData = {
         title: 'New Todo',
@@ -90,7 +127,7 @@ describe('API Functions', () => {
 
       const result = await createTodo('list-id', todoData)
 
-      expect(mockAxiosInstance.post).toHaveBeenCalledWith('/lists/list-id/todos', todoData)
+      expect(mockAxiosInstance.post).toHaveBeenCalledWith('/lists/list-id/todos', todoData, idempotencyHeaders)
       expect(result).toEqual(mockResponse.data)
     })
   })
@@ -132,7 +169,7 @@ describe('API Functions', () => {
 
       const result = await inviteUser('list-id')
 
-      expect(mockAxiosInstance.post).toHaveBeenCalledWith('/lists/list-id/users')
+      expect(mockAxiosInstance.post).toHaveBeenCalledWith('/lists/list-id/users', undefined, idempotencyHeaders)
       expect(result).toEqual(mockResponse.data)
     })
   })
@@ -12,11 +12,25 @@ import type {
 
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api'
 
+// 作成系のリクエストを再送する回数と間隔
+const MAX_ATTEMPTS = 3
+const RETRY_DELAY_MS = 500
+
 const api = axios.create({
   baseURL: API_BASE_URL,
   timeout: 10000
 })
 
+// ApiError はサーバーとの通信のエラー。statusはレスポンスを受け取れなかった場合（タイムアウトを含む）undefined
+export class ApiError extends Error {
+  status?: number
+
+  constructor(message: string, status?: number) {
+    super(message)
+    this.status = status
+  }
+}
+
 // レスポンスインターセプターでエラーハンドリングを統一
 api.interceptors.response.use(
   (response: AxiosResponse) => response,
@@ -24,10 +38,10 @@ api.interceptors.response.use(
     if (error.response) {
       // サーバーエラーレスポンス
       const message = error.response.data?.error || 'サーバーエラーが発生しました'
-      throw new Error(`${error.response.status}: ${message}`)
+      throw new ApiError(`${error.response.status}: ${message}`, error.response.status)
     } else if (error.request) {
       // ネットワークエラー
-      throw new Error('ネットワークエラー: サーバーに接続できません')
+      throw new ApiError('ネットワークエラー: サーバーに接続できません')
     } else {
       // その他のエラー
       throw new Error('リクエストエラーが発生しました')
@@ -35,8 +49,36 @@ api.interceptors.response.use(
   }
 )
 
+// 操作したユーザーを操作履歴に残すため、以降のリクエストに付ける
+export const setActor = (userId: string): void => {
+  api.defaults.headers.common['X-User-ID'] = userId
+}
+
+const newIdempotencyKey = (): string => {
+  const bytes = crypto.getRandomValues(new Uint8Array(16))
+  return Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('')
+}
+
+// 作成系のリクエストを送る。タイムアウトなどで失敗した場合は同じIdempotency-Keyで再送し、
+// サーバーが最初のレスポンスを返すため重複して作成されない
+const postIdempotent = async <T>(url: string, data?: unknown): Promise<AxiosResponse<T>> => {
+  const headers = { 'Idempotency-Key': newIdempotencyKey() }
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await api.post<T>(url, data, { headers })
+    } catch (error) {
+      // レスポンスがない場合と、最初のリクエストがまだ処理中（409）の場合だけ再送する
+      const retryable = error instanceof ApiError && (error.status === undefined || error.status === 409)
+      if (!retryable || attempt >= MAX_ATTEMPTS) {
+        throw error
+      }
+      await new Promise((resolve) => setTimeout(resolve, RETRY_DELAY_MS * attempt))
+    }
+  }
+}
+
 export const createList = async (): Promise<CreateListResponse> => {
-  const response = await api.post<CreateListResponse>('/lists')
+  const response = await postIdempotent<CreateListResponse>('/lists')
   return response.data
 }
 
@@ -46,7 +88,7 @@ export const getListData = async (listId: string, userId: string): Promise<GetLi
 }
 
 export const createTodo = async (listId: string, todo: CreateTodoRequest): Promise<Todo> => {
-  const response = await api.post<Todo>(`/lists/${listId}/todos`, todo)
+  const response = await postIdempotent<Todo>(`/lists/${listId}/todos`, todo)
   return response.data
 }
 
@@ -67,7 +109,7 @@ export const updateListMemo = async (listId: string, memo: string): Promise<{ me
 }
 
 export const inviteUser = async (listId: string): Promise<InviteUserResponse> => {
-  const response = await api.post<InviteUserResponse>(`/lists/${listId}/users`)
+  const response = await postIdempotent<InviteUserResponse>(`/lists/${listId}/users`)
   return response.data
 }
 